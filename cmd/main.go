@@ -5,6 +5,8 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"github.com/gin-gonic/gin"
 	_ "github.com/swaggo/files"
@@ -15,10 +17,19 @@ import (
 	"jumyste-app-backend/internal/applicator"
 	"jumyste-app-backend/internal/middleware"
 	"jumyste-app-backend/internal/router"
+	"jumyste-app-backend/pkg/jwtkeys"
 	"jumyste-app-backend/pkg/logger"
+	"jumyste-app-backend/utils"
+	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 )
 
+// shutdownTimeout — сколько ждём завершения активных запросов и закрытия WebSocket при остановке
+const shutdownTimeout = 10 * time.Second
+
 func main() {
 	logger.InitLogger()
 
@@ -26,8 +37,27 @@ func main() {
 
 	logger.Log.Info("Starting application...")
 
-	auth := middleware.NewAuthMiddleware(config.AppConfig)
-	app := applicator.NewApp(auth)
+	if err := checkJWTSecret(config.AppConfig.JWT); err != nil {
+		logger.Log.Error("Insecure JWT configuration", "error", err.Error())
+		os.Exit(1)
+	}
+
+	jwtKeys, err := jwtkeys.NewManager(jwtkeys.Config{
+		Algorithm:        config.AppConfig.JWT.Algorithm,
+		KeyID:            config.AppConfig.JWT.KeyID,
+		Secret:           config.AppConfig.JWT.Secret,
+		PrivateKey:       config.AppConfig.JWT.PrivateKey,
+		PrivateKeyFile:   config.AppConfig.JWT.PrivateKeyFile,
+		VerificationKeys: config.AppConfig.JWT.VerificationKeys,
+	})
+	if err != nil {
+		logger.Log.Error("Failed to load JWT keys", "error", err.Error())
+		os.Exit(1)
+	}
+	utils.InitJWT(jwtKeys, time.Duration(config.AppConfig.JWT.ExpirationHours)*time.Hour)
+
+	auth := middleware.NewAuthMiddleware()
+	app := applicator.NewApp(auth, jwtKeys)
 
 	r := router.SetupRouter(
 		app.AuthHandler,
@@ -42,16 +72,57 @@ func main() {
 		app.JobAppHandler,
 		app.DepartmentHandler,
 		app.CompanyHandler,
+		app.StageHandler,
+		app.InterviewHandler,
+		app.FileHandler,
 	)
 
 	serverPort := config.AppConfig.Server.Port
 	addr := fmt.Sprintf(":%s", serverPort)
 	logger.Log.Info("Starting server", "port", serverPort)
 	setupSwagger(r)
-	if err := r.Run(addr); err != nil {
-		logger.Log.Error("Failed to start server", "error", err.Error())
+
+	server := &http.Server{Addr: addr, Handler: r}
+	go func() {
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Log.Error("Failed to start server", "error", err.Error())
+			os.Exit(1)
+		}
+	}()
+
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	<-quit
+
+	// WebSocket-соединения захвачены у http.Server, поэтому закрываем их отдельно
+	logger.Log.Info("Shutting down server...")
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := app.WSManager.Shutdown(ctx); err != nil {
+		logger.Log.Warn("WebSocket connections were not closed in time", "error", err)
+	}
+	if err := server.Shutdown(ctx); err != nil {
+		logger.Log.Error("Server forced to shutdown", "error", err)
 	}
+}
+
+// defaultJWTSecret — значение JWT_SECRET по умолчанию из config.LoadConfig
+const defaultJWTSecret = "secretkey"
 
+// checkJWTSecret не даёт запустить production с общеизвестным или пустым секретом HS256;
+// в остальных окружениях секрет по умолчанию допускается с предупреждением
+func checkJWTSecret(cfg config.JWTConfig) error {
+	if cfg.Algorithm != "" && cfg.Algorithm != jwtkeys.AlgHS256 {
+		return nil
+	}
+	if cfg.Secret != "" && cfg.Secret != defaultJWTSecret {
+		return nil
+	}
+	if config.AppConfig.AppEnv.AppEnv == "production" {
+		return errors.New("JWT_SECRET must be set to a non-default value for HS256 in production")
+	}
+	logger.Log.Warn("JWT is signed with the default secret, set JWT_SECRET or switch to RS256/EdDSA")
+	return nil
 }
 
 func setupSwagger(r *gin.Engine) {
@@ -9,12 +9,16 @@ import (
 )
 
 type Config struct {
-	Server   ServerConfig
-	Database DatabaseConfig
-	JWT      JWTConfig
-	SMTP     SMTPConfig
-	AI       AIConfig
-	AppEnv   AppEnv
+	Server    ServerConfig
+	Database  DatabaseConfig
+	JWT       JWTConfig
+	SMTP      SMTPConfig
+	AI        AIConfig
+	Interview InterviewConfig
+	JobApp    JobApplicationConfig
+	WebSocket WebSocketConfig
+	Storage   StorageConfig
+	AppEnv    AppEnv
 }
 
 type ServerConfig struct {
@@ -33,6 +37,14 @@ type DatabaseConfig struct {
 type JWTConfig struct {
 	Secret          string
 	ExpirationHours int
+
+	// Algorithm — HS256 (общий секрет) или RS256/EdDSA (закрытый ключ, открытые ключи в JWKS)
+	Algorithm      string
+	KeyID          string
+	PrivateKey     string
+	PrivateKeyFile string
+	// VerificationKeys — прежние открытые ключи на время ротации: "kid=путь,kid=путь"
+	VerificationKeys string
 }
 
 type SMTPConfig struct {
@@ -44,7 +56,49 @@ type SMTPConfig struct {
 }
 
 type AIConfig struct {
-	APIKey string
+	Provider       string
+	APIKey         string
+	BaseURL        string
+	Model          string
+	TimeoutSeconds int
+
+	MatchingWorkers        int
+	MatchingMaxAttempts    int
+	MatchingBackoffSeconds int
+}
+
+type InterviewConfig struct {
+	ReminderMinutes int
+	Timezone        string
+}
+
+type JobApplicationConfig struct {
+	ReapplyCooldownDays int
+}
+
+type WebSocketConfig struct {
+	BusChannel       string
+	SendBufferSize   int
+	MaxMessageBytes  int
+	PongWaitSeconds  int
+	WriteWaitSeconds int
+	SlowClientPolicy string
+}
+
+type StorageConfig struct {
+	Driver        string // local или s3
+	LocalDir      string
+	PublicBaseURL string // адрес API для подписанных ссылок локального хранилища
+	SigningSecret string
+	URLTTLMinutes int
+	MaxUploadMB   int
+
+	S3Endpoint  string
+	S3Region    string
+	S3Bucket    string
+	S3AccessKey string
+	S3SecretKey string
+	S3PathStyle bool
 }
 
 type AppEnv struct {
@@ -73,6 +127,12 @@ func LoadConfig() {
 		JWT: JWTConfig{
 			Secret:          getEnv("JWT_SECRET", "secretkey"),
 			ExpirationHours: getEnvInt("JWT_EXPIRATION_HOURS", 1),
+
+			Algorithm:        getEnv("JWT_ALGORITHM", "HS256"),
+			KeyID:            getEnv("JWT_KEY_ID", ""),
+			PrivateKey:       getEnv("JWT_PRIVATE_KEY", ""),
+			PrivateKeyFile:   getEnv("JWT_PRIVATE_KEY_FILE", ""),
+			VerificationKeys: getEnv("JWT_VERIFICATION_KEYS", ""),
 		},
 		SMTP: SMTPConfig{
 			Host:     getEnv("SMTP_HOST", "localhost"),
@@ -82,7 +142,45 @@ func LoadConfig() {
 			Sender:   getEnv("SMTP_SENDER", "noreply@jumyste-app.local"),
 		},
 		AI: AIConfig{
-			APIKey: getEnv("OPENAI_API_KE", ""),
+			Provider:       getEnv("AI_PROVIDER", "openai"),
+			APIKey:         getEnv("OPENAI_API_KEY", ""),
+			BaseURL:        getEnv("AI_BASE_URL", "https://api.openai.com/v1"),
+			Model:          getEnv("AI_MODEL", "gpt-4o-mini"),
+			TimeoutSeconds: getEnvInt("AI_TIMEOUT_SECONDS", 60),
+
+			MatchingWorkers:        getEnvInt("AI_MATCHING_WORKERS", 4),
+			MatchingMaxAttempts:    getEnvInt("AI_MATCHING_MAX_ATTEMPTS", 3),
+			MatchingBackoffSeconds: getEnvInt("AI_MATCHING_BACKOFF_SECONDS", 2),
+		},
+		Interview: InterviewConfig{
+			ReminderMinutes: getEnvInt("INTERVIEW_REMINDER_MINUTES", 60),
+			Timezone:        getEnv("INTERVIEW_TIMEZONE", "Asia/Almaty"),
+		},
+		JobApp: JobApplicationConfig{
+			ReapplyCooldownDays: getEnvInt("REAPPLY_COOLDOWN_DAYS", 30),
+		},
+		WebSocket: WebSocketConfig{
+			BusChannel:       getEnv("WS_BUS_CHANNEL", "jumyste:ws:events"),
+			SendBufferSize:   getEnvInt("WS_SEND_BUFFER_SIZE", 64),
+			MaxMessageBytes:  getEnvInt("WS_MAX_MESSAGE_BYTES", 64*1024),
+			PongWaitSeconds:  getEnvInt("WS_PONG_WAIT_SECONDS", 60),
+			WriteWaitSeconds: getEnvInt("WS_WRITE_WAIT_SECONDS", 10),
+			SlowClientPolicy: getEnv("WS_SLOW_CLIENT_POLICY", "disconnect"),
+		},
+		Storage: StorageConfig{
+			Driver:        getEnv("STORAGE_DRIVER", "local"),
+			LocalDir:      getEnv("STORAGE_LOCAL_DIR", "./uploads"),
+			PublicBaseURL: getEnv("STORAGE_PUBLIC_BASE_URL", "http://localhost:8080"),
+			SigningSecret: getEnv("STORAGE_SIGNING_SECRET", ""),
+			URLTTLMinutes: getEnvInt("STORAGE_URL_TTL_MINUTES", 5),
+			MaxUploadMB:   getEnvInt("STORAGE_MAX_UPLOAD_MB", 100),
+
+			S3Endpoint:  getEnv("S3_ENDPOINT", ""),
+			S3Region:    getEnv("S3_REGION", "us-east-1"),
+			S3Bucket:    getEnv("S3_BUCKET", ""),
+			S3AccessKey: getEnv("S3_ACCESS_KEY", ""),
+			S3SecretKey: getEnv("S3_SECRET_KEY", ""),
+			S3PathStyle: getEnv("S3_PATH_STYLE", "false") == "true",
 		},
 		AppEnv: AppEnv{
 			AppEnv: getEnv("APP_ENV", "development"),
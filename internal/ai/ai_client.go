@@ -5,23 +5,30 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"jumyste-app-backend/config"
 	"jumyste-app-backend/internal/dto"
 	"jumyste-app-backend/pkg/helper"
 	"jumyste-app-backend/pkg/logger"
 	"log"
 	"net/http"
-	"os"
-	"strconv"
 	"strings"
+	"time"
 )
 
+// OpenAIClient работает с любым API, совместимым с OpenAI Chat Completions
 type OpenAIClient struct {
-	APIKey string
+	APIKey     string
+	BaseURL    string
+	Model      string
+	HTTPClient *http.Client
 }
 
-func NewOpenAIClient() *OpenAIClient {
+func NewOpenAIClient(cfg config.AIConfig) *OpenAIClient {
 	return &OpenAIClient{
-		APIKey: os.Getenv("OPENAI_API_KEY"),
+		APIKey:     cfg.APIKey,
+		BaseURL:    strings.TrimSuffix(cfg.BaseURL, "/"),
+		Model:      cfg.Model,
+		HTTPClient: &http.Client{Timeout: time.Duration(cfg.TimeoutSeconds) * time.Second},
 	}
 }
 
@@ -31,8 +38,20 @@ type Message struct {
 }
 
 type OpenAIRequest struct {
-	Model    string    `json:"model"`
-	Messages []Message `json:"messages"`
+	Model          string          `json:"model"`
+	Messages       []Message       `json:"messages"`
+	ResponseFormat *ResponseFormat `json:"response_format,omitempty"`
+}
+
+type ResponseFormat struct {
+	Type       string      `json:"type"`
+	JSONSchema *JSONSchema `json:"json_schema,omitempty"`
+}
+
+type JSONSchema struct {
+	Name   string                 `json:"name"`
+	Strict bool                   `json:"strict"`
+	Schema map[string]interface{} `json:"schema"`
 }
 
 type OpenAIResponse struct {
@@ -43,74 +62,88 @@ type OpenAIResponse struct {
 	} `json:"choices"`
 }
 
-type Resume struct {
-	FullName        string   `json:"full_name"`
-	DesiredPosition string   `json:"desired_position"`
-	Skills          []string `json:"skills"`
-	City            string   `json:"city"`
-	AboutMe         string   `json:"about_me"`
-}
-
-func (c *OpenAIClient) AnalyzeResume(text string) (*Resume, error) {
-	prompt := fmt.Sprintf(`
-Parse the following resume text and return a JSON object with the following structure:
-
-{
-  "full_name": "Full name",
-  "desired_position": "Desired job position",
-  "skills": ["Skill1", "Skill2", "Skill3"],
-  "city": "City of residence",
-  "about_me": "Everything else important from the resume (experience, achievements, etc.)"
-}
-
-If some information is missing — leave an empty string or an empty array.
-
-Resume text:
-%s
-`, text)
-
+// complete отправляет запрос в chat/completions и возвращает текст первого ответа.
+// format задаёт response_format (например, JSON-схему); nil — свободный текст.
+func (c *OpenAIClient) complete(systemPrompt, userPrompt string, format *ResponseFormat) (string, error) {
 	requestBody, err := json.Marshal(OpenAIRequest{
-		Model: "gpt-4o-mini",
+		Model: c.Model,
 		Messages: []Message{
-			{Role: "system", Content: "You are an AI that extracts structured resume information in JSON format."},
-			{Role: "user", Content: prompt},
+			{Role: "system", Content: systemPrompt},
+			{Role: "user", Content: userPrompt},
 		},
+		ResponseFormat: format,
 	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to encode request: %w", err)
+		return "", fmt.Errorf("failed to encode request: %w", err)
 	}
 
-	req, err := http.NewRequest("POST", "https://api.openai.com/v1/chat/completions", bytes.NewBuffer(requestBody))
+	req, err := http.NewRequest("POST", c.BaseURL+"/chat/completions", bytes.NewBuffer(requestBody))
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		return "", fmt.Errorf("failed to create request: %w", err)
 	}
 
 	req.Header.Set("Authorization", "Bearer "+c.APIKey)
 	req.Header.Set("Content-Type", "application/json")
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := c.HTTPClient.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to send request: %w", err)
+		return "", fmt.Errorf("failed to send request: %w", err)
 	}
 	defer resp.Body.Close()
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %w", err)
+		return "", fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		logger.Log.Error("AI provider returned error status", "status", resp.StatusCode, "body", string(body))
+		return "", fmt.Errorf("AI provider returned status %d", resp.StatusCode)
 	}
-	log.Printf("Raw AI Response: %s", string(body))
 
 	var openAIResp OpenAIResponse
 	if err := json.Unmarshal(body, &openAIResp); err != nil {
-		log.Printf("Invalid OpenAI response: %s", string(body))
-		return nil, fmt.Errorf("failed to parse OpenAI response: %w", err)
+		logger.Log.Error("Failed to parse OpenAI response", "body", string(body), "error", err)
+		return "", fmt.Errorf("failed to parse OpenAI response: %w", err)
 	}
 
 	if len(openAIResp.Choices) == 0 {
-		return nil, fmt.Errorf("no response from AI")
+		return "", fmt.Errorf("no response from AI")
 	}
 
-	responseText := strings.TrimSpace(openAIResp.Choices[0].Message.Content)
+	return strings.TrimSpace(openAIResp.Choices[0].Message.Content), nil
+}
+
+type Resume struct {
+	FullName        string   `json:"full_name"`
+	DesiredPosition string   `json:"desired_position"`
+	Skills          []string `json:"skills"`
+	City            string   `json:"city"`
+	AboutMe         string   `json:"about_me"`
+}
+
+func (c *OpenAIClient) AnalyzeResume(text string) (*Resume, error) {
+	prompt := fmt.Sprintf(`
+Parse the following resume text and return a JSON object with the following structure:
+
+{
+  "full_name": "Full name",
+  "desired_position": "Desired job position",
+  "skills": ["Skill1", "Skill2", "Skill3"],
+  "city": "City of residence",
+  "about_me": "Everything else important from the resume (experience, achievements, etc.)"
+}
+
+If some information is missing — leave an empty string or an empty array.
+
+Resume text:
+%s
+`, text)
+
+	responseText, err := c.complete("You are an AI that extracts structured resume information in JSON format.", prompt, nil)
+	if err != nil {
+		return nil, err
+	}
 	log.Printf("AI Response: %s", responseText)
 
 	jsonStr := helper.ExtractJSON(responseText)
@@ -162,47 +195,11 @@ func (c *OpenAIClient) GenerateVacancyDescription(input dto.VacancyInput) (strin
 Результат должен быть валидным HTML-блоком, без объяснений и комментариев.
 `, input.Title, input.EmploymentType, input.WorkFormat, strings.Join(input.Skills, ", "), input.Location, input.Experience, input.SalaryMin, input.SalaryMax)
 
-	requestBody, err := json.Marshal(OpenAIRequest{
-		Model: "gpt-4o-mini",
-		Messages: []Message{
-			{Role: "system", Content: "Ты помощник HR, создающий описания вакансий."},
-			{Role: "user", Content: prompt},
-		},
-	})
-	if err != nil {
-		return "", fmt.Errorf("failed to encode request: %w", err)
-	}
-
-	req, err := http.NewRequest("POST", "https://api.openai.com/v1/chat/completions", bytes.NewBuffer(requestBody))
+	description, err := c.complete("Ты помощник HR, создающий описания вакансий.", prompt, nil)
 	if err != nil {
-		return "", fmt.Errorf("failed to create request: %w", err)
-	}
-
-	req.Header.Set("Authorization", "Bearer "+c.APIKey)
-	req.Header.Set("Content-Type", "application/json")
-
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return "", fmt.Errorf("failed to send request: %w", err)
-	}
-	defer resp.Body.Close()
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return "", fmt.Errorf("failed to read response body: %w", err)
-	}
-
-	var openAIResp OpenAIResponse
-	if err := json.Unmarshal(body, &openAIResp); err != nil {
-		return "", fmt.Errorf("failed to parse OpenAI response: %w", err)
+		return "", err
 	}
 
-	if len(openAIResp.Choices) == 0 {
-		return "", fmt.Errorf("no response from AI")
-	}
-
-	description := strings.TrimSpace(openAIResp.Choices[0].Message.Content)
-
 	if strings.HasPrefix(description, "```html") && strings.HasSuffix(description, "```") {
 		description = strings.TrimPrefix(description, "```html")
 		description = strings.TrimSuffix(description, "```")
@@ -211,21 +208,19 @@ func (c *OpenAIClient) GenerateVacancyDescription(input dto.VacancyInput) (strin
 	return description, nil
 }
 
-type MatchingResult struct {
-	Score      int
-	Strengths  string
-	Weaknesses string
-}
-
 func (c *OpenAIClient) GetMatchingScore(resumeText string, vacancyDescription string) (*MatchingResult, error) {
 	prompt := fmt.Sprintf(`
 Оцени соответствие резюме описанию вакансии по шкале от 0 до 100.
-Затем обязательно укажи 2–3 сильные стороны и 2–3 слабые стороны кандидата.
+Дай отдельные оценки от 0 до 100 по навыкам (skills), опыту (experience), локации (location) и зарплатным ожиданиям (salary).
+Укажи 2–3 сильные стороны и 2–3 слабые стороны кандидата — каждую отдельным элементом массива.
 
-Ответ **только строго в формате**:
-Оценка: <число>
-Преимущества: <список через запятую>
-Недостатки: <список через запятую>
+Ответ — только JSON-объект по схеме:
+{
+  "score": <целое число 0-100>,
+  "strengths": ["..."],
+  "weaknesses": ["..."],
+  "breakdown": {"skills": <0-100>, "experience": <0-100>, "location": <0-100>, "salary": <0-100>}
+}
 
 Резюме:
 %s
@@ -234,72 +229,15 @@ func (c *OpenAIClient) GetMatchingScore(resumeText string, vacancyDescription st
 %s
 `, resumeText, vacancyDescription)
 
-	requestBody, err := json.Marshal(OpenAIRequest{
-		Model: "gpt-4o-mini",
-		Messages: []Message{
-			{Role: "system", Content: "Ты AI-рекрутер. Оцени соответствие кандидата вакансии, дай сильные и слабые стороны. Ответ строго в нужном формате."},
-			{Role: "user", Content: prompt},
-		},
-	})
+	text, err := c.complete("Ты AI-рекрутер. Оцени соответствие кандидата вакансии. Отвечай только валидным JSON по заданной схеме.", prompt, matchingResponseFormat)
 	if err != nil {
-		return nil, fmt.Errorf("failed to encode analysis request: %w", err)
+		return nil, fmt.Errorf("analysis request failed: %w", err)
 	}
 
-	req, err := http.NewRequest("POST", "https://api.openai.com/v1/chat/completions", bytes.NewBuffer(requestBody))
+	result, err := ParseMatchingResult(text)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create analysis request: %w", err)
-	}
-
-	req.Header.Set("Authorization", "Bearer "+c.APIKey)
-	req.Header.Set("Content-Type", "application/json")
-
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("failed to send analysis request: %w", err)
-	}
-	defer resp.Body.Close()
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read analysis response: %w", err)
-	}
-
-	var openAIResp OpenAIResponse
-	if err := json.Unmarshal(body, &openAIResp); err != nil {
-		return nil, fmt.Errorf("failed to parse analysis response: %w", err)
-	}
-
-	if len(openAIResp.Choices) == 0 {
-		return nil, fmt.Errorf("empty analysis response")
-	}
-
-	text := strings.TrimSpace(openAIResp.Choices[0].Message.Content)
-	lines := strings.Split(text, "\n")
-
-	result := &MatchingResult{}
-
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-
-		switch {
-		case strings.HasPrefix(line, "Оценка:"):
-			scoreStr := strings.TrimSpace(strings.TrimPrefix(line, "Оценка:"))
-			score, err := strconv.Atoi(scoreStr)
-			if err != nil {
-				return nil, fmt.Errorf("invalid score format: %w", err)
-			}
-			result.Score = score
-
-		case strings.HasPrefix(line, "Преимущества:"),
-			strings.HasPrefix(line, "Плюсы:"):
-			value := strings.TrimSpace(strings.SplitN(line, ":", 2)[1])
-			result.Strengths = value
-
-		case strings.HasPrefix(line, "Недостатки:"),
-			strings.HasPrefix(line, "Минусы:"):
-			value := strings.TrimSpace(strings.SplitN(line, ":", 2)[1])
-			result.Weaknesses = value
-		}
+		logger.Log.Error("Invalid matching response", "content", text, "error", err)
+		return nil, err
 	}
 
 	return result, nil
@@ -334,45 +272,10 @@ func (c *OpenAIClient) GenerateResumeFromAI(input dto.GenerateResumeRequest) (*d
 
 `, input.Position)
 
-	requestBody, err := json.Marshal(OpenAIRequest{
-		Model: "gpt-4o-mini",
-		Messages: []Message{
-			{Role: "system", Content: "Ты AI, генерирующий резюме для соискателей."},
-			{Role: "user", Content: prompt},
-		},
-	})
-	if err != nil {
-		return nil, fmt.Errorf("failed to encode request: %w", err)
-	}
-
-	req, err := http.NewRequest("POST", "https://api.openai.com/v1/chat/completions", bytes.NewBuffer(requestBody))
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
-	}
-	req.Header.Set("Authorization", "Bearer "+c.APIKey)
-	req.Header.Set("Content-Type", "application/json")
-
-	resp, err := http.DefaultClient.Do(req)
+	content, err := c.complete("Ты AI, генерирующий резюме для соискателей.", prompt, nil)
 	if err != nil {
-		return nil, fmt.Errorf("failed to send request: %w", err)
+		return nil, err
 	}
-	defer resp.Body.Close()
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %w", err)
-	}
-
-	var openAIResp OpenAIResponse
-	if err := json.Unmarshal(body, &openAIResp); err != nil {
-		logger.Log.Error("Failed to parse OpenAI response", "body", string(body), "error", err)
-		return nil, fmt.Errorf("failed to parse AI response: %w", err)
-	}
-	if len(openAIResp.Choices) == 0 {
-		return nil, fmt.Errorf("no response from AI")
-	}
-
-	content := strings.TrimSpace(openAIResp.Choices[0].Message.Content)
 	logger.Log.Info("AI content", "content", content)
 
 	jsonStr := helper.ExtractJSONFromOpenAI(content)
@@ -0,0 +1,222 @@
+package ai
+
+import (
+	"fmt"
+	"jumyste-app-backend/internal/dto"
+	"jumyste-app-backend/internal/entity"
+	"strings"
+	"unicode"
+)
+
+// LocalProvider — детерминированная реализация Provider на правилах, без сетевых запросов.
+// Используется в CI и локальной разработке, где OpenAI недоступен.
+type LocalProvider struct {
+	KnownSkills    []string
+	KnownCities    []string
+	KnownPositions []string
+}
+
+func NewLocalProvider() *LocalProvider {
+	return &LocalProvider{
+		KnownSkills: []string{
+			"Go", "Golang", "Python", "Java", "JavaScript", "TypeScript", "Node.js", "React", "Vue", "Angular",
+			"PostgreSQL", "MySQL", "MongoDB", "Redis", "Docker", "Kubernetes", "Git", "Linux", "SQL",
+			"HTML", "CSS", "C++", "C#", "PHP", "Kotlin", "Swift", "Figma", "Excel", "1C",
+		},
+		KnownCities: []string{
+			"Алматы", "Астана", "Шымкент", "Караганда", "Актобе", "Атырау", "Павлодар", "Москва",
+			"Almaty", "Astana", "Shymkent", "Karaganda",
+		},
+		KnownPositions: []string{
+			"Backend Developer", "Frontend Developer", "Fullstack Developer", "Software Engineer",
+			"Data Analyst", "QA Engineer", "DevOps Engineer", "Project Manager", "Product Manager", "Designer",
+			"Разработчик", "Программист", "Аналитик", "Тестировщик", "Менеджер", "Дизайнер", "Бухгалтер",
+		},
+	}
+}
+
+func (p *LocalProvider) AnalyzeResume(text string) (*Resume, error) {
+	if strings.TrimSpace(text) == "" {
+		return nil, fmt.Errorf("empty resume text")
+	}
+
+	var nameParts []string
+	for _, word := range strings.Fields(text) {
+		runes := []rune(word)
+		if len(runes) < 2 || !unicode.IsUpper(runes[0]) || !isLetters(runes) {
+			if len(nameParts) > 0 {
+				break
+			}
+			continue
+		}
+		nameParts = append(nameParts, word)
+		if len(nameParts) == 2 {
+			break
+		}
+	}
+
+	return &Resume{
+		FullName:        strings.Join(nameParts, " "),
+		DesiredPosition: firstMatch(text, p.KnownPositions),
+		Skills:          allMatches(text, p.KnownSkills),
+		City:            firstMatch(text, p.KnownCities),
+		AboutMe:         text,
+	}, nil
+}
+
+func (p *LocalProvider) GenerateVacancyDescription(input dto.VacancyInput) (string, error) {
+	var b strings.Builder
+
+	b.WriteString("<div>")
+	b.WriteString(fmt.Sprintf("<h2>%s</h2>", input.Title))
+	b.WriteString(fmt.Sprintf("<p>Тип занятости: %s. Формат работы: %s. Локация: %s.</p>", input.EmploymentType, input.WorkFormat, input.Location))
+
+	b.WriteString("<h2>Обязанности</h2><ul>")
+	b.WriteString(fmt.Sprintf("<li>Выполнение задач по направлению «%s»</li>", input.Title))
+	b.WriteString("<li>Взаимодействие с командой и участие в планировании</li>")
+	b.WriteString("</ul>")
+
+	b.WriteString("<h2>Требования</h2><ul>")
+	if input.Experience != "" {
+		b.WriteString(fmt.Sprintf("<li>Опыт: %s</li>", input.Experience))
+	}
+	for _, skill := range input.Skills {
+		b.WriteString(fmt.Sprintf("<li>%s</li>", skill))
+	}
+	b.WriteString("</ul>")
+
+	b.WriteString("<h2>Мы предлагаем</h2><ul>")
+	if input.SalaryMin > 0 || input.SalaryMax > 0 {
+		b.WriteString(fmt.Sprintf("<li>Заработная плата от %d до %d</li>", input.SalaryMin, input.SalaryMax))
+	}
+	b.WriteString(fmt.Sprintf("<li>%s</li>", input.EmploymentType))
+	b.WriteString("</ul>")
+	b.WriteString("</div>")
+
+	return b.String(), nil
+}
+
+// GetMatchingScore сравнивает строки "Навыки:" и город/локацию из текстов резюме и вакансии
+func (p *LocalProvider) GetMatchingScore(resumeText string, vacancyDescription string) (*MatchingResult, error) {
+	resumeSkills := splitList(extractField(resumeText, "Навыки:"))
+	vacancySkills := splitList(extractField(vacancyDescription, "Навыки:"))
+
+	have := make(map[string]bool, len(resumeSkills))
+	for _, skill := range resumeSkills {
+		have[strings.ToLower(skill)] = true
+	}
+
+	var matched, missing []string
+	for _, skill := range vacancySkills {
+		if have[strings.ToLower(skill)] {
+			matched = append(matched, skill)
+		} else {
+			missing = append(missing, skill)
+		}
+	}
+
+	skillScore := 50
+	if len(vacancySkills) > 0 {
+		skillScore = len(matched) * 100 / len(vacancySkills)
+	}
+
+	locationScore := 50
+	city := strings.ToLower(extractField(resumeText, "Город:"))
+	location := strings.ToLower(extractField(vacancyDescription, "Локация:"))
+	if city != "" && location != "" {
+		if strings.Contains(location, city) || strings.Contains(city, location) {
+			locationScore = 100
+		} else {
+			locationScore = 0
+		}
+	}
+
+	return &MatchingResult{
+		Score:      (skillScore*4 + locationScore) / 5,
+		Strengths:  cleanList(matched),
+		Weaknesses: cleanList(missing),
+		// Опыт и зарплату заглушка не сравнивает, поэтому их оценки не выдумываются
+		Breakdown: entity.AIScoreBreakdown{
+			Skills:   skillScore,
+			Location: locationScore,
+		},
+	}, nil
+}
+
+func (p *LocalProvider) GenerateResumeFromAI(input dto.GenerateResumeRequest) (*dto.GeneratedResumeResponse, error) {
+	skills := allMatches(input.Position, p.KnownSkills)
+	if len(skills) == 0 {
+		skills = []string{"Коммуникабельность", "Работа в команде", "Ответственность", "Обучаемость", "Git"}
+	}
+
+	return &dto.GeneratedResumeResponse{
+		About: fmt.Sprintf("Специалист на позицию «%s». Быстро обучаюсь и стремлюсь к профессиональному росту. "+
+			"Умею работать в команде и доводить задачи до результата.", input.Position),
+		Skills: skills,
+		WorkExperience: dto.WorkExperienceResponse{
+			CompanyName:    "Example",
+			Position:       input.Position,
+			StartDate:      "2022-01",
+			EndDate:        "2024-01",
+			Location:       "Алматы",
+			EmploymentType: "Полная занятость",
+			Description:    fmt.Sprintf("Выполнение задач на позиции «%s».", input.Position),
+		},
+	}, nil
+}
+
+func extractField(text, label string) string {
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, label) {
+			return strings.TrimSpace(strings.TrimPrefix(line, label))
+		}
+	}
+	return ""
+}
+
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func firstMatch(text string, candidates []string) string {
+	lower := strings.ToLower(text)
+	for _, candidate := range candidates {
+		if strings.Contains(lower, strings.ToLower(candidate)) {
+			return candidate
+		}
+	}
+	return ""
+}
+
+func allMatches(text string, candidates []string) []string {
+	words := make(map[string]bool)
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return unicode.IsSpace(r) || r == ',' || r == ';' || r == '(' || r == ')'
+	}) {
+		words[strings.Trim(word, ".:")] = true
+	}
+
+	var result []string
+	for _, candidate := range candidates {
+		if words[strings.ToLower(candidate)] {
+			result = append(result, candidate)
+		}
+	}
+	return result
+}
+
+func isLetters(runes []rune) bool {
+	for _, r := range runes {
+		if !unicode.IsLetter(r) && r != '-' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,163 @@
+package ai
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"jumyste-app-backend/internal/entity"
+	"jumyste-app-backend/pkg/helper"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+type MatchingResult struct {
+	Score      int
+	Strengths  []string
+	Weaknesses []string
+	Breakdown  entity.AIScoreBreakdown
+}
+
+var scoreSchema = map[string]interface{}{"type": "integer", "minimum": 0, "maximum": 100}
+
+// matchingResponseFormat — JSON-схема ответа матчера для OpenAI-совместимых API
+var matchingResponseFormat = &ResponseFormat{
+	Type: "json_schema",
+	JSONSchema: &JSONSchema{
+		Name:   "matching_result",
+		Strict: true,
+		Schema: map[string]interface{}{
+			"type":                 "object",
+			"additionalProperties": false,
+			"required":             []string{"score", "strengths", "weaknesses", "breakdown"},
+			"properties": map[string]interface{}{
+				"score":      scoreSchema,
+				"strengths":  map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}},
+				"weaknesses": map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}},
+				"breakdown": map[string]interface{}{
+					"type":                 "object",
+					"additionalProperties": false,
+					"required":             []string{"skills", "experience", "location", "salary"},
+					"properties": map[string]interface{}{
+						"skills":     scoreSchema,
+						"experience": scoreSchema,
+						"location":   scoreSchema,
+						"salary":     scoreSchema,
+					},
+				},
+			},
+		},
+	},
+}
+
+type rawMatchingResult struct {
+	Score      json.RawMessage `json:"score"`
+	Strengths  []string        `json:"strengths"`
+	Weaknesses []string        `json:"weaknesses"`
+	Breakdown  *struct {
+		Skills     json.RawMessage `json:"skills"`
+		Experience json.RawMessage `json:"experience"`
+		Location   json.RawMessage `json:"location"`
+		Salary     json.RawMessage `json:"salary"`
+	} `json:"breakdown"`
+}
+
+var leadingNumber = regexp.MustCompile(`^-?\d+(\.\d+)?`)
+
+// ParseMatchingResult проверяет ответ матчера по схеме и приводит оценки к диапазону 0–100
+func ParseMatchingResult(content string) (*MatchingResult, error) {
+	jsonStr := helper.ExtractJSONFromOpenAI(content)
+	if jsonStr == "" {
+		return nil, errors.New("failed to extract JSON from matching response")
+	}
+
+	var raw rawMatchingResult
+	if err := json.Unmarshal([]byte(jsonStr), &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse matching response: %w", err)
+	}
+
+	if raw.Score == nil {
+		return nil, errors.New("matching response has no score")
+	}
+	if raw.Breakdown == nil {
+		return nil, errors.New("matching response has no breakdown")
+	}
+
+	score, err := parseScore(raw.Score)
+	if err != nil {
+		return nil, fmt.Errorf("invalid score: %w", err)
+	}
+
+	result := &MatchingResult{
+		Score:      score,
+		Strengths:  cleanList(raw.Strengths),
+		Weaknesses: cleanList(raw.Weaknesses),
+	}
+	var experience, salary int
+	result.Breakdown.Experience, result.Breakdown.Salary = &experience, &salary
+
+	fields := []struct {
+		name  string
+		value json.RawMessage
+		dest  *int
+	}{
+		{"skills", raw.Breakdown.Skills, &result.Breakdown.Skills},
+		{"experience", raw.Breakdown.Experience, &experience},
+		{"location", raw.Breakdown.Location, &result.Breakdown.Location},
+		{"salary", raw.Breakdown.Salary, &salary},
+	}
+	for _, field := range fields {
+		if field.value == nil {
+			return nil, fmt.Errorf("matching response has no %s sub-score", field.name)
+		}
+		value, err := parseScore(field.value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s sub-score: %w", field.name, err)
+		}
+		*field.dest = value
+	}
+
+	return result, nil
+}
+
+// parseScore принимает число или строку вида "85" / "85/100" и ограничивает значение 0–100
+func parseScore(raw json.RawMessage) (int, error) {
+	var number float64
+	if err := json.Unmarshal(raw, &number); err != nil {
+		var text string
+		if err := json.Unmarshal(raw, &text); err != nil {
+			return 0, fmt.Errorf("unexpected value %s", string(raw))
+		}
+		match := leadingNumber.FindString(strings.TrimSpace(text))
+		if match == "" {
+			return 0, fmt.Errorf("unexpected value %q", text)
+		}
+		number, err = strconv.ParseFloat(match, 64)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return clampScore(int(math.Round(number))), nil
+}
+
+func clampScore(score int) int {
+	if score < 0 {
+		return 0
+	}
+	if score > 100 {
+		return 100
+	}
+	return score
+}
+
+func cleanList(items []string) []string {
+	result := make([]string, 0, len(items))
+	for _, item := range items {
+		if item = strings.TrimSpace(item); item != "" {
+			result = append(result, item)
+		}
+	}
+	return result
+}
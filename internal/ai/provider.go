@@ -0,0 +1,37 @@
+package ai
+
+import (
+	"fmt"
+	"jumyste-app-backend/config"
+	"jumyste-app-backend/internal/dto"
+	"jumyste-app-backend/pkg/logger"
+	"strings"
+)
+
+// Provider описывает все AI-операции, которые используют сервисы приложения
+type Provider interface {
+	AnalyzeResume(text string) (*Resume, error)
+	GenerateVacancyDescription(input dto.VacancyInput) (string, error)
+	GetMatchingScore(resumeText string, vacancyDescription string) (*MatchingResult, error)
+	GenerateResumeFromAI(input dto.GenerateResumeRequest) (*dto.GeneratedResumeResponse, error)
+}
+
+const (
+	ProviderOpenAI = "openai"
+	ProviderLocal  = "local"
+)
+
+// NewProvider выбирает реализацию по AI_PROVIDER из конфигурации. Неизвестное значение — ошибка:
+// тихий переход на локальный провайдер сохранял бы в проде выдуманные оценки
+func NewProvider(cfg config.AIConfig) (Provider, error) {
+	switch strings.ToLower(strings.TrimSpace(cfg.Provider)) {
+	case ProviderLocal:
+		logger.Log.Info("Using local AI provider")
+		return NewLocalProvider(), nil
+	case ProviderOpenAI, "":
+		logger.Log.Info("Using OpenAI-compatible AI provider", "base_url", cfg.BaseURL, "model", cfg.Model)
+		return NewOpenAIClient(cfg), nil
+	default:
+		return nil, fmt.Errorf("unknown AI_PROVIDER %q, expected %q or %q", cfg.Provider, ProviderOpenAI, ProviderLocal)
+	}
+}
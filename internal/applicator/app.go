@@ -1,16 +1,23 @@
 package applicator
 
 import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"github.com/redis/go-redis/v9"
+	"jumyste-app-backend/config"
 	"jumyste-app-backend/internal/ai"
 	"jumyste-app-backend/internal/database"
 	"jumyste-app-backend/internal/handler"
 	"jumyste-app-backend/internal/manager"
 	"jumyste-app-backend/internal/middleware"
+	"jumyste-app-backend/internal/policy"
 	"jumyste-app-backend/internal/repository"
 	"jumyste-app-backend/internal/service"
+	"jumyste-app-backend/pkg/jwtkeys"
 	"jumyste-app-backend/pkg/logger"
 	"jumyste-app-backend/pkg/redisPkg"
+	"jumyste-app-backend/pkg/storage"
 )
 
 type App struct {
@@ -20,6 +27,8 @@ type App struct {
 	JobAppRepo        *repository.JobApplicationRepository
 	DepartmentRepo    *repository.DepartmentsRepo
 	CompanyRepo       *repository.CompanyRepository
+	StageRepo         *repository.HiringStageRepository
+	InterviewRepo     *repository.InterviewRepository
 	AuthService       *service.AuthService
 	UserService       *service.UserService
 	VacancyService    *service.VacancyService
@@ -28,23 +37,31 @@ type App struct {
 	JobAppService     *service.JobApplicationService
 	DepartmentService *service.DepartmentsService
 	CompanyService    *service.CompanyService
+	StageService      *service.HiringStageService
+	InterviewService  *service.InterviewService
 	AuthHandler       *handler.AuthHandler
 	UserHandler       *handler.UserHandler
 	VacancyHandler    *handler.VacancyHandler
 	JobAppHandler     *handler.JobApplicationHandler
 	ResumeHandler     *handler.ResumeHandler
 	InvitationHandler *handler.InvitationHandler
-	AIClient          *ai.OpenAIClient
+	AIClient          ai.Provider
+	AIMatchingWorker  *service.AIMatchingWorker
 	ChatHandler       *handler.ChatHandler
 	MessageHandler    *handler.MessageHandler
 	DepartmentHandler *handler.DepartmentsHandler
 	CompanyHandler    *handler.CompanyHandler
+	StageHandler      *handler.HiringStageHandler
+	InterviewHandler  *handler.InterviewHandler
+	Policy            *policy.Policy
+	ReminderWorker    *service.InterviewReminderWorker
 	WSManager         *manager.WebSocketManager
 	WSHandler         *handler.WebSocketHandler
+	FileHandler       *handler.FileHandler
 	RedisClient       *redis.Client
 }
 
-func NewApp(authMiddleware *middleware.AuthMiddleware) *App {
+func NewApp(authMiddleware *middleware.AuthMiddleware, jwtKeys *jwtkeys.Manager) *App {
 	logger.Log.Info("Initializing database...")
 	database.InitDB()
 	database.RunMigrations()
@@ -52,9 +69,16 @@ func NewApp(authMiddleware *middleware.AuthMiddleware) *App {
 	logger.Log.Info("Initializing Redis client...")
 	redisClient := redisPkg.InitRedis()
 
+	logger.Log.Info("Initializing file storage...")
+	urlSigner := storage.NewURLSigner(signingSecret(config.AppConfig.Storage))
+	fileStorage := newStorage(config.AppConfig.Storage, urlSigner)
+
 	logger.Log.Info("Initializing AI client...")
 
-	aiClient := ai.NewOpenAIClient()
+	aiClient, err := ai.NewProvider(config.AppConfig.AI)
+	if err != nil {
+		panic(err)
+	}
 
 	logger.Log.Info("Initializing repositories...")
 	authRepo := repository.NewAuthRepository(database.DB)
@@ -68,35 +92,63 @@ func NewApp(authMiddleware *middleware.AuthMiddleware) *App {
 	jobAppRepo := repository.NewJobApplicationRepository(database.DB)
 	companyRepo := repository.NewCompanyRepository(database.DB)
 	departmentRepo := repository.NewDepartmentsRepo(database.DB)
+	stageRepo := repository.NewHiringStageRepository(database.DB)
+	interviewRepo := repository.NewInterviewRepository(database.DB)
+	sessionRepo := repository.NewSessionRepository(redisClient)
+
+	accessPolicy := policy.NewPolicy(vacancyRepo, jobAppRepo, companyRepo, hrRepo)
 
 	logger.Log.Info("Initializing services...")
-	authService := service.NewAuthService(authRepo, redisClient, invitationRepo, hrRepo)
-	userService := service.NewUserService(userRepo, companyRepo)
-	vacancyService := service.NewVacancyService(vacancyRepo, aiClient)
+	tokenRevocations := newTokenRevocations(redisClient)
+	authMiddleware.SetTokenRevocations(tokenRevocations)
+	tokenRevoker := service.NewTokenRevoker(tokenRevocations, sessionRepo)
+
+	authService := service.NewAuthService(authRepo, sessionRepo, invitationRepo, hrRepo, tokenRevoker)
+	userService := service.NewUserService(userRepo, companyRepo, tokenRevoker)
+	presenceStore := newPresenceStore(redisClient)
+	chatService := service.NewChatService(chatRepo, messageRepo, hrRepo, userRepo, accessPolicy, presenceStore)
+	attachmentService := service.NewAttachmentService(fileStorage, config.AppConfig.Storage)
+	messageService := service.NewMessageService(messageRepo, chatRepo, userRepo, attachmentService)
+
+	// Менеджер WebSocket нужен сервисам, которые публикуют события в реальном времени
+	logger.Log.Info("Initializing WebSocket manager...")
+	wsManager := manager.NewWebSocketManager(chatService, messageService, newEventBus(redisClient), presenceStore, userRepo, config.AppConfig.WebSocket)
+	go wsManager.Run()
+	chatService.Realtime = wsManager
+
+	vacancyService := service.NewVacancyService(vacancyRepo, aiClient, wsManager)
 	invitationService := service.NewInvitationService(invitationRepo)
-	chatService := service.NewChatService(chatRepo)
-	messageService := service.NewMessageService(messageRepo)
 	resumeService := service.NewResumeService(aiClient, resumeRepo)
-	jobAppService := service.NewJobApplicationService(jobAppRepo, resumeRepo, vacancyRepo, aiClient, chatRepo, messageRepo)
+	aiMatchingWorker := service.NewAIMatchingWorker(jobAppRepo, resumeRepo, vacancyRepo, aiClient, config.AppConfig.AI)
+	stageService := service.NewHiringStageService(stageRepo, vacancyRepo)
+	jobAppService := service.NewJobApplicationService(jobAppRepo, resumeRepo, vacancyRepo, userRepo, chatRepo, messageRepo, aiMatchingWorker, stageService, wsManager, config.AppConfig.JobApp)
 	departmentService := service.NewDepartmentsService(departmentRepo)
-	companyService := service.NewCompanyService(companyRepo)
+	companyService := service.NewCompanyService(companyRepo, hrRepo, tokenRevoker)
+	interviewService := service.NewInterviewService(interviewRepo, jobAppRepo, vacancyRepo, userRepo, chatRepo, messageRepo, jobAppService, wsManager, config.AppConfig.Interview)
+	jobAppService.Interviews = interviewService
+	reminderWorker := service.NewInterviewReminderWorker(interviewService, config.AppConfig.Interview)
 
-	logger.Log.Info("Initializing WebSocket manager...")
-	wsManager := manager.NewWebSocketManager()
-	go wsManager.Run()
+	logger.Log.Info("Starting AI matching worker...")
+	aiMatchingWorker.Start(context.Background())
+
+	logger.Log.Info("Starting interview reminder worker...")
+	reminderWorker.Start(context.Background())
 
 	logger.Log.Info("Initializing handlers...")
-	authHandler := handler.NewAuthHandler(authService)
+	authHandler := handler.NewAuthHandler(authService, jwtKeys)
 	userHandler := handler.NewUserHandler(userService)
-	vacancyHandler := handler.NewVacancyHandler(vacancyService)
+	vacancyHandler := handler.NewVacancyHandler(vacancyService, accessPolicy)
 	invitationHandler := handler.NewInvitationHandler(invitationService)
 	chatHandler := handler.NewChatHandler(chatService)
 	messageHandler := handler.NewMessageHandler(messageService, wsManager)
-	resumeHandler := handler.NewResumeHandler(resumeService, aiClient)
-	wsHandler := handler.NewWebSocketHandler(wsManager, authMiddleware)
-	jobAppHandler := handler.NewJobApplicationHandler(jobAppService, resumeService)
+	resumeHandler := handler.NewResumeHandler(resumeService, aiClient, accessPolicy)
+	wsHandler := handler.NewWebSocketHandler(wsManager, authMiddleware, chatService)
+	jobAppHandler := handler.NewJobApplicationHandler(jobAppService, resumeService, accessPolicy)
 	departmentHandler := handler.NewDepartmentsHandler(departmentService)
-	companyHandler := handler.NewCompanyHandler(companyService)
+	companyHandler := handler.NewCompanyHandler(companyService, accessPolicy)
+	stageHandler := handler.NewHiringStageHandler(stageService)
+	interviewHandler := handler.NewInterviewHandler(interviewService)
+	fileHandler := handler.NewFileHandler(fileStorage, urlSigner)
 
 	logger.Log.Info("Application initialized successfully")
 
@@ -106,6 +158,8 @@ func NewApp(authMiddleware *middleware.AuthMiddleware) *App {
 		VacancyRepo:       vacancyRepo,
 		JobAppRepo:        jobAppRepo,
 		DepartmentRepo:    departmentRepo,
+		StageRepo:         stageRepo,
+		InterviewRepo:     interviewRepo,
 		AuthService:       authService,
 		UserService:       userService,
 		VacancyService:    vacancyService,
@@ -113,6 +167,8 @@ func NewApp(authMiddleware *middleware.AuthMiddleware) *App {
 		JobAppService:     jobAppService,
 		InvitationService: invitationService,
 		DepartmentService: departmentService,
+		StageService:      stageService,
+		InterviewService:  interviewService,
 		AuthHandler:       authHandler,
 		UserHandler:       userHandler,
 		VacancyHandler:    vacancyHandler,
@@ -123,9 +179,84 @@ func NewApp(authMiddleware *middleware.AuthMiddleware) *App {
 		JobAppHandler:     jobAppHandler,
 		DepartmentHandler: departmentHandler,
 		CompanyHandler:    companyHandler,
+		StageHandler:      stageHandler,
+		InterviewHandler:  interviewHandler,
+		Policy:            accessPolicy,
+		ReminderWorker:    reminderWorker,
 		AIClient:          aiClient,
+		AIMatchingWorker:  aiMatchingWorker,
 		WSManager:         wsManager,
 		WSHandler:         wsHandler,
+		FileHandler:       fileHandler,
 		RedisClient:       redisClient,
 	}
 }
+
+// newEventBus выбирает шину событий WebSocket: Redis, чтобы события доходили до соединений
+// на всех экземплярах, или шину в памяти, если Redis недоступен
+func newEventBus(redisClient *redis.Client) manager.Bus {
+	if redisClient == nil {
+		logger.Log.Warn("Redis is unavailable, WebSocket events are delivered within this instance only")
+		return manager.NewMemoryBus()
+	}
+	return manager.NewRedisBus(redisClient, config.AppConfig.WebSocket.BusChannel)
+}
+
+// newPresenceStore выбирает хранилище присутствия: в Redis оно общее для всех экземпляров
+func newPresenceStore(redisClient *redis.Client) manager.PresenceStore {
+	if redisClient == nil {
+		return manager.NewMemoryPresence()
+	}
+	return manager.NewRedisPresence(redisClient)
+}
+
+// newTokenRevocations выбирает хранилище отозванных токенов: в Redis отзыв действует на всех экземплярах
+func newTokenRevocations(redisClient *redis.Client) repository.TokenRevocationStore {
+	if redisClient == nil {
+		logger.Log.Warn("Redis is unavailable, revoked tokens are tracked within this instance only")
+		return repository.NewMemoryTokenRevocations()
+	}
+	return repository.NewRedisTokenRevocations(redisClient)
+}
+
+// signingSecret — ключ подписи ссылок на файлы. Он отдельный от ключа JWT; без него в production
+// локальное хранилище не стартует, иначе используется случайный ключ, и ссылки живут до рестарта экземпляра
+func signingSecret(cfg config.StorageConfig) string {
+	if cfg.SigningSecret != "" {
+		return cfg.SigningSecret
+	}
+	if cfg.Driver != "s3" && config.AppConfig.AppEnv.AppEnv == "production" {
+		panic("STORAGE_SIGNING_SECRET is required for local file storage")
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		panic(err)
+	}
+	logger.Log.Warn("STORAGE_SIGNING_SECRET is not set, file links are signed with a random key and expire on restart")
+	return hex.EncodeToString(secret)
+}
+
+// newStorage выбирает хранилище вложений; при неверной настройке приложение не стартует
+func newStorage(cfg config.StorageConfig, signer *storage.URLSigner) storage.Storage {
+	if cfg.Driver == "s3" {
+		s3Storage, err := storage.NewS3Storage(storage.S3Config{
+			Endpoint:  cfg.S3Endpoint,
+			Region:    cfg.S3Region,
+			Bucket:    cfg.S3Bucket,
+			AccessKey: cfg.S3AccessKey,
+			SecretKey: cfg.S3SecretKey,
+			PathStyle: cfg.S3PathStyle,
+		})
+		if err != nil {
+			panic(err)
+		}
+		return s3Storage
+	}
+
+	localStorage, err := storage.NewLocalStorage(cfg.LocalDir, cfg.PublicBaseURL, signer)
+	if err != nil {
+		panic(err)
+	}
+	return localStorage
+}
@@ -23,6 +23,7 @@ type LoginRequest struct {
 type LoginResponse struct {
 	AccessToken  string `json:"access_token" example:"eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9..."`
 	RefreshToken string `json:"refresh_token" example:"eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9..."`
+	SessionID    string `json:"session_id" example:"9f2c4e1a7b3d5f60a1b2c3d4e5f60718"`
 }
 
 type RequestPasswordResetRequest struct {
@@ -47,3 +48,16 @@ type ResetPasswordResponse struct {
 type RefreshTokenRequest struct {
 	RefreshToken string `json:"refresh_token"`
 }
+
+type LogoutAllResponse struct {
+	Revoked int `json:"revoked" example:"3"`
+}
+
+type VerifyEmailRequest struct {
+	Email string `json:"email" binding:"required,email" example:"user@example.com"`
+	Code  string `json:"code" binding:"required,len=6" example:"123456"`
+}
+
+type ResendVerificationRequest struct {
+	Email string `json:"email" binding:"required,email" example:"user@example.com"`
+}
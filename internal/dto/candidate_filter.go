@@ -2,7 +2,10 @@ package dto
 
 type CandidateFilter struct {
 	AIMatchMin int      `form:"ai_match"`
+	RuleMin    int      `form:"rule_match"`
+	VacancyID  int      `form:"vacancy_id"`
 	Skills     []string `form:"skills"`
 	City       string   `form:"city"`
 	Position   string   `form:"position"`
+	CompanyID  int      `form:"-"`
 }
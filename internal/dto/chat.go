@@ -11,3 +11,24 @@ type ChatResponse struct {
 type CreateChatRequest struct {
 	SecondUserId int `json:"second_user_id" binding:"required"`
 }
+
+// CreateGroupChatRequest — групповой чат; internal создаёт закрытый чат HR своей компании,
+// application_id привязывает внутренний чат к обсуждаемому отклику
+type CreateGroupChatRequest struct {
+	Title         string `json:"title" binding:"required,max=255"`
+	UserIDs       []int  `json:"user_ids" binding:"required,min=1"`
+	Internal      bool   `json:"internal"`
+	ApplicationID *int   `json:"application_id"`
+}
+
+type UpdateChatRequest struct {
+	Title string `json:"title" binding:"required,max=255"`
+}
+
+type AddParticipantsRequest struct {
+	UserIDs []int `json:"user_ids" binding:"required,min=1"`
+}
+
+type SetParticipantRoleRequest struct {
+	Role string `json:"role" binding:"required,oneof=member admin"`
+}
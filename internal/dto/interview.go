@@ -0,0 +1,44 @@
+package dto
+
+import (
+	"jumyste-app-backend/internal/entity"
+	"time"
+)
+
+type CreateInterviewRequest struct {
+	ApplicationID  int       `json:"application_id" binding:"required" example:"1"`
+	InterviewerIDs []int     `json:"interviewer_ids" example:"5,7"`
+	StartAt        time.Time `json:"start_at" binding:"required" example:"2025-05-20T10:00:00+05:00"`
+	EndAt          time.Time `json:"end_at" binding:"required" example:"2025-05-20T11:00:00+05:00"`
+	Format         string    `json:"format" binding:"required,oneof=online offline phone" example:"online"`
+	Location       *string   `json:"location" example:"Алматы, пр. Абая 10, офис 5"`
+	MeetingLink    *string   `json:"meeting_link" example:"https://meet.google.com/abc-defg-hij"`
+	Notes          *string   `json:"notes" example:"Техническое интервью с тимлидом"`
+}
+
+// UpdateInterviewRequest — частичное обновление; отсутствующие поля не меняются
+type UpdateInterviewRequest struct {
+	InterviewerIDs []int      `json:"interviewer_ids"`
+	StartAt        *time.Time `json:"start_at"`
+	EndAt          *time.Time `json:"end_at"`
+	Format         *string    `json:"format" binding:"omitempty,oneof=online offline phone"`
+	Location       *string    `json:"location"`
+	MeetingLink    *string    `json:"meeting_link"`
+	Notes          *string    `json:"notes"`
+	Status         *string    `json:"status" binding:"omitempty,oneof=scheduled completed" example:"completed"`
+}
+
+type CancelInterviewRequest struct {
+	Reason string `json:"reason" example:"Кандидат попросил перенести"`
+}
+
+type InterviewListFilter struct {
+	ApplicationID int       `form:"application_id"`
+	From          time.Time `form:"from" time_format:"2006-01-02T15:04:05Z07:00"`
+	To            time.Time `form:"to" time_format:"2006-01-02T15:04:05Z07:00"`
+}
+
+type InterviewConflictResponse struct {
+	Error     string                     `json:"error" example:"interviewer has another interview at this time"`
+	Conflicts []entity.InterviewConflict `json:"conflicts"`
+}
@@ -1,6 +1,9 @@
 package dto
 
-import "time"
+import (
+	"jumyste-app-backend/internal/entity"
+	"time"
+)
 
 type JobApplicationResponse struct {
 	ID        int       `json:"id"`
@@ -13,19 +16,63 @@ type JobApplicationResponse struct {
 	AppliedAt time.Time `json:"applied_at"`
 }
 
+// DuplicateApplicationResponse — отклик на вакансию уже есть; ReapplyAt задан, если идёт пауза после отказа
+type DuplicateApplicationResponse struct {
+	Error         string     `json:"error" example:"you have already applied for this vacancy"`
+	ApplicationID int        `json:"application_id,omitempty" example:"42"`
+	ReapplyAt     *time.Time `json:"reapply_at,omitempty"`
+}
+
 type JobApplicationWithResumeResponse struct {
-	ID                int            `json:"id"`
-	UserID            int            `json:"user_id"`
-	VacancyID         int            `json:"vacancy_id"`
-	FirstName         string         `json:"first_name"`
-	LastName          string         `json:"last_name"`
-	Email             string         `json:"email"`
-	Status            string         `json:"status"`
-	AppliedAt         string         `json:"applied_at"`
-	Resume            ResumeResponse `json:"resume"`
-	AIMatchingScore   int            `json:"ai_matching_score"`
-	AIStrengths       string         `json:"ai_strengths,omitempty"`
-	AIMatchWeaknesses string         `json:"ai_weaknesses,omitempty"`
+	ID                int                        `json:"id"`
+	UserID            int                        `json:"user_id"`
+	VacancyID         int                        `json:"vacancy_id"`
+	FirstName         string                     `json:"first_name"`
+	LastName          string                     `json:"last_name"`
+	Email             string                     `json:"email"`
+	Status            string                     `json:"status"`
+	StageID           *int                       `json:"stage_id"`
+	Stage             *string                    `json:"stage"`
+	AppliedAt         string                     `json:"applied_at"`
+	Resume            ResumeResponse             `json:"resume"`
+	AIMatchingScore   int                        `json:"ai_matching_score"`
+	AIStrengths       []string                   `json:"ai_strengths"`
+	AIMatchWeaknesses []string                   `json:"ai_weaknesses"`
+	AIBreakdown       *entity.AIScoreBreakdown   `json:"ai_breakdown,omitempty"`
+	AIStatus          string                     `json:"ai_status"`
+	RuleScore         *int                       `json:"rule_score"`
+	RuleBreakdown     *entity.RuleScoreBreakdown `json:"rule_breakdown,omitempty"`
+}
+
+type UpdateApplicationStatusRequest struct {
+	Comment string `json:"comment" example:"Хорошо прошёл скрининг"`
+}
+
+type StatusTransitionErrorResponse struct {
+	Error   string   `json:"error" example:"status transition is not allowed: new -> accepted"`
+	Allowed []string `json:"allowed" example:"screening,invited,interview,rejected"`
+}
+
+type HiringStageInput struct {
+	ID     int    `json:"id" example:"0"`
+	Name   string `json:"name" binding:"required" example:"Техническое интервью"`
+	Status string `json:"status" binding:"required" example:"interview"`
+}
+
+// ReplaceStagesRequest — новая воронка целиком; без vacancy_id меняется воронка компании
+type ReplaceStagesRequest struct {
+	VacancyID *int               `json:"vacancy_id" example:"12"`
+	Stages    []HiringStageInput `json:"stages" binding:"required,dive"`
+}
+
+type StageColumn struct {
+	Stage        entity.HiringStage                 `json:"stage"`
+	Count        int                                `json:"count"`
+	Applications []JobApplicationWithResumeResponse `json:"applications"`
+}
+
+type RescoreResponse struct {
+	Queued int `json:"queued" example:"3"`
 }
 
 type JobAppStatusAnalytics struct {
@@ -0,0 +1,57 @@
+package dto
+
+import "jumyste-app-backend/internal/entity"
+
+const (
+	DefaultMessagePageSize = 50
+	MaxMessagePageSize     = 100
+)
+
+// MessagePageQuery — курсор истории чата: before — сообщения старше указанного,
+// after — новее; без курсора возвращается последняя страница
+type MessagePageQuery struct {
+	Before int `form:"before" binding:"omitempty,min=1"`
+	After  int `form:"after" binding:"omitempty,min=1"`
+	Limit  int `form:"limit" binding:"omitempty,min=1,max=100"`
+}
+
+// MessagePage — страница истории по возрастанию id; next_cursor передаётся в тот же параметр
+// (before или after), чтобы получить следующую страницу в том же направлении
+type MessagePage struct {
+	Messages   []entity.Message `json:"messages"`
+	HasMore    bool             `json:"has_more"`
+	NextCursor *int             `json:"next_cursor,omitempty"`
+}
+
+type MessageSearchQuery struct {
+	Query  string `form:"q" binding:"required"`
+	ChatID int    `form:"chat_id" binding:"omitempty,min=1"`
+	Before int    `form:"before" binding:"omitempty,min=1"`
+	Limit  int    `form:"limit" binding:"omitempty,min=1,max=100"`
+}
+
+// MessageSearchPage — результаты поиска от новых к старым
+type MessageSearchPage struct {
+	Results    []entity.MessageSearchResult `json:"results"`
+	HasMore    bool                         `json:"has_more"`
+	NextCursor *int                         `json:"next_cursor,omitempty"`
+}
+
+type EditMessageRequest struct {
+	Content string `json:"content" binding:"required" example:"Исправленный текст"`
+}
+
+type MarkChatReadRequest struct {
+	UpToMessageID int `json:"up_to_message_id" binding:"required,min=1" example:"120"`
+}
+
+type MarkChatReadResponse struct {
+	ChatID      int `json:"chat_id"`
+	UnreadCount int `json:"unread_count"`
+}
+
+// UnreadBadge — счётчик для иконки входящих
+type UnreadBadge struct {
+	Total int `json:"total"`
+	Chats int `json:"chats"`
+}
@@ -0,0 +1,8 @@
+package dto
+
+// UpdateUserRequest — поля профиля, которые пользователь меняет сам; отсутствующие поля не меняются
+type UpdateUserRequest struct {
+	FirstName      *string `json:"first_name" binding:"omitempty,min=1,max=100" example:"John"`
+	LastName       *string `json:"last_name" binding:"omitempty,min=1,max=100" example:"Doe"`
+	ProfilePicture *string `json:"profile_picture" example:"/static/images/profile.jpg"`
+}
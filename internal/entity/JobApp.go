@@ -2,19 +2,64 @@ package entity
 
 import "time"
 
+const (
+	ApplicationStatusNew       = "new"
+	ApplicationStatusScreening = "screening"
+	ApplicationStatusInvited   = "invited"
+	ApplicationStatusInterview = "interview"
+	ApplicationStatusOffer     = "offer"
+	ApplicationStatusAccepted  = "accepted"
+	ApplicationStatusRejected  = "rejected"
+	ApplicationStatusWithdrawn = "withdrawn"
+)
+
+const (
+	AIStatusPending    = "pending"
+	AIStatusProcessing = "processing"
+	AIStatusDone       = "done"
+	AIStatusFailed     = "failed"
+)
+
 type JobApplication struct {
-	ID              int       `json:"id"`
-	UserID          int       `json:"user_id"`
-	VacancyID       int       `json:"vacancy_id"`
-	FirstName       string    `json:"first_name"`
-	LastName        string    `json:"last_name"`
-	Email           string    `json:"email"`
-	Status          string    `json:"status"`
-	AppliedAt       time.Time `json:"applied_at"`
-	ResumeID        int       `json:"resume_id"`
-	AIMatchingScore int       `json:"ai_matching_score"`
-	AIStrengths     string    `json:"ai_strengths"`
-	AIWeaknesses    string    `json:"ai_weaknesses"`
+	ID              int                 `json:"id"`
+	UserID          int                 `json:"user_id"`
+	VacancyID       int                 `json:"vacancy_id"`
+	FirstName       string              `json:"first_name"`
+	LastName        string              `json:"last_name"`
+	Email           string              `json:"email"`
+	Status          string              `json:"status"`
+	AppliedAt       time.Time           `json:"applied_at"`
+	ResumeID        int                 `json:"resume_id"`
+	AIMatchingScore int                 `json:"ai_matching_score"`
+	AIStrengths     []string            `json:"ai_strengths"`
+	AIWeaknesses    []string            `json:"ai_weaknesses"`
+	AIBreakdown     *AIScoreBreakdown   `json:"ai_breakdown,omitempty"`
+	AIStatus        string              `json:"ai_status"`
+	AIAttempts      int                 `json:"ai_attempts"`
+	AIError         *string             `json:"ai_error,omitempty"`
+	StageID         *int                `json:"stage_id,omitempty"`
+	RuleScore       *int                `json:"rule_score,omitempty"`
+	RuleBreakdown   *RuleScoreBreakdown `json:"rule_breakdown,omitempty"`
+	IdempotencyKey  *string             `json:"-"`
+}
+
+// AIScoreBreakdown — частные оценки AI по направлениям, каждая от 0 до 100;
+// null — провайдер это направление не оценивал (локальный провайдер не оценивает опыт и зарплату)
+type AIScoreBreakdown struct {
+	Skills     int  `json:"skills"`
+	Experience *int `json:"experience"`
+	Location   int  `json:"location"`
+	Salary     *int `json:"salary"`
+}
+
+// RuleScoreBreakdown объясняет rule_score: частные оценки 0–100 и совпавшие/недостающие навыки
+type RuleScoreBreakdown struct {
+	Skills           int      `json:"skills"`
+	Experience       int      `json:"experience"`
+	Location         int      `json:"location"`
+	MatchedSkills    []string `json:"matched_skills"`
+	MissingSkills    []string `json:"missing_skills"`
+	ExperienceMonths int      `json:"experience_months"`
 }
 
 type JobApplicationWithResume struct {
@@ -23,7 +68,41 @@ type JobApplicationWithResume struct {
 	User   User   `json:"user"`
 }
 
+// CandidateApplication — отклик глазами соискателя: вакансия, компания, текущий статус и история
+type CandidateApplication struct {
+	ID           int                          `json:"id"`
+	VacancyID    int                          `json:"vacancy_id"`
+	VacancyTitle string                       `json:"vacancy_title"`
+	CompanyID    int                          `json:"company_id"`
+	CompanyName  string                       `json:"company_name"`
+	Status       string                       `json:"status"`
+	StageID      *int                         `json:"stage_id"`
+	Stage        *string                      `json:"stage"`
+	AppliedAt    time.Time                    `json:"applied_at"`
+	Timeline     []JobApplicationStatusChange `json:"timeline"`
+}
+
+// JobApplicationStatusChange — запись истории статусов отклика; FromStatus пуст для создания отклика
+type JobApplicationStatusChange struct {
+	ID            int       `json:"id"`
+	ApplicationID int       `json:"application_id"`
+	FromStatus    *string   `json:"from_status"`
+	ToStatus      string    `json:"to_status"`
+	FromStageID   *int      `json:"from_stage_id"`
+	FromStage     *string   `json:"from_stage"`
+	ToStageID     *int      `json:"to_stage_id"`
+	ToStage       *string   `json:"to_stage"`
+	ActorID       *int      `json:"actor_id"`
+	ActorName     *string   `json:"actor_name"`
+	ActorRoleID   *int      `json:"actor_role_id"`
+	Comment       *string   `json:"comment"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// ApplicationStatusStat — количество откликов на этапе воронки; Stage пуст для откликов без этапа
 type ApplicationStatusStat struct {
+	Stage      string `json:"stage"`
+	Position   int    `json:"position"`
 	Status     string `json:"status"`
 	Count      int    `json:"count"`
 	Percentage int    `json:"percentage"`
@@ -2,12 +2,38 @@ package entity
 
 import "time"
 
+// Роли участников чата
+const (
+	ChatRoleMember = "member"
+	ChatRoleAdmin  = "admin"
+)
+
 type Chat struct {
-	ID            int            `json:"id"`
+	ID      int     `json:"id"`
+	IsGroup bool    `json:"is_group"`
+	Title   *string `json:"title,omitempty"`
+	// Внутренний чат HR одной компании: соискатели в него не добавляются
+	IsInternal    bool           `json:"is_internal"`
+	CompanyID     *int           `json:"company_id,omitempty"`
+	CreatedBy     *int           `json:"-"`
+	VacancyID     *int           `json:"vacancy_id,omitempty"`
+	ApplicationID *int           `json:"application_id,omitempty"`
+	Context       *ChatContext   `json:"context,omitempty"`
 	Users         []UserResponse `json:"users"`
 	CreatedAt     time.Time      `json:"created_at"`
 	UpdatedAt     time.Time      `json:"updated_at"`
 	LastMessage   string         `json:"last_message"`
 	LastMessageAt time.Time      `json:"last_message_at"`
-	IsRead        bool           `json:"is_read"`
+	IsRead        bool           `json:"is_read"` // нет непрочитанных, оставлено для старых клиентов
+	UnreadCount   int            `json:"unread_count"`
+}
+
+// ChatContext — вакансия и отклик, к которым относится чат, для шапки чата
+type ChatContext struct {
+	VacancyID         int     `json:"vacancy_id"`
+	VacancyTitle      string  `json:"vacancy_title"`
+	VacancyStatus     string  `json:"vacancy_status"`
+	ApplicationID     *int    `json:"application_id,omitempty"`
+	ApplicationStatus *string `json:"application_status,omitempty"`
+	StageID           *int    `json:"stage_id,omitempty"`
 }
@@ -0,0 +1,16 @@
+package entity
+
+import "time"
+
+// HiringStage — этап воронки найма. Этапы без VacancyID образуют воронку компании,
+// этапы с VacancyID переопределяют её для конкретной вакансии.
+// Status связывает этап с базовым статусом отклика, по которому проверяются переходы.
+type HiringStage struct {
+	ID        int       `json:"id"`
+	CompanyID int       `json:"company_id"`
+	VacancyID *int      `json:"vacancy_id,omitempty"`
+	Name      string    `json:"name"`
+	Status    string    `json:"status"`
+	Position  int       `json:"position"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,41 @@
+package entity
+
+import "time"
+
+const (
+	InterviewStatusScheduled = "scheduled"
+	InterviewStatusCompleted = "completed"
+	InterviewStatusCancelled = "cancelled"
+)
+
+const (
+	InterviewFormatOnline  = "online"
+	InterviewFormatOffline = "offline"
+	InterviewFormatPhone   = "phone"
+)
+
+type Interview struct {
+	ID             int            `json:"id"`
+	ApplicationID  int            `json:"application_id"`
+	OrganizerID    *int           `json:"organizer_id"`
+	Interviewers   []UserResponse `json:"interviewers"`
+	StartAt        time.Time      `json:"start_at"`
+	EndAt          time.Time      `json:"end_at"`
+	Format         string         `json:"format"`
+	Location       *string        `json:"location,omitempty"`
+	MeetingLink    *string        `json:"meeting_link,omitempty"`
+	Notes          *string        `json:"notes,omitempty"`
+	Status         string         `json:"status"`
+	Sequence       int            `json:"-"`
+	ReminderSentAt *time.Time     `json:"reminder_sent_at,omitempty"`
+	CreatedAt      time.Time      `json:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at"`
+}
+
+// InterviewConflict — пересечение с другим интервью того же интервьюера
+type InterviewConflict struct {
+	InterviewerID int       `json:"interviewer_id"`
+	InterviewID   int       `json:"interview_id"`
+	StartAt       time.Time `json:"start_at"`
+	EndAt         time.Time `json:"end_at"`
+}
@@ -13,17 +13,61 @@ const (
 	VideoMessage MessageType = "video"
 	AudioMessage MessageType = "audio"
 	FileMessage  MessageType = "file"
+	// SystemMessage — служебная запись чата (создание, изменение состава участников)
+	SystemMessage MessageType = "system"
 )
 
 type Message struct {
-	ID        int           `gorm:"primaryKey" json:"id"`
-	ChatID    int           `gorm:"index" json:"chat_id"`
-	SenderID  int           `gorm:"index" json:"sender_id"`
-	Type      MessageType   `gorm:"type:varchar(255)" json:"type"`
-	Content   *string       `json:"content,omitempty"`
-	FileURL   *string       `json:"file_url,omitempty"`
-	ReadBy    pq.Int64Array `gorm:"type:integer[]" json:"read_by"`
-	IsMine    bool          `gorm:"type:boolean" json:"is_mine"`
-	CreatedAt time.Time     `json:"created_at"`
-	UpdatedAt time.Time     `json:"updated_at"`
+	ID         int           `gorm:"primaryKey" json:"id"`
+	ChatID     int           `gorm:"index" json:"chat_id"`
+	SenderID   int           `gorm:"index" json:"sender_id"`
+	Type       MessageType   `gorm:"type:varchar(255)" json:"type"`
+	Content    *string       `json:"content,omitempty"`
+	FileURL    *string       `json:"file_url,omitempty"`
+	ReadBy     pq.Int64Array `gorm:"type:integer[]" json:"read_by"`
+	IsMine     bool          `gorm:"type:boolean" json:"is_mine"`
+	Attachment *Attachment   `json:"attachment,omitempty"`
+	ReplyToID  *int          `json:"reply_to_id,omitempty"`
+	ReplyTo    *MessageQuote `json:"reply_to,omitempty"`
+	EditedAt   *time.Time    `json:"edited_at,omitempty"`
+	IsDeleted  bool          `json:"is_deleted"`
+	CreatedAt  time.Time     `json:"created_at"`
+	UpdatedAt  time.Time     `json:"updated_at"`
+}
+
+// MessageQuote — цитата сообщения, на которое отвечают
+type MessageQuote struct {
+	ID        int         `json:"id"`
+	SenderID  int         `json:"sender_id"`
+	Type      MessageType `json:"type"`
+	Content   *string     `json:"content,omitempty"`
+	IsDeleted bool        `json:"is_deleted"`
+}
+
+// MessageEdit — предыдущая версия текста сообщения
+type MessageEdit struct {
+	ID        int       `json:"id"`
+	MessageID int       `json:"message_id"`
+	Content   *string   `json:"content"`
+	EditedAt  time.Time `json:"edited_at"`
+}
+
+// Attachment — файл сообщения в хранилище. Ключи наружу не отдаются: клиент получает
+// подписанные ссылки, которые действуют до ExpiresAt.
+type Attachment struct {
+	Key          string    `json:"-"`
+	ThumbnailKey *string   `json:"-"`
+	Name         string    `json:"name"`
+	MimeType     string    `json:"mime_type"`
+	Size         int64     `json:"size"`
+	URL          string    `json:"url"`
+	ThumbnailURL *string   `json:"thumbnail_url,omitempty"`
+	ExpiresAt    time.Time `json:"expires_at"`
+}
+
+// MessageSearchResult — найденное сообщение с фрагментом текста, где совпадения обёрнуты в <mark>.
+// Текст фрагмента экранирован, его можно вставлять как HTML.
+type MessageSearchResult struct {
+	Message
+	Snippet string `json:"snippet"`
 }
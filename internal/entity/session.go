@@ -0,0 +1,18 @@
+package entity
+
+import "time"
+
+// Session — вход пользователя с одного устройства; refresh-токены сессии ротируются при каждом обновлении
+type Session struct {
+	ID         string    `json:"id"`
+	UserID     int       `json:"-"`
+	UserAgent  string    `json:"user_agent"`
+	IP         string    `json:"ip"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	// Current — сессия, из которой пришёл запрос
+	Current bool `json:"current"`
+	// TokenID — jti последнего выданного refresh-токена
+	TokenID string `json:"-"`
+}
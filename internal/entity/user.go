@@ -12,6 +12,7 @@ type User struct {
 	RoleId         int    `json:"role_id"`
 	CompanyID      int    `json:"company_id"`
 	DepID          int    `json:"department_id"`
+	EmailVerified  bool   `json:"email_verified"`
 }
 
 type UserResponse struct {
@@ -23,4 +24,10 @@ type UserResponse struct {
 	Company        *Company  `json:"company"`
 	CreatedAt      time.Time `json:"created_at"`
 	IsOwner        bool      `json:"is_owner"`
+	EmailVerified  bool      `json:"email_verified"`
+	// Присутствие заполняется только в списке чатов
+	IsOnline   *bool      `json:"is_online,omitempty"`
+	LastSeenAt *time.Time `json:"last_seen_at,omitempty"`
+	// Роль участника заполняется только в составе чата
+	ChatRole string `json:"chat_role,omitempty"`
 }
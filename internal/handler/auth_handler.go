@@ -6,23 +6,28 @@ import (
 	"jumyste-app-backend/internal/dto"
 	"jumyste-app-backend/internal/entity"
 	"jumyste-app-backend/internal/service"
+	"jumyste-app-backend/pkg/jwtkeys"
 	"jumyste-app-backend/pkg/logger"
 	"jumyste-app-backend/utils"
 	"log/slog"
 	"net/http"
+	"strconv"
+	"time"
 )
 
 type AuthHandler struct {
 	AuthService *service.AuthService
+	Keys        *jwtkeys.Manager
 }
 
-func NewAuthHandler(authService *service.AuthService) *AuthHandler {
-	return &AuthHandler{AuthService: authService}
+func NewAuthHandler(authService *service.AuthService, keys *jwtkeys.Manager) *AuthHandler {
+	return &AuthHandler{AuthService: authService, Keys: keys}
 }
 
 // Login godoc
 // @Summary User login
 // @Description Authenticates a user and returns access and refresh tokens.
+// @Description Every login opens a separate device session; sessions on other devices stay valid.
 // @Tags Auth
 // @Accept json
 // @Produce json
@@ -42,16 +47,13 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 
-	accessToken, refreshToken, err := h.AuthService.LoginUser(credentials.Email, credentials.Password)
+	tokens, err := h.AuthService.LoginUser(credentials.Email, credentials.Password, clientInfo(c))
 	if err != nil {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
 		return
 	}
 
-	c.JSON(http.StatusOK, dto.LoginResponse{
-		AccessToken:  accessToken,
-		RefreshToken: refreshToken,
-	})
+	c.JSON(http.StatusOK, loginResponse(tokens))
 }
 
 // RequestPasswordReset godoc
@@ -121,9 +123,97 @@ func (h *AuthHandler) ResetPassword(c *gin.Context) {
 	c.JSON(http.StatusOK, dto.ResetPasswordResponse{Message: "Password reset successful"})
 }
 
+// VerifyEmail godoc
+// @Summary Verify email
+// @Description Confirms the account email with the code sent on registration. Verifying an already verified email succeeds.
+// @Description A code stops working after it expires or after too many wrong attempts; request a new one then.
+// @Description Wrong attempts are also limited per user across resent codes; past the limit the request is rejected until Retry-After.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param request body dto.VerifyEmailRequest true "Email and verification code"
+// @Success 200 {object} dto.SuccessResponse
+// @Failure 400 {object} dto.ErrorResponse
+// @Failure 404 {object} dto.ErrorResponse
+// @Failure 429 {object} dto.ErrorResponse "Too many wrong attempts, see Retry-After"
+// @Failure 500 {object} dto.ErrorResponse
+// @Router /auth/verify-email [post]
+func (h *AuthHandler) VerifyEmail(c *gin.Context) {
+	var req dto.VerifyEmailRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{Error: "Invalid request"})
+		return
+	}
+
+	err := h.AuthService.VerifyEmail(req.Email, req.Code)
+	var throttledErr *service.VerificationThrottledError
+	switch {
+	case errors.As(err, &throttledErr):
+		c.Header("Retry-After", strconv.Itoa(int(time.Until(throttledErr.RetryAt).Seconds())+1))
+		c.JSON(http.StatusTooManyRequests, dto.ErrorResponse{Error: err.Error()})
+		return
+	case errors.Is(err, service.ErrInvalidVerificationCode):
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{Error: "Invalid or expired verification code"})
+		return
+	case errors.Is(err, service.ErrUserNotFound):
+		c.JSON(http.StatusNotFound, dto.ErrorResponse{Error: "User not found"})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Error: "Failed to verify email"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Email verified"})
+}
+
+// ResendVerification godoc
+// @Summary Resend verification code
+// @Description Emails a new verification code; the previous code stops working. A new code can be requested once a minute.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param request body dto.ResendVerificationRequest true "User email"
+// @Success 200 {object} dto.SuccessResponse
+// @Failure 400 {object} dto.ErrorResponse
+// @Failure 404 {object} dto.ErrorResponse
+// @Failure 409 {object} dto.ErrorResponse "Email is already verified"
+// @Failure 429 {object} dto.ErrorResponse "Code was sent recently, see Retry-After"
+// @Failure 500 {object} dto.ErrorResponse
+// @Router /auth/resend-verification [post]
+func (h *AuthHandler) ResendVerification(c *gin.Context) {
+	var req dto.ResendVerificationRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{Error: "Invalid request"})
+		return
+	}
+
+	err := h.AuthService.ResendVerification(req.Email)
+	var throttledErr *service.VerificationThrottledError
+	switch {
+	case errors.As(err, &throttledErr):
+		c.Header("Retry-After", strconv.Itoa(int(time.Until(throttledErr.RetryAt).Seconds())+1))
+		c.JSON(http.StatusTooManyRequests, dto.ErrorResponse{Error: err.Error()})
+		return
+	case errors.Is(err, service.ErrEmailAlreadyVerified):
+		c.JSON(http.StatusConflict, dto.ErrorResponse{Error: err.Error()})
+		return
+	case errors.Is(err, service.ErrUserNotFound):
+		c.JSON(http.StatusNotFound, dto.ErrorResponse{Error: "User not found"})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Error: "Failed to send verification code"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Verification code sent to your email"})
+}
+
 // RegisterUser godoc
 // @Summary Register a new user
-// @Description Creates a new user account.
+// @Description Creates a new user account and emails a verification code.
+// @Description Until the email is verified the user cannot apply for vacancies or send messages.
 // @Tags Auth
 // @Accept json
 // @Produce json
@@ -160,7 +250,7 @@ func (h *AuthHandler) RegisterUser(c *gin.Context) {
 
 // RegisterHR godoc
 // @Summary Register a new HR
-// @Description Creates a new HR account (requires invitation).
+// @Description Creates a new HR account (requires invitation) and emails a verification code.
 // @Tags Auth
 // @Accept json
 // @Produce json
@@ -195,36 +285,125 @@ func (h *AuthHandler) RegisterHR(c *gin.Context) {
 
 // RefreshToken godoc
 // @Summary Refresh JWT token
-// @Description Generates a new access token using the provided refresh token
+// @Description Rotates the refresh token of the session and returns a new token pair; the old refresh token stops working.
+// @Description Presenting an already rotated refresh token revokes the whole session.
 // @Tags Auth
 // @Accept json
 // @Produce json
 // @Param request body dto.RefreshTokenRequest true "Refresh Token Request"
-// @Success 200 {object} dto.SuccessResponse
+// @Success 200 {object} dto.LoginResponse
 // @Failure 400 {object} dto.ErrorResponse
 // @Failure 401 {object} dto.ErrorResponse
+// @Failure 500 {object} dto.ErrorResponse
 // @Router /auth/refresh [post]
 func (h *AuthHandler) RefreshToken(c *gin.Context) {
-	var req struct {
-		RefreshToken string `json:"refresh_token"`
-	}
+	var req dto.RefreshTokenRequest
 
 	if err := c.ShouldBindJSON(&req); err != nil || req.RefreshToken == "" {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Refresh token is required"})
 		return
 	}
 
-	claims, err := utils.ValidateRefreshToken(req.RefreshToken)
+	tokens, err := h.AuthService.RefreshTokens(req.RefreshToken, clientInfo(c))
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired refresh token"})
+		if errors.Is(err, service.ErrInvalidRefreshToken) || errors.Is(err, service.ErrRefreshTokenReused) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to refresh tokens"})
+		return
+	}
+
+	c.JSON(http.StatusOK, loginResponse(tokens))
+}
+
+// Logout godoc
+// @Summary Log out of the current device
+// @Description Revokes the access token of the request and its device session; the session's refresh token can no longer be used
+// @Tags Auth
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} dto.SuccessResponse
+// @Failure 401 {object} dto.ErrorResponse
+// @Failure 500 {object} dto.ErrorResponse
+// @Router /auth/logout [post]
+func (h *AuthHandler) Logout(c *gin.Context) {
+	claims, ok := c.MustGet("claims").(*utils.Claims)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Error: "Invalid claims format"})
 		return
 	}
 
-	accessToken, err := utils.GenerateJWT(claims.UserID, claims.RoleID, claims.CompanyID, claims.DepartmentID)
+	if err := h.AuthService.Logout(claims); err != nil {
+		c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Error: "Failed to log out"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Logged out"})
+}
+
+// LogoutAll godoc
+// @Summary Log out of all devices
+// @Description Revokes every session of the user, including the current one, and all access tokens issued so far
+// @Tags Auth
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} dto.LogoutAllResponse
+// @Failure 401 {object} dto.ErrorResponse
+// @Failure 500 {object} dto.ErrorResponse
+// @Router /auth/logout-all [post]
+func (h *AuthHandler) LogoutAll(c *gin.Context) {
+	revoked, err := h.AuthService.LogoutAll(c.GetInt("user_id"))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate access token"})
+		c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Error: "Failed to log out"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"access_token": accessToken})
+	c.JSON(http.StatusOK, dto.LogoutAllResponse{Revoked: revoked})
+}
+
+// GetSessions godoc
+// @Summary List active sessions
+// @Description Returns the user's device sessions, most recently used first; current marks the session of this request
+// @Tags Auth
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} entity.Session
+// @Failure 401 {object} dto.ErrorResponse
+// @Failure 500 {object} dto.ErrorResponse
+// @Router /auth/sessions [get]
+func (h *AuthHandler) GetSessions(c *gin.Context) {
+	sessions, err := h.AuthService.GetSessions(c.GetInt("user_id"), c.GetString("session_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Error: "Failed to fetch sessions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, sessions)
+}
+
+// JWKS godoc
+// @Summary JSON Web Key Set
+// @Description Public keys that verify Jumyste access tokens, selected by the kid header.
+// @Description Keys being rotated out stay listed until their tokens expire. Empty for HS256.
+// @Tags Auth
+// @Produce json
+// @Success 200 {object} jwtkeys.JWKS
+// @Router /.well-known/jwks.json [get]
+func (h *AuthHandler) JWKS(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, h.Keys.JWKS())
+}
+
+// clientInfo описывает устройство запроса для сессии
+func clientInfo(c *gin.Context) service.ClientInfo {
+	return service.ClientInfo{UserAgent: c.Request.UserAgent(), IP: c.ClientIP()}
+}
+
+func loginResponse(tokens *service.TokenPair) dto.LoginResponse {
+	return dto.LoginResponse{
+		AccessToken:  tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+		SessionID:    tokens.SessionID,
+	}
 }
@@ -1,9 +1,11 @@
 package handler
 
 import (
+	"errors"
 	"github.com/gin-gonic/gin"
 	"jumyste-app-backend/internal/dto"
 	_ "jumyste-app-backend/internal/entity"
+	"jumyste-app-backend/internal/policy"
 	"jumyste-app-backend/internal/service"
 	"net/http"
 	"strconv"
@@ -19,7 +21,8 @@ func NewChatHandler(chatService *service.ChatService) *ChatHandler {
 
 // CreateChatHandler godoc
 // @Summary Create a chat between two users
-// @Description Create a new chat by providing the second user's ID
+// @Description Create a new chat by providing the second user's ID.
+// @Description The second user must already share a chat with the creator or, for HRs, be an HR of their company.
 // @Tags Chats
 // @Accept json
 // @Produce json
@@ -28,31 +31,19 @@ func NewChatHandler(chatService *service.ChatService) *ChatHandler {
 // @Success 201 {object} entity.Chat "Chat created successfully"
 // @Failure 400 {object} dto.ErrorResponse "Invalid request"
 // @Failure 401 {object} dto.ErrorResponse "Unauthorized"
+// @Failure 403 {object} dto.ErrorResponse "User not allowed or email not verified"
 // @Failure 500 {object} dto.ErrorResponse "Failed to create chat"
 // @Router /chats [post]
 func (h *ChatHandler) CreateChatHandler(c *gin.Context) {
 	var req dto.CreateChatRequest
-
-	userID, exists := c.Get("user_id")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, dto.ErrorResponse{Error: "Unauthorized"})
-		return
-	}
-
-	id, ok := userID.(int)
-	if !ok {
-		c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Error: "Invalid user ID type"})
-		return
-	}
-
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
 		return
 	}
 
-	chat, err := h.ChatService.CreateChat(id, req.SecondUserId)
+	chat, err := h.ChatService.CreateChat(policy.SubjectFromContext(c), req.SecondUserId)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondChatError(c, err, "Failed to create chat")
 		return
 	}
 
@@ -67,8 +58,9 @@ func (h *ChatHandler) CreateChatHandler(c *gin.Context) {
 // @Produce json
 // @Param chatID path int true "Chat ID"
 // @Security BearerAuth
-// @Success 200 {object} entity.Chat "Chat found"
+// @Success 200 {object} entity.Chat "Chat found; context holds the linked vacancy and the current application status"
 // @Failure 400 {object} dto.ErrorResponse "Invalid chat ID"
+// @Failure 403 {object} dto.ErrorResponse "Not a chat member"
 // @Failure 404 {object} dto.ErrorResponse "Chat not found"
 // @Failure 401 {object} dto.ErrorResponse "Unauthorized"
 // @Failure 500 {object} dto.ErrorResponse "Internal server error"
@@ -80,6 +72,16 @@ func (h *ChatHandler) GetChatByIDHandler(c *gin.Context) {
 		return
 	}
 
+	isMember, err := h.ChatService.IsChatMember(int(chatID), c.GetInt("user_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch chat"})
+		return
+	}
+	if !isMember {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Not a chat member"})
+		return
+	}
+
 	chat, err := h.ChatService.GetChatByID(uint(chatID))
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Chat not found"})
@@ -90,18 +92,18 @@ func (h *ChatHandler) GetChatByIDHandler(c *gin.Context) {
 }
 
 // GetAllChatsHandler godoc
-// @Summary Get all chats
-// @Description Retrieve all chats
+// @Summary Get all chats of the current user
+// @Description Retrieve every chat the current user participates in. Internal HR chats are returned only to HRs of the owning company.
 // @Tags Chats
 // @Accept json
 // @Produce json
 // @Security BearerAuth
-// @Success 200 {array} entity.Chat "List of all chats"
+// @Success 200 {array} entity.Chat "List of the user's chats"
 // @Failure 401 {object} dto.ErrorResponse "Unauthorized"
 // @Failure 500 {object} dto.ErrorResponse "Internal server error"
 // @Router /chats [get]
 func (h *ChatHandler) GetAllChatsHandler(c *gin.Context) {
-	chats, err := h.ChatService.GetAllChats()
+	chats, err := h.ChatService.GetAllChats(policy.SubjectFromContext(c))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch chats"})
 		return
@@ -112,12 +114,16 @@ func (h *ChatHandler) GetAllChatsHandler(c *gin.Context) {
 
 // GetChatsByUserIDHandler godoc
 // @Summary Get chats by user ID
-// @Description Retrieve all chats for a specific user by their user ID
+// @Description Retrieve all chats for a specific user by their user ID.
+// @Description For each interlocutor is_online (connected via WebSocket right now) and last_seen_at are returned.
+// @Description Chats about a vacancy or an application carry their context (vacancy title, application status).
 // @Tags Chats
 // @Accept json
 // @Produce json
 // @Security BearerAuth
+// @Param vacancy_id query int false "Only chats about this vacancy"
 // @Success 200 {array} entity.Chat "List of chats for the user"
+// @Failure 400 {object} dto.ErrorResponse "Invalid vacancy ID"
 // @Failure 401 {object} dto.ErrorResponse "Unauthorized"
 // @Failure 500 {object} dto.ErrorResponse "Internal server error"
 // @Router /chats/user [get]
@@ -134,7 +140,17 @@ func (h *ChatHandler) GetChatsByUserIDHandler(c *gin.Context) {
 		return
 	}
 
-	chats, err := h.ChatService.GetChatsByUserID(uid)
+	vacancyID := 0
+	if raw := c.Query("vacancy_id"); raw != "" {
+		id, err := strconv.Atoi(raw)
+		if err != nil || id <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid vacancy ID"})
+			return
+		}
+		vacancyID = id
+	}
+
+	chats, err := h.ChatService.GetChatsByUserID(uid, vacancyID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch chats"})
 		return
@@ -142,3 +158,213 @@ func (h *ChatHandler) GetChatsByUserIDHandler(c *gin.Context) {
 
 	c.JSON(http.StatusOK, chats)
 }
+
+// CreateGroupChatHandler godoc
+// @Summary Create a group chat
+// @Description Create a group chat with a title; the creator becomes its admin.
+// @Description internal=true creates a thread visible only to HRs of the creator's company (candidates cannot be added),
+// @Description optionally linked to the application being discussed.
+// @Description Other chats may include only users the creator already shares a chat with and, for HRs, HRs of their company.
+// @Tags Chats
+// @Accept json
+// @Produce json
+// @Param request body dto.CreateGroupChatRequest true "Group chat payload"
+// @Security BearerAuth
+// @Success 201 {object} entity.Chat "Group chat created"
+// @Failure 400 {object} dto.ErrorResponse "Invalid request or participants"
+// @Failure 401 {object} dto.ErrorResponse "Unauthorized"
+// @Failure 403 {object} dto.ErrorResponse "Access denied, participant not allowed or email not verified"
+// @Failure 404 {object} dto.ErrorResponse "Application not found"
+// @Failure 500 {object} dto.ErrorResponse "Failed to create chat"
+// @Router /chats/group [post]
+func (h *ChatHandler) CreateGroupChatHandler(c *gin.Context) {
+	var req dto.CreateGroupChatRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	chat, err := h.ChatService.CreateGroupChat(c.Request.Context(), policy.SubjectFromContext(c), req)
+	if err != nil {
+		respondChatError(c, err, "Failed to create chat")
+		return
+	}
+
+	c.JSON(http.StatusCreated, chat)
+}
+
+// UpdateChatHandler godoc
+// @Summary Rename a group chat
+// @Description Only chat admins can rename a group chat; participants get a system message and a chat.updated event
+// @Tags Chats
+// @Accept json
+// @Produce json
+// @Param chatID path int true "Chat ID"
+// @Param request body dto.UpdateChatRequest true "New title"
+// @Security BearerAuth
+// @Success 200 {object} entity.Chat "Updated chat"
+// @Failure 400 {object} dto.ErrorResponse "Invalid request or not a group chat"
+// @Failure 401 {object} dto.ErrorResponse "Unauthorized"
+// @Failure 403 {object} dto.ErrorResponse "Not a chat admin"
+// @Failure 404 {object} dto.ErrorResponse "Chat not found"
+// @Failure 500 {object} dto.ErrorResponse "Failed to update chat"
+// @Router /chats/{chatID} [patch]
+func (h *ChatHandler) UpdateChatHandler(c *gin.Context) {
+	chatID, err := strconv.Atoi(c.Param("chatID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{Error: "Invalid chat ID"})
+		return
+	}
+	var req dto.UpdateChatRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	chat, err := h.ChatService.UpdateGroupTitle(c.Request.Context(), policy.SubjectFromContext(c), chatID, req.Title)
+	if err != nil {
+		respondChatError(c, err, "Failed to update chat")
+		return
+	}
+
+	c.JSON(http.StatusOK, chat)
+}
+
+// AddParticipantsHandler godoc
+// @Summary Add participants to a group chat
+// @Description Only chat admins can add participants; users already in the chat are skipped.
+// @Description Internal chats accept only HRs of the same company; other chats accept users the admin
+// @Description already shares a chat with and, for HRs, HRs of their company.
+// @Tags Chats
+// @Accept json
+// @Produce json
+// @Param chatID path int true "Chat ID"
+// @Param request body dto.AddParticipantsRequest true "Users to add"
+// @Security BearerAuth
+// @Success 200 {object} entity.Chat "Updated chat"
+// @Failure 400 {object} dto.ErrorResponse "Invalid participants or not a group chat"
+// @Failure 401 {object} dto.ErrorResponse "Unauthorized"
+// @Failure 403 {object} dto.ErrorResponse "Not a chat admin or participant not allowed"
+// @Failure 404 {object} dto.ErrorResponse "Chat not found"
+// @Failure 500 {object} dto.ErrorResponse "Failed to add participants"
+// @Router /chats/{chatID}/participants [post]
+func (h *ChatHandler) AddParticipantsHandler(c *gin.Context) {
+	chatID, err := strconv.Atoi(c.Param("chatID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{Error: "Invalid chat ID"})
+		return
+	}
+	var req dto.AddParticipantsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	chat, err := h.ChatService.AddParticipants(c.Request.Context(), policy.SubjectFromContext(c), chatID, req.UserIDs)
+	if err != nil {
+		respondChatError(c, err, "Failed to add participants")
+		return
+	}
+
+	c.JSON(http.StatusOK, chat)
+}
+
+// RemoveParticipantHandler godoc
+// @Summary Remove a participant from a group chat
+// @Description Admins can remove any participant; any participant can remove themselves to leave the chat.
+// @Description The last admin cannot leave while other participants remain.
+// @Tags Chats
+// @Produce json
+// @Param chatID path int true "Chat ID"
+// @Param userID path int true "User ID"
+// @Security BearerAuth
+// @Success 204 "Participant removed"
+// @Failure 400 {object} dto.ErrorResponse "Invalid ID or not a group chat"
+// @Failure 401 {object} dto.ErrorResponse "Unauthorized"
+// @Failure 403 {object} dto.ErrorResponse "Not a chat admin"
+// @Failure 404 {object} dto.ErrorResponse "Chat or participant not found"
+// @Failure 409 {object} dto.ErrorResponse "Chat must keep at least one admin"
+// @Failure 500 {object} dto.ErrorResponse "Failed to remove participant"
+// @Router /chats/{chatID}/participants/{userID} [delete]
+func (h *ChatHandler) RemoveParticipantHandler(c *gin.Context) {
+	chatID, err := strconv.Atoi(c.Param("chatID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{Error: "Invalid chat ID"})
+		return
+	}
+	userID, err := strconv.Atoi(c.Param("userID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{Error: "Invalid user ID"})
+		return
+	}
+
+	if err := h.ChatService.RemoveParticipant(c.Request.Context(), policy.SubjectFromContext(c), chatID, userID); err != nil {
+		respondChatError(c, err, "Failed to remove participant")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// SetParticipantRoleHandler godoc
+// @Summary Change a participant's role
+// @Description Only chat admins can promote participants to admin or demote them to member
+// @Tags Chats
+// @Accept json
+// @Produce json
+// @Param chatID path int true "Chat ID"
+// @Param userID path int true "User ID"
+// @Param request body dto.SetParticipantRoleRequest true "New role"
+// @Security BearerAuth
+// @Success 200 {object} entity.Chat "Updated chat"
+// @Failure 400 {object} dto.ErrorResponse "Invalid request or not a group chat"
+// @Failure 401 {object} dto.ErrorResponse "Unauthorized"
+// @Failure 403 {object} dto.ErrorResponse "Not a chat admin"
+// @Failure 404 {object} dto.ErrorResponse "Chat or participant not found"
+// @Failure 409 {object} dto.ErrorResponse "Chat must keep at least one admin"
+// @Failure 500 {object} dto.ErrorResponse "Failed to change role"
+// @Router /chats/{chatID}/participants/{userID}/role [put]
+func (h *ChatHandler) SetParticipantRoleHandler(c *gin.Context) {
+	chatID, err := strconv.Atoi(c.Param("chatID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{Error: "Invalid chat ID"})
+		return
+	}
+	userID, err := strconv.Atoi(c.Param("userID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{Error: "Invalid user ID"})
+		return
+	}
+	var req dto.SetParticipantRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	chat, err := h.ChatService.SetParticipantRole(c.Request.Context(), policy.SubjectFromContext(c), chatID, userID, req.Role)
+	if err != nil {
+		respondChatError(c, err, "Failed to change role")
+		return
+	}
+
+	c.JSON(http.StatusOK, chat)
+}
+
+// respondChatError переводит ошибки групповых чатов в HTTP-статусы
+func respondChatError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, service.ErrChatNotFound), errors.Is(err, service.ErrParticipantNotFound):
+		c.JSON(http.StatusNotFound, dto.ErrorResponse{Error: err.Error()})
+	case errors.Is(err, service.ErrNotChatMember), errors.Is(err, service.ErrChatAdminRequired), errors.Is(err, service.ErrParticipantNotAllowed),
+		errors.Is(err, service.ErrEmailNotVerified):
+		c.JSON(http.StatusForbidden, dto.ErrorResponse{Error: err.Error()})
+	case errors.Is(err, service.ErrNotGroupChat), errors.Is(err, service.ErrInvalidGroupChat), errors.Is(err, service.ErrInvalidChat):
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{Error: err.Error()})
+	case errors.Is(err, service.ErrLastAdmin):
+		c.JSON(http.StatusConflict, dto.ErrorResponse{Error: err.Error()})
+	case errors.Is(err, policy.ErrForbidden), errors.Is(err, policy.ErrNotFound):
+		authorized(c, err)
+	default:
+		c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Error: fallback})
+	}
+}
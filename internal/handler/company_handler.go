@@ -4,6 +4,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"jumyste-app-backend/internal/dto"
 	"jumyste-app-backend/internal/entity"
+	"jumyste-app-backend/internal/policy"
 	"jumyste-app-backend/internal/service"
 	"jumyste-app-backend/pkg/logger"
 	"log/slog"
@@ -13,10 +14,11 @@ import (
 
 type CompanyHandler struct {
 	CompanyService *service.CompanyService
+	Policy         *policy.Policy
 }
 
-func NewCompanyHandler(companyService *service.CompanyService) *CompanyHandler {
-	return &CompanyHandler{CompanyService: companyService}
+func NewCompanyHandler(companyService *service.CompanyService, policy *policy.Policy) *CompanyHandler {
+	return &CompanyHandler{CompanyService: companyService, Policy: policy}
 }
 
 // CreateCompany godoc
@@ -39,6 +41,9 @@ func (h *CompanyHandler) CreateCompany(c *gin.Context) {
 		return
 	}
 
+	// Владельцем всегда становится автор запроса, а не значение из тела
+	company.OwnerId = c.GetInt("user_id")
+
 	if err := h.CompanyService.CreateCompany(&company); err != nil {
 		c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Error: "Failed to create company"})
 		return
@@ -86,6 +91,8 @@ func (h *CompanyHandler) GetCompanyByID(c *gin.Context) {
 // @Param company body entity.Company true "Updated company"
 // @Success 200 {object} dto.SuccessResponse
 // @Failure 400 {object} dto.ErrorResponse
+// @Failure 403 {object} dto.ErrorResponse "Only the company owner can change it"
+// @Failure 404 {object} dto.ErrorResponse
 // @Failure 500 {object} dto.ErrorResponse
 // @Router /companies/{id} [put]
 func (h *CompanyHandler) UpdateCompany(c *gin.Context) {
@@ -95,6 +102,10 @@ func (h *CompanyHandler) UpdateCompany(c *gin.Context) {
 		return
 	}
 
+	if _, err := h.Policy.ManageCompany(c.Request.Context(), policy.SubjectFromContext(c), id); !authorized(c, err) {
+		return
+	}
+
 	var company entity.Company
 	if err := c.ShouldBindJSON(&company); err != nil {
 		c.JSON(http.StatusBadRequest, dto.ErrorResponse{Error: "Invalid request payload"})
@@ -120,6 +131,8 @@ func (h *CompanyHandler) UpdateCompany(c *gin.Context) {
 // @Param id path int true "Company ID"
 // @Success 200 {object} dto.SuccessResponse
 // @Failure 400 {object} dto.ErrorResponse
+// @Failure 403 {object} dto.ErrorResponse "Only the company owner can change it"
+// @Failure 404 {object} dto.ErrorResponse
 // @Failure 500 {object} dto.ErrorResponse
 // @Router /companies/{id} [delete]
 func (h *CompanyHandler) DeleteCompany(c *gin.Context) {
@@ -129,6 +142,10 @@ func (h *CompanyHandler) DeleteCompany(c *gin.Context) {
 		return
 	}
 
+	if _, err := h.Policy.ManageCompany(c.Request.Context(), policy.SubjectFromContext(c), id); !authorized(c, err) {
+		return
+	}
+
 	if err := h.CompanyService.DeleteCompany(id); err != nil {
 		c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Error: "Failed to delete company"})
 		return
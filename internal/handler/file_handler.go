@@ -0,0 +1,79 @@
+package handler
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"io"
+	"jumyste-app-backend/pkg/logger"
+	"jumyste-app-backend/pkg/storage"
+	"log/slog"
+	"net/http"
+	"strconv"
+)
+
+// FileHandler отдаёт файлы локального хранилища по подписанным ссылкам.
+// Авторизация — сама подпись: ссылку выдают только участникам чата, но пересланная ссылка или ссылка
+// участника, исключённого из чата, работает до истечения. Это осознанный компромисс: ссылки открываются
+// из <img>/<video> без заголовка Authorization, поэтому срок жизни ссылки (STORAGE_URL_TTL_MINUTES) короткий.
+type FileHandler struct {
+	Storage storage.Storage
+	Signer  *storage.URLSigner
+}
+
+func NewFileHandler(store storage.Storage, signer *storage.URLSigner) *FileHandler {
+	return &FileHandler{Storage: store, Signer: signer}
+}
+
+// DownloadFile godoc
+// @Summary Download an attachment
+// @Description Serves a stored file by a signed link from a message attachment.
+// @Description The signature alone authorizes the download: a forwarded link keeps working until it expires,
+// @Description so links are short-lived (5 minutes by default).
+// @Tags Files
+// @Produce octet-stream
+// @Param key path string true "Object key"
+// @Param expires query int true "Link expiry (unix time)"
+// @Param name query string false "File name for Content-Disposition"
+// @Param signature query string true "Link signature"
+// @Success 200 {file} file
+// @Failure 403 {object} dto.ErrorResponse "Invalid or expired link"
+// @Failure 404 {object} dto.ErrorResponse "File not found"
+// @Failure 500 {object} dto.ErrorResponse "Internal server error"
+// @Router /files/{key} [get]
+func (h *FileHandler) DownloadFile(c *gin.Context) {
+	key, err := storage.CleanKey(c.Param("key"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "File not found"})
+		return
+	}
+
+	expires, err := strconv.ParseInt(c.Query("expires"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": storage.ErrInvalidSignature.Error()})
+		return
+	}
+	filename := c.Query("name")
+	if err := h.Signer.Verify(key, filename, expires, c.Query("signature")); err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+
+	body, object, err := h.Storage.Get(c.Request.Context(), key)
+	if errors.Is(err, storage.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "File not found"})
+		return
+	}
+	if err != nil {
+		logger.Log.Error("Failed to read file", slog.String("key", key), slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read file"})
+		return
+	}
+	defer body.Close()
+
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.Header("Cache-Control", "private, max-age=300")
+	if filename != "" {
+		c.Header("Content-Disposition", storage.ContentDisposition(filename))
+	}
+	c.DataFromReader(http.StatusOK, object.Size, object.ContentType, io.Reader(body), nil)
+}
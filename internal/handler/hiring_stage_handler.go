@@ -0,0 +1,138 @@
+package handler
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"jumyste-app-backend/internal/dto"
+	"jumyste-app-backend/internal/service"
+	"jumyste-app-backend/pkg/logger"
+	"net/http"
+	"strconv"
+)
+
+type HiringStageHandler struct {
+	StageService *service.HiringStageService
+}
+
+func NewHiringStageHandler(stageService *service.HiringStageService) *HiringStageHandler {
+	return &HiringStageHandler{StageService: stageService}
+}
+
+// GetStages godoc
+// @Summary Get hiring pipeline stages
+// @Description Returns the company pipeline, or the pipeline in effect for a vacancy when vacancy_id is given
+// @Tags Hiring Stages
+// @Produce json
+// @Param vacancy_id query int false "Vacancy ID"
+// @Security BearerAuth
+// @Success 200 {array} entity.HiringStage
+// @Failure 400 {object} dto.ErrorResponse "Invalid vacancy ID"
+// @Failure 401 {object} dto.ErrorResponse "Unauthorized"
+// @Failure 403 {object} dto.ErrorResponse "Vacancy belongs to another company"
+// @Failure 404 {object} dto.ErrorResponse "Vacancy not found"
+// @Failure 500 {object} dto.ErrorResponse "Failed to get stages"
+// @Router /stages [get]
+func (h *HiringStageHandler) GetStages(c *gin.Context) {
+	var vacancyID *int
+	if raw := c.Query("vacancy_id"); raw != "" {
+		id, err := strconv.Atoi(raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid vacancy ID"})
+			return
+		}
+		vacancyID = &id
+	}
+
+	stages, err := h.StageService.GetPipeline(c.Request.Context(), c.GetInt("company_id"), vacancyID)
+	if err != nil {
+		respondStageError(c, err, "Failed to get stages")
+		return
+	}
+
+	c.JSON(http.StatusOK, stages)
+}
+
+// ReplaceStages godoc
+// @Summary Replace the hiring pipeline
+// @Description Replaces the company pipeline, or sets a per-vacancy override when vacancy_id is given. Stages are ordered as listed; stages with an id are updated, the rest are created, missing ones are removed.
+// @Tags Hiring Stages
+// @Accept json
+// @Produce json
+// @Param request body dto.ReplaceStagesRequest true "Pipeline"
+// @Security BearerAuth
+// @Success 200 {array} entity.HiringStage
+// @Failure 400 {object} dto.ErrorResponse "Invalid pipeline"
+// @Failure 401 {object} dto.ErrorResponse "Unauthorized"
+// @Failure 403 {object} dto.ErrorResponse "Vacancy belongs to another company"
+// @Failure 404 {object} dto.ErrorResponse "Vacancy or stage not found"
+// @Failure 409 {object} dto.ErrorResponse "A removed or restatused stage still has applications, or the override lacks a stage for a status in use"
+// @Failure 500 {object} dto.ErrorResponse "Failed to save stages"
+// @Router /stages [put]
+func (h *HiringStageHandler) ReplaceStages(c *gin.Context) {
+	var req dto.ReplaceStagesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	stages, err := h.StageService.ReplacePipeline(c.Request.Context(), c.GetInt("company_id"), req.VacancyID, req.Stages)
+	if err != nil {
+		respondStageError(c, err, "Failed to save stages")
+		return
+	}
+
+	c.JSON(http.StatusOK, stages)
+}
+
+// DeleteVacancyStages godoc
+// @Summary Remove the vacancy pipeline override
+// @Description The vacancy goes back to the company pipeline; its applications move to company stages with the same status
+// @Tags Hiring Stages
+// @Produce json
+// @Param vacancy_id path int true "Vacancy ID"
+// @Security BearerAuth
+// @Success 200 {object} dto.SuccessResponse
+// @Failure 400 {object} dto.ErrorResponse "Invalid vacancy ID"
+// @Failure 401 {object} dto.ErrorResponse "Unauthorized"
+// @Failure 403 {object} dto.ErrorResponse "Vacancy belongs to another company"
+// @Failure 404 {object} dto.ErrorResponse "Vacancy not found"
+// @Failure 500 {object} dto.ErrorResponse "Failed to delete stages"
+// @Router /stages/vacancy/{vacancy_id} [delete]
+func (h *HiringStageHandler) DeleteVacancyStages(c *gin.Context) {
+	vacancyID, err := strconv.Atoi(c.Param("vacancy_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid vacancy ID"})
+		return
+	}
+
+	if err := h.StageService.DeleteVacancyOverride(c.Request.Context(), c.GetInt("company_id"), vacancyID); err != nil {
+		respondStageError(c, err, "Failed to delete stages")
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.SuccessResponse{Message: "Vacancy uses the company pipeline"})
+}
+
+func respondStageError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, service.ErrInvalidPipeline):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Pipeline must contain named stages including one with status new"})
+	case errors.Is(err, service.ErrUnknownApplicationStatus):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid stage status"})
+	case errors.Is(err, service.ErrVacancyNotInCompany):
+		c.JSON(http.StatusForbidden, gin.H{"error": "Vacancy belongs to another company"})
+	case errors.Is(err, service.ErrVacancyNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Vacancy not found"})
+	case errors.Is(err, service.ErrStageNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Stage not found"})
+	case errors.Is(err, service.ErrStageInUse):
+		c.JSON(http.StatusConflict, gin.H{"error": "Cannot remove a stage that still has applications"})
+	case errors.Is(err, service.ErrStageStatusInUse):
+		c.JSON(http.StatusConflict, gin.H{"error": "Cannot change the status of a stage that still has applications"})
+	case errors.Is(err, service.ErrStageStatusMissing):
+		c.JSON(http.StatusConflict, gin.H{"error": "Vacancy pipeline needs a stage for every status its applications are in"})
+	default:
+		logger.Log.Error(fallback, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
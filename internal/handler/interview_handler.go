@@ -0,0 +1,248 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"jumyste-app-backend/internal/dto"
+	"jumyste-app-backend/internal/service"
+	"jumyste-app-backend/pkg/logger"
+	"net/http"
+	"strconv"
+)
+
+type InterviewHandler struct {
+	InterviewService *service.InterviewService
+}
+
+func NewInterviewHandler(interviewService *service.InterviewService) *InterviewHandler {
+	return &InterviewHandler{InterviewService: interviewService}
+}
+
+// ScheduleInterview godoc
+// @Summary Schedule an interview for a job application
+// @Description Creates an interview, checks interviewer conflicts, moves the application to "interview", posts a confirmation to the chat and emails .ics invites
+// @Tags Interviews
+// @Accept json
+// @Produce json
+// @Param request body dto.CreateInterviewRequest true "Interview"
+// @Security BearerAuth
+// @Success 201 {object} entity.Interview
+// @Failure 400 {object} dto.ErrorResponse "Invalid interview"
+// @Failure 401 {object} dto.ErrorResponse "Unauthorized"
+// @Failure 403 {object} dto.ErrorResponse "Application belongs to another company"
+// @Failure 404 {object} dto.ErrorResponse "Application not found"
+// @Failure 409 {object} dto.InterviewConflictResponse "Interviewer is busy"
+// @Failure 500 {object} dto.ErrorResponse "Failed to schedule interview"
+// @Router /interviews [post]
+func (h *InterviewHandler) ScheduleInterview(c *gin.Context) {
+	var req dto.CreateInterviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	interview, err := h.InterviewService.ScheduleInterview(c.Request.Context(), interviewActor(c), req)
+	if err != nil {
+		respondInterviewError(c, err, "Failed to schedule interview")
+		return
+	}
+
+	c.JSON(http.StatusCreated, interview)
+}
+
+// ListInterviews godoc
+// @Summary List interviews
+// @Description HR users get interviews of their company, candidates get their own
+// @Tags Interviews
+// @Produce json
+// @Param application_id query int false "Application ID"
+// @Param from query string false "From (RFC3339)"
+// @Param to query string false "To (RFC3339)"
+// @Security BearerAuth
+// @Success 200 {array} entity.Interview
+// @Failure 400 {object} dto.ErrorResponse "Invalid query parameters"
+// @Failure 401 {object} dto.ErrorResponse "Unauthorized"
+// @Failure 500 {object} dto.ErrorResponse "Failed to list interviews"
+// @Router /interviews [get]
+func (h *InterviewHandler) ListInterviews(c *gin.Context) {
+	var filter dto.InterviewListFilter
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid query parameters"})
+		return
+	}
+
+	interviews, err := h.InterviewService.ListInterviews(c.Request.Context(), interviewActor(c), filter)
+	if err != nil {
+		respondInterviewError(c, err, "Failed to list interviews")
+		return
+	}
+
+	c.JSON(http.StatusOK, interviews)
+}
+
+// GetInterview godoc
+// @Summary Get an interview
+// @Tags Interviews
+// @Produce json
+// @Param id path int true "Interview ID"
+// @Security BearerAuth
+// @Success 200 {object} entity.Interview
+// @Failure 400 {object} dto.ErrorResponse "Invalid interview ID"
+// @Failure 401 {object} dto.ErrorResponse "Unauthorized"
+// @Failure 403 {object} dto.ErrorResponse "Access denied"
+// @Failure 404 {object} dto.ErrorResponse "Interview not found"
+// @Failure 500 {object} dto.ErrorResponse "Failed to get interview"
+// @Router /interviews/{id} [get]
+func (h *InterviewHandler) GetInterview(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid interview ID"})
+		return
+	}
+
+	interview, err := h.InterviewService.GetInterview(c.Request.Context(), interviewActor(c), id)
+	if err != nil {
+		respondInterviewError(c, err, "Failed to get interview")
+		return
+	}
+
+	c.JSON(http.StatusOK, interview)
+}
+
+// UpdateInterview godoc
+// @Summary Update or reschedule an interview
+// @Description Partially updates the interview. Rescheduling re-checks conflicts, posts to the chat and re-sends the .ics
+// @Tags Interviews
+// @Accept json
+// @Produce json
+// @Param id path int true "Interview ID"
+// @Param request body dto.UpdateInterviewRequest true "Changes"
+// @Security BearerAuth
+// @Success 200 {object} entity.Interview
+// @Failure 400 {object} dto.ErrorResponse "Invalid interview"
+// @Failure 401 {object} dto.ErrorResponse "Unauthorized"
+// @Failure 403 {object} dto.ErrorResponse "Access denied"
+// @Failure 404 {object} dto.ErrorResponse "Interview not found"
+// @Failure 409 {object} dto.InterviewConflictResponse "Interviewer is busy or interview is closed"
+// @Failure 500 {object} dto.ErrorResponse "Failed to update interview"
+// @Router /interviews/{id} [put]
+func (h *InterviewHandler) UpdateInterview(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid interview ID"})
+		return
+	}
+
+	var req dto.UpdateInterviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	interview, err := h.InterviewService.UpdateInterview(c.Request.Context(), interviewActor(c), id, req)
+	if err != nil {
+		respondInterviewError(c, err, "Failed to update interview")
+		return
+	}
+
+	c.JSON(http.StatusOK, interview)
+}
+
+// CancelInterview godoc
+// @Summary Cancel an interview
+// @Description Cancels the interview, posts the reason to the chat and sends a calendar cancellation
+// @Tags Interviews
+// @Accept json
+// @Produce json
+// @Param id path int true "Interview ID"
+// @Param request body dto.CancelInterviewRequest false "Reason"
+// @Security BearerAuth
+// @Success 200 {object} dto.SuccessResponse
+// @Failure 400 {object} dto.ErrorResponse "Invalid interview ID"
+// @Failure 401 {object} dto.ErrorResponse "Unauthorized"
+// @Failure 403 {object} dto.ErrorResponse "Access denied"
+// @Failure 404 {object} dto.ErrorResponse "Interview not found"
+// @Failure 409 {object} dto.ErrorResponse "Interview is already closed"
+// @Failure 500 {object} dto.ErrorResponse "Failed to cancel interview"
+// @Router /interviews/{id} [delete]
+func (h *InterviewHandler) CancelInterview(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid interview ID"})
+		return
+	}
+
+	var req dto.CancelInterviewRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+			return
+		}
+	}
+
+	if err := h.InterviewService.CancelInterview(c.Request.Context(), interviewActor(c), id, req.Reason); err != nil {
+		respondInterviewError(c, err, "Failed to cancel interview")
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.SuccessResponse{Message: "Interview cancelled"})
+}
+
+// DownloadInterviewICS godoc
+// @Summary Download the interview as an iCalendar file
+// @Tags Interviews
+// @Produce text/calendar
+// @Param id path int true "Interview ID"
+// @Security BearerAuth
+// @Success 200 {file} file
+// @Failure 400 {object} dto.ErrorResponse "Invalid interview ID"
+// @Failure 401 {object} dto.ErrorResponse "Unauthorized"
+// @Failure 403 {object} dto.ErrorResponse "Access denied"
+// @Failure 404 {object} dto.ErrorResponse "Interview not found"
+// @Router /interviews/{id}/ics [get]
+func (h *InterviewHandler) DownloadInterviewICS(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid interview ID"})
+		return
+	}
+
+	data, err := h.InterviewService.GetInterviewICS(c.Request.Context(), interviewActor(c), id)
+	if err != nil {
+		respondInterviewError(c, err, "Failed to build calendar file")
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=interview-%d.ics", id))
+	c.Data(http.StatusOK, "text/calendar; charset=UTF-8", data)
+}
+
+func interviewActor(c *gin.Context) service.InterviewActor {
+	return service.InterviewActor{
+		UserID:    c.GetInt("user_id"),
+		RoleID:    c.GetInt("role_id"),
+		CompanyID: c.GetInt("company_id"),
+	}
+}
+
+func respondInterviewError(c *gin.Context, err error, fallback string) {
+	var conflictErr *service.InterviewConflictError
+	switch {
+	case errors.As(err, &conflictErr):
+		c.JSON(http.StatusConflict, dto.InterviewConflictResponse{Error: err.Error(), Conflicts: conflictErr.Conflicts})
+	case errors.Is(err, service.ErrInvalidInterview):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrInterviewForbidden):
+		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
+	case errors.Is(err, service.ErrInterviewNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Interview not found"})
+	case errors.Is(err, service.ErrJobApplicationNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Application not found"})
+	case errors.Is(err, service.ErrInterviewClosed):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		logger.Log.Error(fallback, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
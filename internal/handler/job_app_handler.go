@@ -1,34 +1,47 @@
 package handler
 
 import (
+	"errors"
 	"github.com/gin-gonic/gin"
-	_ "jumyste-app-backend/internal/dto"
+	"jumyste-app-backend/internal/dto"
+	"jumyste-app-backend/internal/policy"
 	"jumyste-app-backend/internal/service"
 	"jumyste-app-backend/pkg/logger"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 )
 
+const maxIdempotencyKeyLength = 255
+
 type JobApplicationHandler struct {
 	JobApplicationService *service.JobApplicationService
 	ResumeService         *service.ResumeService
+	Policy                *policy.Policy
 }
 
-func NewJobApplicationHandler(service *service.JobApplicationService, resumeService *service.ResumeService) *JobApplicationHandler {
-	return &JobApplicationHandler{JobApplicationService: service, ResumeService: resumeService}
+func NewJobApplicationHandler(service *service.JobApplicationService, resumeService *service.ResumeService, policy *policy.Policy) *JobApplicationHandler {
+	return &JobApplicationHandler{JobApplicationService: service, ResumeService: resumeService, Policy: policy}
 }
 
 // ApplyForJob godoc
 // @Summary Apply for a job
-// @Description Apply for a job by providing vacancy ID and user details
+// @Description Apply for a job by providing vacancy ID and user details. Only one active application per vacancy is allowed; after a rejection the candidate can re-apply once the cooldown expires.
+// @Description Retrying the request with the same Idempotency-Key returns the original application with 200 instead of creating a duplicate.
 // @Tags Job Applications
 // @Accept json
 // @Produce json
 // @Param vacancy_id path int true "Vacancy ID"
+// @Param Idempotency-Key header string false "Client-generated key to safely retry the request"
 // @Security BearerAuth
 // @Success 201 {object} dto.JobApplicationResponse
+// @Success 200 {object} dto.JobApplicationResponse "Replayed idempotent request"
 // @Failure 400 {object} dto.ErrorResponse "Invalid vacancy ID"
 // @Failure 401 {object} dto.ErrorResponse "Unauthorized"
+// @Failure 403 {object} dto.ErrorResponse "Email not verified"
+// @Failure 409 {object} dto.DuplicateApplicationResponse "Already applied or re-apply cooldown"
+// @Failure 422 {object} dto.ErrorResponse "Idempotency-Key reused for another vacancy"
 // @Failure 500 {object} dto.ErrorResponse "Failed to apply for job"
 // @Router /jobs/apply/{vacancy_id} [post]
 func (h *JobApplicationHandler) ApplyForJob(c *gin.Context) {
@@ -42,6 +55,12 @@ func (h *JobApplicationHandler) ApplyForJob(c *gin.Context) {
 		return
 	}
 
+	idempotencyKey := strings.TrimSpace(c.GetHeader("Idempotency-Key"))
+	if len(idempotencyKey) > maxIdempotencyKeyLength {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Idempotency-Key is too long"})
+		return
+	}
+
 	resume, user, err := h.ResumeService.GetResumeAndUserByUserID(c.Request.Context(), userID)
 	if err != nil {
 		logger.Log.Error("Failed to retrieve user information and resume", "user_id", userID, "error", err)
@@ -54,7 +73,7 @@ func (h *JobApplicationHandler) ApplyForJob(c *gin.Context) {
 		return
 	}
 
-	application, err := h.JobApplicationService.ApplyForJob(
+	application, replayed, err := h.JobApplicationService.ApplyForJob(
 		c.Request.Context(),
 		userID,
 		vacancyID,
@@ -62,16 +81,106 @@ func (h *JobApplicationHandler) ApplyForJob(c *gin.Context) {
 		user.LastName,
 		user.Email,
 		resume.ID,
+		idempotencyKey,
 	)
-	if err != nil {
+	var duplicateErr *service.DuplicateApplicationError
+	var cooldownErr *service.ReapplyCooldownError
+	switch {
+	case errors.Is(err, service.ErrEmailNotVerified):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	case errors.As(err, &duplicateErr):
+		c.JSON(http.StatusConflict, dto.DuplicateApplicationResponse{Error: err.Error(), ApplicationID: duplicateErr.ApplicationID})
+		return
+	case errors.As(err, &cooldownErr):
+		c.Header("Retry-After", strconv.Itoa(int(time.Until(cooldownErr.ReapplyAt).Seconds())+1))
+		c.JSON(http.StatusConflict, dto.DuplicateApplicationResponse{Error: err.Error(), ApplicationID: cooldownErr.ApplicationID, ReapplyAt: &cooldownErr.ReapplyAt})
+		return
+	case errors.Is(err, service.ErrAlreadyApplied):
+		c.JSON(http.StatusConflict, dto.DuplicateApplicationResponse{Error: err.Error()})
+		return
+	case errors.Is(err, service.ErrIdempotencyKeyMismatch):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	case err != nil:
 		logger.Log.Error("Failed to apply for job", "user_id", userID, "vacancy_id", vacancyID, "error", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
+	if replayed {
+		c.Header("Idempotent-Replayed", "true")
+		c.JSON(http.StatusOK, application)
+		return
+	}
 	c.JSON(http.StatusCreated, application)
 }
 
+// GetMyJobApplications godoc
+// @Summary Get applications of the current candidate
+// @Description Returns the candidate's applications with vacancy title, company, current status and status timeline
+// @Tags Job Applications
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} entity.CandidateApplication
+// @Failure 401 {object} dto.ErrorResponse "Unauthorized"
+// @Failure 403 {object} dto.ErrorResponse "Forbidden"
+// @Failure 500 {object} dto.ErrorResponse "Failed to retrieve job applications"
+// @Router /jobs/my [get]
+func (h *JobApplicationHandler) GetMyJobApplications(c *gin.Context) {
+	userID := c.GetInt("user_id")
+
+	applications, err := h.JobApplicationService.GetMyJobApplications(c.Request.Context(), userID)
+	if err != nil {
+		logger.Log.Error("Failed to get candidate applications", "user_id", userID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve job applications"})
+		return
+	}
+
+	c.JSON(http.StatusOK, applications)
+}
+
+// WithdrawJobApplication godoc
+// @Summary Withdraw own job application
+// @Description Sets the application status to "withdrawn" instead of deleting it; the record stays visible to HR
+// @Tags Job Applications
+// @Accept json
+// @Produce json
+// @Param application_id path int true "Application ID"
+// @Param request body dto.UpdateApplicationStatusRequest false "Optional comment"
+// @Security BearerAuth
+// @Success 200 {object} dto.SuccessResponse
+// @Failure 400 {object} dto.ErrorResponse "Invalid application ID"
+// @Failure 401 {object} dto.ErrorResponse "Unauthorized"
+// @Failure 403 {object} dto.ErrorResponse "Application belongs to another user"
+// @Failure 404 {object} dto.ErrorResponse "Application not found"
+// @Failure 409 {object} dto.StatusTransitionErrorResponse "Application is already closed"
+// @Failure 500 {object} dto.ErrorResponse "Failed to withdraw application"
+// @Router /jobs/application/{application_id}/withdraw [post]
+func (h *JobApplicationHandler) WithdrawJobApplication(c *gin.Context) {
+	applicationID, err := strconv.Atoi(c.Param("application_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid application ID"})
+		return
+	}
+
+	var req dto.UpdateApplicationStatusRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+			return
+		}
+	}
+
+	err = h.JobApplicationService.WithdrawJobApplication(c.Request.Context(), applicationID, c.GetInt("user_id"), req.Comment)
+	if err != nil {
+		respondStatusChangeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "withdrawn"})
+}
+
 // GetJobApplicationsByVacancyID godoc
 // @Summary Get job applications by vacancy ID
 // @Description Retrieve all job applications for a specific vacancy
@@ -83,6 +192,7 @@ func (h *JobApplicationHandler) ApplyForJob(c *gin.Context) {
 // @Success 200 {array} dto.JobApplicationWithResumeResponse
 // @Failure 400 {object} dto.ErrorResponse "Invalid vacancy ID"
 // @Failure 401 {object} dto.ErrorResponse "Unauthorized"
+// @Failure 403 {object} dto.ErrorResponse "Access denied"
 // @Failure 500 {object} dto.ErrorResponse "Failed to retrieve job applications"
 // @Router /jobs/{vacancy_id} [get]
 func (h *JobApplicationHandler) GetJobApplicationsByVacancyID(c *gin.Context) {
@@ -95,6 +205,10 @@ func (h *JobApplicationHandler) GetJobApplicationsByVacancyID(c *gin.Context) {
 		return
 	}
 
+	if _, err := h.Policy.CompanyVacancy(c.Request.Context(), policy.SubjectFromContext(c), vacancyID); !authorized(c, err) {
+		return
+	}
+
 	applications, err := h.JobApplicationService.GetJobApplicationsByVacancyID(c.Request.Context(), vacancyID)
 	if err != nil {
 		logger.Log.Error("Failed to get job applications", "error", err)
@@ -107,16 +221,20 @@ func (h *JobApplicationHandler) GetJobApplicationsByVacancyID(c *gin.Context) {
 
 // UpdateJobApplicationStatus godoc
 // @Summary Update the status of a job application
-// @Description Update the status of a specific job application by application ID
+// @Description Moves the application to a new status if the transition is allowed and records it in the status history
 // @Tags Job Applications
 // @Accept json
 // @Produce json
 // @Param application_id path int true "Application ID"
-// @Param status path string true "New Status"
+// @Param status path string true "New Status" Enums(new, screening, invited, interview, offer, accepted, rejected)
+// @Param request body dto.UpdateApplicationStatusRequest false "Optional comment"
 // @Security BearerAuth
 // @Success 200 {object} dto.SuccessResponse
 // @Failure 400 {object} dto.ErrorResponse "Invalid application ID or status"
 // @Failure 401 {object} dto.ErrorResponse "Unauthorized"
+// @Failure 403 {object} dto.ErrorResponse "Access denied"
+// @Failure 404 {object} dto.ErrorResponse "Application not found"
+// @Failure 409 {object} dto.StatusTransitionErrorResponse "Transition is not allowed"
 // @Failure 500 {object} dto.ErrorResponse "Failed to update application status"
 // @Router /jobs/{application_id}/status/{status} [put]
 func (h *JobApplicationHandler) UpdateJobApplicationStatus(c *gin.Context) {
@@ -130,19 +248,185 @@ func (h *JobApplicationHandler) UpdateJobApplicationStatus(c *gin.Context) {
 		return
 	}
 
-	err = h.JobApplicationService.UpdateJobApplicationStatus(c.Request.Context(), applicationID, status)
+	if _, err := h.Policy.ManageApplication(c.Request.Context(), policy.SubjectFromContext(c), applicationID); !authorized(c, err) {
+		return
+	}
+
+	var req dto.UpdateApplicationStatusRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+			return
+		}
+	}
+
+	err = h.JobApplicationService.ChangeJobApplicationStatus(c.Request.Context(), applicationID, status, c.GetInt("user_id"), req.Comment)
+	if err != nil {
+		respondStatusChangeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "updated"})
+}
+
+// MoveJobApplicationToStage godoc
+// @Summary Move a job application to a hiring stage
+// @Description Moves the application to a stage of the vacancy pipeline. Changing the underlying status follows the transition rules.
+// @Tags Job Applications
+// @Accept json
+// @Produce json
+// @Param application_id path int true "Application ID"
+// @Param stage_id path int true "Stage ID"
+// @Param request body dto.UpdateApplicationStatusRequest false "Optional comment"
+// @Security BearerAuth
+// @Success 200 {object} dto.SuccessResponse
+// @Failure 400 {object} dto.ErrorResponse "Invalid application or stage ID"
+// @Failure 401 {object} dto.ErrorResponse "Unauthorized"
+// @Failure 403 {object} dto.ErrorResponse "Access denied"
+// @Failure 404 {object} dto.ErrorResponse "Application or stage not found"
+// @Failure 409 {object} dto.StatusTransitionErrorResponse "Transition is not allowed"
+// @Failure 500 {object} dto.ErrorResponse "Failed to move application"
+// @Router /jobs/application/{application_id}/stage/{stage_id} [put]
+func (h *JobApplicationHandler) MoveJobApplicationToStage(c *gin.Context) {
+	applicationID, err := strconv.Atoi(c.Param("application_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid application ID"})
+		return
+	}
+
+	if _, err := h.Policy.ManageApplication(c.Request.Context(), policy.SubjectFromContext(c), applicationID); !authorized(c, err) {
+		return
+	}
+	stageID, err := strconv.Atoi(c.Param("stage_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid stage ID"})
+		return
+	}
+
+	var req dto.UpdateApplicationStatusRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+			return
+		}
+	}
+
+	err = h.JobApplicationService.MoveJobApplicationToStage(c.Request.Context(), applicationID, stageID, c.GetInt("user_id"), req.Comment)
+	if err != nil {
+		respondStatusChangeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "updated"})
+}
+
+// GetVacancyBoard godoc
+// @Summary Get the Kanban board of a vacancy
+// @Description Returns the vacancy pipeline stages in order, each with its applications
+// @Tags Job Applications
+// @Produce json
+// @Param vacancy_id path int true "Vacancy ID"
+// @Security BearerAuth
+// @Success 200 {array} dto.StageColumn
+// @Failure 400 {object} dto.ErrorResponse "Invalid vacancy ID"
+// @Failure 401 {object} dto.ErrorResponse "Unauthorized"
+// @Failure 403 {object} dto.ErrorResponse "Access denied"
+// @Failure 404 {object} dto.ErrorResponse "Vacancy not found"
+// @Failure 500 {object} dto.ErrorResponse "Failed to retrieve board"
+// @Router /jobs/vacancy/{vacancy_id}/board [get]
+func (h *JobApplicationHandler) GetVacancyBoard(c *gin.Context) {
+	vacancyID, err := strconv.Atoi(c.Param("vacancy_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid vacancy ID"})
+		return
+	}
+
+	if _, err := h.Policy.CompanyVacancy(c.Request.Context(), policy.SubjectFromContext(c), vacancyID); !authorized(c, err) {
+		return
+	}
+
+	board, err := h.JobApplicationService.GetVacancyBoard(c.Request.Context(), vacancyID)
+	if errors.Is(err, service.ErrVacancyNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Vacancy not found"})
+		return
+	}
 	if err != nil {
+		logger.Log.Error("Failed to get vacancy board", "vacancy_id", vacancyID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve board"})
+		return
+	}
+
+	c.JSON(http.StatusOK, board)
+}
+
+func respondStatusChangeError(c *gin.Context, err error) {
+	var transitionErr *service.StatusTransitionError
+	switch {
+	case errors.Is(err, service.ErrUnknownApplicationStatus):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid status"})
+	case errors.Is(err, service.ErrJobApplicationNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Application not found"})
+	case errors.Is(err, service.ErrJobApplicationForbidden):
+		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
+	case errors.Is(err, service.ErrStageNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Stage not found"})
+	case errors.As(err, &transitionErr):
+		c.JSON(http.StatusConflict, dto.StatusTransitionErrorResponse{Error: err.Error(), Allowed: transitionErr.Allowed})
+	case errors.Is(err, service.ErrStatusTransitionDenied):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
 		logger.Log.Error("Failed to update status", "error", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+// GetJobApplicationTimeline godoc
+// @Summary Get the status timeline of a job application
+// @Description Returns every status change of the application with its author, comment and time
+// @Tags Job Applications
+// @Produce json
+// @Param application_id path int true "Application ID"
+// @Security BearerAuth
+// @Success 200 {array} entity.JobApplicationStatusChange
+// @Failure 400 {object} dto.ErrorResponse "Invalid application ID"
+// @Failure 401 {object} dto.ErrorResponse "Unauthorized"
+// @Failure 403 {object} dto.ErrorResponse "Access denied"
+// @Failure 404 {object} dto.ErrorResponse "Application not found"
+// @Failure 500 {object} dto.ErrorResponse "Failed to retrieve timeline"
+// @Router /jobs/application/{application_id}/timeline [get]
+func (h *JobApplicationHandler) GetJobApplicationTimeline(c *gin.Context) {
+	applicationID, err := strconv.Atoi(c.Param("application_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid application ID"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"status": "updated"})
+	sub := policy.SubjectFromContext(c)
+	app, err := h.Policy.ViewApplication(c.Request.Context(), sub, applicationID)
+	if !authorized(c, err) {
+		return
+	}
+
+	timeline, err := h.JobApplicationService.GetJobApplicationTimeline(c.Request.Context(), applicationID)
+	if err == nil && app.UserID == sub.UserID {
+		timeline = service.HideForeignComments(timeline, sub.UserID)
+	}
+	if errors.Is(err, service.ErrJobApplicationNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Application not found"})
+		return
+	}
+	if err != nil {
+		logger.Log.Error("Failed to get timeline", "application_id", applicationID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve timeline"})
+		return
+	}
+
+	c.JSON(http.StatusOK, timeline)
 }
 
 // DeleteJobApplication godoc
 // @Summary Delete a job application
-// @Description Delete a job application by application ID
+// @Description Permanently delete a job application by application ID (HR only). Candidates withdraw applications instead.
 // @Tags Job Applications
 // @Accept json
 // @Produce json
@@ -151,6 +435,7 @@ func (h *JobApplicationHandler) UpdateJobApplicationStatus(c *gin.Context) {
 // @Success 200 {object} dto.SuccessResponse
 // @Failure 400 {object} dto.ErrorResponse "Invalid application ID"
 // @Failure 401 {object} dto.ErrorResponse "Unauthorized"
+// @Failure 403 {object} dto.ErrorResponse "Forbidden"
 // @Failure 500 {object} dto.ErrorResponse "Failed to delete job application"
 // @Router /jobs/{application_id} [delete]
 func (h *JobApplicationHandler) DeleteJobApplication(c *gin.Context) {
@@ -163,6 +448,10 @@ func (h *JobApplicationHandler) DeleteJobApplication(c *gin.Context) {
 		return
 	}
 
+	if _, err := h.Policy.ManageApplication(c.Request.Context(), policy.SubjectFromContext(c), applicationID); !authorized(c, err) {
+		return
+	}
+
 	err = h.JobApplicationService.DeleteJobApplication(c.Request.Context(), applicationID)
 	if err != nil {
 		logger.Log.Error("Failed to delete job application", "error", err)
@@ -175,9 +464,10 @@ func (h *JobApplicationHandler) DeleteJobApplication(c *gin.Context) {
 
 // GetJobAppAnalytics godoc
 // @Summary Get HR analytics for job applications
-// @Description Retrieves analytical statistics for job applications assigned to the HR user
+// @Description Retrieves statistics for job applications of the HR user grouped by hiring stage
 // @Tags Job Applications
 // @Produce json
+// @Param vacancy_id query int false "Limit statistics to one vacancy"
 // @Security BearerAuth
 // @Success 200 {array} dto.JobAppStatusAnalytics
 // @Failure 401 {object} dto.ErrorResponse "Unauthorized"
@@ -187,7 +477,17 @@ func (h *JobApplicationHandler) GetJobAppAnalytics(c *gin.Context) {
 	userID := c.GetInt("user_id")
 	logger.Log.Info("Getting HR analytics", "user_id", userID)
 
-	stats, err := h.JobApplicationService.GetJobAppAnalytics(c.Request.Context(), userID)
+	vacancyID := 0
+	if raw := c.Query("vacancy_id"); raw != "" {
+		id, err := strconv.Atoi(raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid vacancy ID"})
+			return
+		}
+		vacancyID = id
+	}
+
+	stats, err := h.JobApplicationService.GetJobAppAnalytics(c.Request.Context(), userID, vacancyID)
 	if err != nil {
 		logger.Log.Error("Failed to get HR analytics", "error", err, "user_id", userID)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get analytics"})
@@ -209,6 +509,7 @@ func (h *JobApplicationHandler) GetJobAppAnalytics(c *gin.Context) {
 // @Success 200 {object} dto.JobApplicationWithResumeResponse
 // @Failure 400 {object} dto.ErrorResponse "Invalid application ID"
 // @Failure 401 {object} dto.ErrorResponse "Unauthorized"
+// @Failure 403 {object} dto.ErrorResponse "Access denied"
 // @Failure 500 {object} dto.ErrorResponse "Failed to retrieve application"
 // @Router /jobs/application/{application_id} [get]
 func (h *JobApplicationHandler) GetJobApplicationByID(c *gin.Context) {
@@ -219,6 +520,10 @@ func (h *JobApplicationHandler) GetJobApplicationByID(c *gin.Context) {
 		return
 	}
 
+	if _, err := h.Policy.ViewApplication(c.Request.Context(), policy.SubjectFromContext(c), applicationID); !authorized(c, err) {
+		return
+	}
+
 	application, err := h.JobApplicationService.GetJobApplicationByID(c.Request.Context(), applicationID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve application"})
@@ -227,3 +532,81 @@ func (h *JobApplicationHandler) GetJobApplicationByID(c *gin.Context) {
 
 	c.JSON(http.StatusOK, application)
 }
+
+// RescoreJobApplication godoc
+// @Summary Re-run AI matching for a job application
+// @Description Resets the AI score of the application to pending and queues it for background scoring
+// @Tags Job Applications
+// @Produce json
+// @Param application_id path int true "Application ID"
+// @Security BearerAuth
+// @Success 202 {object} dto.SuccessResponse
+// @Failure 400 {object} dto.ErrorResponse "Invalid application ID"
+// @Failure 401 {object} dto.ErrorResponse "Unauthorized"
+// @Failure 403 {object} dto.ErrorResponse "Access denied"
+// @Failure 404 {object} dto.ErrorResponse "Application not found"
+// @Failure 409 {object} dto.ErrorResponse "Application is being scored right now"
+// @Failure 500 {object} dto.ErrorResponse "Failed to queue rescoring"
+// @Router /jobs/application/{application_id}/rescore [post]
+func (h *JobApplicationHandler) RescoreJobApplication(c *gin.Context) {
+	applicationID, err := strconv.Atoi(c.Param("application_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid application ID"})
+		return
+	}
+
+	if _, err := h.Policy.ManageApplication(c.Request.Context(), policy.SubjectFromContext(c), applicationID); !authorized(c, err) {
+		return
+	}
+
+	err = h.JobApplicationService.RescoreJobApplication(c.Request.Context(), applicationID)
+	if errors.Is(err, service.ErrJobApplicationNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Application not found"})
+		return
+	}
+	if errors.Is(err, service.ErrAIScoringInProgress) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		logger.Log.Error("Failed to queue rescoring", "application_id", applicationID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to queue rescoring"})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, dto.SuccessResponse{Message: "Application queued for rescoring"})
+}
+
+// RescoreVacancyApplications godoc
+// @Summary Re-run AI matching for all applications of a vacancy
+// @Description Resets AI scores of every application for the vacancy and queues them for background scoring
+// @Tags Job Applications
+// @Produce json
+// @Param vacancy_id path int true "Vacancy ID"
+// @Security BearerAuth
+// @Success 202 {object} dto.RescoreResponse
+// @Failure 400 {object} dto.ErrorResponse "Invalid vacancy ID"
+// @Failure 401 {object} dto.ErrorResponse "Unauthorized"
+// @Failure 403 {object} dto.ErrorResponse "Access denied"
+// @Failure 500 {object} dto.ErrorResponse "Failed to queue rescoring"
+// @Router /jobs/vacancy/{vacancy_id}/rescore [post]
+func (h *JobApplicationHandler) RescoreVacancyApplications(c *gin.Context) {
+	vacancyID, err := strconv.Atoi(c.Param("vacancy_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid vacancy ID"})
+		return
+	}
+
+	if _, err := h.Policy.CompanyVacancy(c.Request.Context(), policy.SubjectFromContext(c), vacancyID); !authorized(c, err) {
+		return
+	}
+
+	queued, err := h.JobApplicationService.RescoreVacancyApplications(c.Request.Context(), vacancyID)
+	if err != nil {
+		logger.Log.Error("Failed to queue rescoring", "vacancy_id", vacancyID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to queue rescoring"})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, dto.RescoreResponse{Queued: queued})
+}
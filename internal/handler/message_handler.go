@@ -1,9 +1,9 @@
 package handler
 
 import (
-	"encoding/json"
+	"errors"
 	"github.com/gin-gonic/gin"
-	_ "jumyste-app-backend/internal/dto"
+	"jumyste-app-backend/internal/dto"
 	"jumyste-app-backend/internal/entity"
 	"jumyste-app-backend/internal/manager"
 	"jumyste-app-backend/internal/service"
@@ -11,6 +11,7 @@ import (
 	"log/slog"
 	"net/http"
 	"strconv"
+	"strings"
 )
 
 type MessageHandler struct {
@@ -27,30 +28,58 @@ func NewMessageHandler(messageService *service.MessageService, wsManager *manage
 
 // SendMessageHandler godoc
 // @Summary Send a message
-// @Description Send a message to a specific chat
+// @Description Send a text message or upload an attachment to a chat. The file type is detected from its content;
+// @Description images, video, audio and documents (pdf, doc, docx, xls, xlsx, txt) are accepted. Images get a thumbnail.
+// @Description Attachment links in the response are signed and expire.
 // @Tags Messages
 // @Accept multipart/form-data
 // @Produce json
 // @Security BearerAuth
 // @Param chat_id formData int true "Chat ID"
-// @Param type formData string true "Message Type (text, image, etc.)"
-// @Param content formData string true "Message Content"
-// @Param file_data formData string false "File URL (optional)"
-// @Success 201 {object} gin.H "Message successfully sent"
+// @Param type formData string false "Message Type (text, image, video, audio, file); derived from the file when omitted"
+// @Param content formData string false "Message Content (required without a file)"
+// @Param file formData file false "Attachment"
+// @Param reply_to_id formData int false "ID of the quoted message from the same chat"
+// @Success 201 {object} entity.Message "Message successfully sent"
 // @Failure 400 {object} dto.ErrorResponse "Invalid input"
 // @Failure 401 {object} dto.ErrorResponse "Unauthorized"
+// @Failure 403 {object} dto.ErrorResponse "Not a chat member or email not verified"
+// @Failure 413 {object} dto.ErrorResponse "File is too large"
+// @Failure 415 {object} dto.ErrorResponse "Unsupported file type"
 // @Failure 500 {object} dto.ErrorResponse "Internal server error"
 // @Router /messages [post]
 func (h *MessageHandler) SendMessageHandler(c *gin.Context) {
+	// Запас сверх лимита файла — на остальные поля формы
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, h.MessageService.Attachments.MaxBytes+1<<20)
+
+	if err := c.Request.ParseMultipartForm(32 << 20); err != nil && !errors.Is(err, http.ErrNotMultipart) {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": service.ErrFileTooLarge.Error()})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid form data"})
+		return
+	}
+
 	chatID, err := strconv.Atoi(c.PostForm("chat_id"))
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid chat ID"})
 		return
 	}
 
-	messageType := c.PostForm("type")
+	messageType := entity.MessageType(c.PostForm("type"))
 	content := c.PostForm("content")
-	fileData := c.PostForm("file_data")
+
+	var replyToID *int
+	if raw := c.PostForm("reply_to_id"); raw != "" {
+		id, err := strconv.Atoi(raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid reply_to_id"})
+			return
+		}
+		replyToID = &id
+	}
 
 	senderID, exists := c.Get("user_id")
 	if !exists {
@@ -64,45 +93,75 @@ func (h *MessageHandler) SendMessageHandler(c *gin.Context) {
 		return
 	}
 
-	var fileURL *string
-	if fileData != "" {
-		fileURL = &fileData
+	var upload *service.AttachmentUpload
+	file, header, err := c.Request.FormFile("file")
+	switch {
+	case err == nil:
+		defer file.Close()
+		upload = &service.AttachmentUpload{File: file, Header: header}
+	case !errors.Is(err, http.ErrMissingFile):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid file"})
+		return
 	}
 
-	message, err := h.MessageService.SendMessage(chatID, sender, entity.MessageType(messageType), &content, fileURL)
+	message, err := h.MessageService.SendMessage(c.Request.Context(), chatID, sender, messageType, &content, upload, replyToID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send message"})
+		switch {
+		case errors.Is(err, service.ErrNotChatMember), errors.Is(err, service.ErrEmailNotVerified):
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		case errors.Is(err, service.ErrUnsupportedMediaType):
+			c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": err.Error()})
+		case errors.Is(err, service.ErrFileTooLarge):
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": err.Error()})
+		case errors.Is(err, service.ErrAttachmentTypeMismatch), errors.Is(err, service.ErrEmptyMessage), errors.Is(err, service.ErrInvalidReply):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send message"})
+		}
 		return
 	}
-	messageData := gin.H{
-		"id":         message.ID,
-		"chat_id":    message.ChatID,
-		"sender_id":  message.SenderID,
-		"type":       message.Type,
-		"content":    message.Content,
-		"file_url":   message.FileURL,
-		"read_by":    message.ReadBy,
-		"created_at": message.CreatedAt,
-		"updated_at": message.UpdatedAt,
-		"is_mine":    message.SenderID == sender,
-	}
 
-	h.WSManager.Broadcast <- toJSON(messageData)
+	h.WSManager.PublishToChat(message.ChatID, manager.EventMessageNew, newMessagePayload(message))
 
-	c.JSON(http.StatusCreated, messageData)
+	c.JSON(http.StatusCreated, message)
+}
+
+// newMessagePayload — сообщение для рассылки в чат без is_mine, который у каждого получателя свой
+func newMessagePayload(message *entity.Message) gin.H {
+	return gin.H{
+		"id":          message.ID,
+		"chat_id":     message.ChatID,
+		"sender_id":   message.SenderID,
+		"type":        message.Type,
+		"content":     message.Content,
+		"file_url":    message.FileURL,
+		"attachment":  message.Attachment,
+		"reply_to_id": message.ReplyToID,
+		"reply_to":    message.ReplyTo,
+		"edited_at":   message.EditedAt,
+		"is_deleted":  message.IsDeleted,
+		"read_by":     message.ReadBy,
+		"created_at":  message.CreatedAt,
+		"updated_at":  message.UpdatedAt,
+	}
 }
 
 // GetMessagesByChatIDHandler godoc
 // @Summary Get messages by chat ID
-// @Description Retrieve all messages for a specific chat
+// @Description Retrieve a page of a chat's history in ascending order. Without a cursor the latest messages are returned;
+// @Description pass next_cursor as before to load older messages or as after to load newer ones.
 // @Tags Messages
 // @Accept json
 // @Produce json
 // @Security BearerAuth
 // @Param chatID path int true "Chat ID"
-// @Success 200 {array} entity.Message "List of messages"
-// @Failure 400 {object} dto.ErrorResponse "Invalid chat ID"
+// @Param before query int false "Return messages older than this message ID"
+// @Param after query int false "Return messages newer than this message ID"
+// @Param limit query int false "Page size (default 50, max 100)"
+// @Success 200 {object} dto.MessagePage "Page of messages"
+// @Failure 400 {object} dto.ErrorResponse "Invalid chat ID or cursor"
 // @Failure 401 {object} dto.ErrorResponse "Unauthorized"
+// @Failure 403 {object} dto.ErrorResponse "Not a chat member"
 // @Failure 500 {object} dto.ErrorResponse "Internal server error"
 // @Router /messages/chat/{chatID} [get]
 func (h *MessageHandler) GetMessagesByChatIDHandler(c *gin.Context) {
@@ -112,19 +171,66 @@ func (h *MessageHandler) GetMessagesByChatIDHandler(c *gin.Context) {
 		return
 	}
 
+	var cursor dto.MessagePageQuery
+	if err := c.ShouldBindQuery(&cursor); err != nil || (cursor.Before > 0 && cursor.After > 0) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cursor: use either before or after with limit up to 100"})
+		return
+	}
+
 	userID, exists := c.Get("user_id")
 	if !exists {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
 		return
 	}
 
-	messages, err := h.MessageService.GetMessagesByChatID(chatID, userID.(int))
+	page, err := h.MessageService.GetMessagesByChatID(c.Request.Context(), chatID, userID.(int), cursor)
+	if errors.Is(err, service.ErrNotChatMember) {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch messages"})
 		return
 	}
 
-	c.JSON(http.StatusOK, messages)
+	c.JSON(http.StatusOK, page)
+}
+
+// SearchMessagesHandler godoc
+// @Summary Search messages
+// @Description Full-text search across the current user's chats, newest first. Matches in snippet are wrapped in <mark>,
+// @Description the rest of the snippet is HTML-escaped. Pass next_cursor as before to load the next page.
+// @Tags Messages
+// @Produce json
+// @Security BearerAuth
+// @Param q query string true "Search text"
+// @Param chat_id query int false "Search within one chat"
+// @Param before query int false "Return results older than this message ID"
+// @Param limit query int false "Page size (default 50, max 100)"
+// @Success 200 {object} dto.MessageSearchPage "Search results"
+// @Failure 400 {object} dto.ErrorResponse "Invalid search parameters"
+// @Failure 401 {object} dto.ErrorResponse "Unauthorized"
+// @Failure 403 {object} dto.ErrorResponse "Not a chat member"
+// @Failure 500 {object} dto.ErrorResponse "Internal server error"
+// @Router /messages/search [get]
+func (h *MessageHandler) SearchMessagesHandler(c *gin.Context) {
+	var query dto.MessageSearchQuery
+	if err := c.ShouldBindQuery(&query); err != nil || strings.TrimSpace(query.Query) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid search parameters"})
+		return
+	}
+
+	page, err := h.MessageService.SearchMessages(c.Request.Context(), c.GetInt("user_id"), query)
+	if errors.Is(err, service.ErrNotChatMember) {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to search messages"})
+		return
+	}
+
+	c.JSON(http.StatusOK, page)
 }
 
 // GetMessageByIDHandler godoc
@@ -137,6 +243,7 @@ func (h *MessageHandler) GetMessagesByChatIDHandler(c *gin.Context) {
 // @Param messageID path int true "Message ID"
 // @Success 200 {object} entity.Message "Message details"
 // @Failure 400 {object} dto.ErrorResponse "Invalid message ID"
+// @Failure 403 {object} dto.ErrorResponse "Not a chat member"
 // @Failure 404 {object} dto.ErrorResponse "Message not found"
 // @Failure 500 {object} dto.ErrorResponse "Internal server error"
 // @Router /messages/{messageID} [get]
@@ -147,15 +254,206 @@ func (h *MessageHandler) GetMessageByIDHandler(c *gin.Context) {
 		return
 	}
 
-	message, err := h.MessageService.GetMessageByID(int(messageID))
+	message, err := h.MessageService.GetMessageForUser(c.Request.Context(), int(messageID), c.GetInt("user_id"))
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Message not found"})
+		respondMessageError(c, err, "Failed to fetch message")
 		return
 	}
 
 	c.JSON(http.StatusOK, message)
 }
 
+// EditMessageHandler godoc
+// @Summary Edit a message
+// @Description Replace the text of your own message. The previous text is kept in the edit history
+// @Description and chat members receive a message.edited event.
+// @Tags Messages
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param messageID path int true "Message ID"
+// @Param request body dto.EditMessageRequest true "New text"
+// @Success 200 {object} entity.Message "Edited message"
+// @Failure 400 {object} dto.ErrorResponse "Invalid input"
+// @Failure 403 {object} dto.ErrorResponse "Not the sender or not a chat member"
+// @Failure 404 {object} dto.ErrorResponse "Message not found"
+// @Failure 409 {object} dto.ErrorResponse "Message has been deleted"
+// @Failure 500 {object} dto.ErrorResponse "Internal server error"
+// @Router /messages/{messageID} [patch]
+func (h *MessageHandler) EditMessageHandler(c *gin.Context) {
+	messageID, err := strconv.Atoi(c.Param("messageID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid message ID"})
+		return
+	}
+
+	var req dto.EditMessageRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	message, err := h.MessageService.EditMessage(c.Request.Context(), messageID, c.GetInt("user_id"), req.Content)
+	if err != nil {
+		respondMessageError(c, err, "Failed to edit message")
+		return
+	}
+
+	h.WSManager.PublishToChat(message.ChatID, manager.EventMessageEdited, newMessagePayload(message))
+
+	c.JSON(http.StatusOK, message)
+}
+
+// DeleteMessageHandler godoc
+// @Summary Delete a message
+// @Description Delete your own message for everyone (chat members receive message.deleted)
+// @Description or hide any message of the chat only for yourself (scope=self, only your devices are notified).
+// @Tags Messages
+// @Produce json
+// @Security BearerAuth
+// @Param messageID path int true "Message ID"
+// @Param scope query string false "Who the message is deleted for" Enums(everyone, self) default(everyone)
+// @Success 200 {object} gin.H "Status of the operation"
+// @Failure 400 {object} dto.ErrorResponse "Invalid input"
+// @Failure 403 {object} dto.ErrorResponse "Not the sender or not a chat member"
+// @Failure 404 {object} dto.ErrorResponse "Message not found"
+// @Failure 500 {object} dto.ErrorResponse "Internal server error"
+// @Router /messages/{messageID} [delete]
+func (h *MessageHandler) DeleteMessageHandler(c *gin.Context) {
+	messageID, err := strconv.Atoi(c.Param("messageID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid message ID"})
+		return
+	}
+
+	scope := service.DeleteScope(c.DefaultQuery("scope", string(service.DeleteForEveryone)))
+	if scope != service.DeleteForEveryone && scope != service.DeleteForSelf {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "scope must be everyone or self"})
+		return
+	}
+
+	userID := c.GetInt("user_id")
+	message, err := h.MessageService.DeleteMessage(c.Request.Context(), messageID, userID, scope)
+	if err != nil {
+		respondMessageError(c, err, "Failed to delete message")
+		return
+	}
+
+	payload := manager.MessageDeletedPayload{MessageID: message.ID, ChatID: message.ChatID, Scope: string(scope)}
+	if scope == service.DeleteForSelf {
+		h.WSManager.PublishToUser(userID, manager.EventMessageDeleted, payload)
+	} else {
+		h.WSManager.PublishToChat(message.ChatID, manager.EventMessageDeleted, payload)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+// GetMessageEditsHandler godoc
+// @Summary Get message edit history
+// @Description Previous versions of a message text, oldest first
+// @Tags Messages
+// @Produce json
+// @Security BearerAuth
+// @Param messageID path int true "Message ID"
+// @Success 200 {array} entity.MessageEdit "Edit history"
+// @Failure 400 {object} dto.ErrorResponse "Invalid message ID"
+// @Failure 403 {object} dto.ErrorResponse "Not a chat member"
+// @Failure 404 {object} dto.ErrorResponse "Message not found"
+// @Failure 409 {object} dto.ErrorResponse "Message has been deleted"
+// @Failure 500 {object} dto.ErrorResponse "Internal server error"
+// @Router /messages/{messageID}/edits [get]
+func (h *MessageHandler) GetMessageEditsHandler(c *gin.Context) {
+	messageID, err := strconv.Atoi(c.Param("messageID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid message ID"})
+		return
+	}
+
+	edits, err := h.MessageService.GetMessageEdits(c.Request.Context(), messageID, c.GetInt("user_id"))
+	if err != nil {
+		respondMessageError(c, err, "Failed to fetch edit history")
+		return
+	}
+
+	c.JSON(http.StatusOK, edits)
+}
+
+// MarkChatReadHandler godoc
+// @Summary Mark a chat as read
+// @Description Mark every message of the chat up to and including up_to_message_id as read.
+// @Description Chat members receive a chat.read event.
+// @Tags Messages
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param chatID path int true "Chat ID"
+// @Param request body dto.MarkChatReadRequest true "Last read message"
+// @Success 200 {object} dto.MarkChatReadResponse "Remaining unread messages in the chat"
+// @Failure 400 {object} dto.ErrorResponse "Invalid input"
+// @Failure 403 {object} dto.ErrorResponse "Not a chat member"
+// @Failure 500 {object} dto.ErrorResponse "Internal server error"
+// @Router /messages/chat/{chatID}/read [post]
+func (h *MessageHandler) MarkChatReadHandler(c *gin.Context) {
+	chatID, err := strconv.Atoi(c.Param("chatID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid chat ID"})
+		return
+	}
+
+	var req dto.MarkChatReadRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	userID := c.GetInt("user_id")
+	unread, err := h.MessageService.MarkChatRead(c.Request.Context(), chatID, userID, req.UpToMessageID)
+	if err != nil {
+		respondMessageError(c, err, "Failed to mark chat as read")
+		return
+	}
+
+	h.WSManager.PublishToChat(chatID, manager.EventChatRead, manager.ChatReadPayload{UserID: userID, UpToMessageID: req.UpToMessageID})
+
+	c.JSON(http.StatusOK, dto.MarkChatReadResponse{ChatID: chatID, UnreadCount: unread})
+}
+
+// GetUnreadBadgeHandler godoc
+// @Summary Get unread badge
+// @Description Total unread messages across the current user's chats and the number of chats with unread messages
+// @Tags Messages
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} dto.UnreadBadge "Unread counters"
+// @Failure 401 {object} dto.ErrorResponse "Unauthorized"
+// @Failure 500 {object} dto.ErrorResponse "Internal server error"
+// @Router /messages/unread [get]
+func (h *MessageHandler) GetUnreadBadgeHandler(c *gin.Context) {
+	badge, err := h.MessageService.GetUnreadBadge(c.Request.Context(), c.GetInt("user_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count unread messages"})
+		return
+	}
+
+	c.JSON(http.StatusOK, badge)
+}
+
+func respondMessageError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, service.ErrMessageNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Message not found"})
+	case errors.Is(err, service.ErrNotChatMember), errors.Is(err, service.ErrMessageForbidden):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrMessageDeleted):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrEmptyMessage):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
+
 // MarkAsRead godoc
 // @Summary Mark message as read
 // @Description Mark a specific message as read
@@ -167,6 +465,8 @@ func (h *MessageHandler) GetMessageByIDHandler(c *gin.Context) {
 // @Success 200 {object} gin.H "Status of the operation"
 // @Failure 400 {object} dto.ErrorResponse "Invalid message ID"
 // @Failure 401 {object} dto.ErrorResponse "Unauthorized"
+// @Failure 403 {object} dto.ErrorResponse "Not a chat member"
+// @Failure 404 {object} dto.ErrorResponse "Message not found"
 // @Failure 500 {object} dto.ErrorResponse "Internal server error"
 // @Router /messages/read [post]
 func (h *MessageHandler) MarkAsRead(c *gin.Context) {
@@ -180,7 +480,7 @@ func (h *MessageHandler) MarkAsRead(c *gin.Context) {
 
 	err = h.MessageService.MarkMessageAsRead(c.Request.Context(), messageID, userID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to mark message as read"})
+		respondMessageError(c, err, "Failed to mark message as read")
 		return
 	}
 
@@ -190,19 +490,7 @@ func (h *MessageHandler) MarkAsRead(c *gin.Context) {
 		return
 	}
 
-	readEvent := gin.H{
-		"type":       "message_read",
-		"message_id": messageID,
-		"user_id":    userID,
-		"chat_id":    message.ChatID,
-	}
-
-	h.WSManager.Broadcast <- toJSON(readEvent)
+	h.WSManager.PublishToChat(message.ChatID, manager.EventMessageRead, manager.ReadPayload{MessageID: messageID, UserID: userID})
 
 	c.JSON(http.StatusOK, gin.H{"status": "ok"})
 }
-
-func toJSON(data interface{}) []byte {
-	jsonData, _ := json.Marshal(data)
-	return jsonData
-}
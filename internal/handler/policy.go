@@ -0,0 +1,26 @@
+package handler
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"jumyste-app-backend/internal/dto"
+	"jumyste-app-backend/internal/policy"
+	"jumyste-app-backend/pkg/logger"
+	"net/http"
+)
+
+// authorized отвечает 403/404 по ошибке политики доступа и возвращает false, если обработку нужно прервать
+func authorized(c *gin.Context, err error) bool {
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, policy.ErrForbidden):
+		c.JSON(http.StatusForbidden, dto.ErrorResponse{Error: "Access denied"})
+	case errors.Is(err, policy.ErrNotFound):
+		c.JSON(http.StatusNotFound, dto.ErrorResponse{Error: "Resource not found"})
+	default:
+		logger.Log.Error("Failed to check access", "error", err)
+		c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Error: "Failed to check access"})
+	}
+	return false
+}
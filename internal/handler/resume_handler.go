@@ -4,6 +4,7 @@ import (
 	"jumyste-app-backend/internal/ai"
 	"jumyste-app-backend/internal/dto"
 	_ "jumyste-app-backend/internal/entity"
+	"jumyste-app-backend/internal/policy"
 	"net/http"
 	"strconv"
 
@@ -14,11 +15,12 @@ import (
 
 type ResumeHandler struct {
 	ResumeService *service.ResumeService
-	OpenAIClient  *ai.OpenAIClient
+	AIProvider    ai.Provider
+	Policy        *policy.Policy
 }
 
-func NewResumeHandler(resumeService *service.ResumeService, openAIClient *ai.OpenAIClient) *ResumeHandler {
-	return &ResumeHandler{ResumeService: resumeService, OpenAIClient: openAIClient}
+func NewResumeHandler(resumeService *service.ResumeService, aiProvider ai.Provider, policy *policy.Policy) *ResumeHandler {
+	return &ResumeHandler{ResumeService: resumeService, AIProvider: aiProvider, Policy: policy}
 }
 
 // GenerateResumeDraft godoc
@@ -42,7 +44,7 @@ func (h *ResumeHandler) GenerateResumeDraft(c *gin.Context) {
 		return
 	}
 
-	result, err := h.OpenAIClient.GenerateResumeFromAI(req)
+	result, err := h.AIProvider.GenerateResumeFromAI(req)
 	if err != nil {
 		logger.Log.Error("Failed to generate resume draft", "error", err)
 		c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Error: "Failed to generate resume draft"})
@@ -150,7 +152,7 @@ func (h *ResumeHandler) CreateResume(c *gin.Context) {
 
 // GetResumeByUserID godoc
 // @Summary Get a resume by user ID
-// @Description Retrieve the resume of a user by their user ID
+// @Description Retrieve the resume of a user by their user ID. Available to the owner and to HR of companies the user applied to.
 // @Tags Resume
 // @Accept json
 // @Produce json
@@ -159,6 +161,7 @@ func (h *ResumeHandler) CreateResume(c *gin.Context) {
 // @Success 200 {object} dto.ResumeResponse
 // @Failure 400 {object} dto.ErrorResponse "Invalid user ID"
 // @Failure 401 {object} dto.ErrorResponse "Unauthorized"
+// @Failure 403 {object} dto.ErrorResponse "Access denied"
 // @Failure 404 {object} dto.ErrorResponse "Resume or user not found"
 // @Failure 500 {object} dto.ErrorResponse "Failed to get resume"
 // @Router /resume/{user_id} [get]
@@ -171,6 +174,10 @@ func (h *ResumeHandler) GetResumeByUserID(c *gin.Context) {
 		return
 	}
 
+	if err := h.Policy.ViewResume(c.Request.Context(), policy.SubjectFromContext(c), userIDInt); !authorized(c, err) {
+		return
+	}
+
 	resume, user, err := h.ResumeService.GetResumeAndUserByUserID(c.Request.Context(), userIDInt)
 	if err != nil {
 		logger.Log.Error("Failed to get resume and user", "error", err)
@@ -244,11 +251,13 @@ func (h *ResumeHandler) DeleteResumeByUserID(c *gin.Context) {
 
 // FilterCandidates godoc
 // @Summary Filter candidates based on specified criteria
-// @Description Filter candidates using multiple query parameters such as AI match score, skills, city, and position.
+// @Description Filter candidates using multiple query parameters such as AI match score, rule-based score, skills (synonyms like JS/JavaScript are matched), city, and position. Only applications to vacancies of the HR's company are returned, ordered by rule-based score.
 // @Tags Resume
 // @Accept json
 // @Produce json
 // @Param ai_match query int false "Minimum AI match score"
+// @Param rule_match query int false "Minimum rule-based match score"
+// @Param vacancy_id query int false "Vacancy ID"
 // @Param skills query string false "Skills (can be passed multiple times)"
 // @Param city query string false "City of the candidate"
 // @Param position query string false "Desired position of the candidate"
@@ -256,6 +265,7 @@ func (h *ResumeHandler) DeleteResumeByUserID(c *gin.Context) {
 // @Success 200 {array} entity.JobApplicationWithResume "List of filtered candidates"
 // @Failure 400 {object} dto.ErrorResponse "Invalid query parameters"
 // @Failure 401 {object} dto.ErrorResponse "Unauthorized"
+// @Failure 403 {object} dto.ErrorResponse "Insufficient permissions"
 // @Failure 500 {object} dto.ErrorResponse "Failed to filter candidates"
 // @Router /resume/candidates [get]
 func (h *ResumeHandler) FilterCandidates(c *gin.Context) {
@@ -266,6 +276,7 @@ func (h *ResumeHandler) FilterCandidates(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid query parameters"})
 		return
 	}
+	filter.CompanyID = c.GetInt("company_id")
 
 	ctx := c.Request.Context()
 	candidates, err := h.ResumeService.FilterCandidates(ctx, filter)
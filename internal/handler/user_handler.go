@@ -70,12 +70,12 @@ func (h *UserHandler) GetUser(c *gin.Context) {
 
 // UpdateUser godoc
 // @Summary      Update user information
-// @Description  Update user details such as name, email, or profile picture
+// @Description  Update the user's first name, last name or profile picture; other fields are ignored
 // @Tags         Users
 // @Accept       json
 // @Produce      json
 // @Security     BearerAuth
-// @Param        updates  body      map[string]interface{}  true  "Fields to update"
+// @Param        updates  body      dto.UpdateUserRequest  true  "Fields to update"
 // @Success      200      {object}  dto.SuccessResponse
 // @Failure      400      {object}  dto.ErrorResponse  "Invalid request body"
 // @Failure      401      {object}  dto.ErrorResponse  "Unauthorized"
@@ -94,14 +94,14 @@ func (h *UserHandler) UpdateUser(c *gin.Context) {
 		return
 	}
 
-	var updates map[string]interface{}
+	var updates dto.UpdateUserRequest
 	if err := c.ShouldBindJSON(&updates); err != nil {
 		logger.Log.Error("Invalid request body", slog.String("error", err.Error()))
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
 		return
 	}
 
-	logger.Log.Info("Updating user", slog.Int("user_id", id), slog.Any("updates", updates))
+	logger.Log.Info("Updating user", slog.Int("user_id", id))
 
 	if err := h.UserService.UpdateUser(id, updates); err != nil {
 		logger.Log.Error("Failed to update user", slog.String("error", err.Error()))
@@ -4,6 +4,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"jumyste-app-backend/internal/dto"
 	"jumyste-app-backend/internal/entity"
+	"jumyste-app-backend/internal/policy"
 	"jumyste-app-backend/internal/service"
 	"jumyste-app-backend/pkg/logger"
 	"log/slog"
@@ -13,10 +14,11 @@ import (
 
 type VacancyHandler struct {
 	VacancyService *service.VacancyService
+	Policy         *policy.Policy
 }
 
-func NewVacancyHandler(vacancyService *service.VacancyService) *VacancyHandler {
-	return &VacancyHandler{VacancyService: vacancyService}
+func NewVacancyHandler(vacancyService *service.VacancyService, policy *policy.Policy) *VacancyHandler {
+	return &VacancyHandler{VacancyService: vacancyService, Policy: policy}
 }
 
 // CreateVacancy godoc
@@ -63,7 +65,7 @@ func (h *VacancyHandler) CreateVacancy(c *gin.Context) {
 // UpdateVacancy godoc
 //
 // @Summary Update an existing vacancy
-// @Description Allows an employer to update their own vacancy
+// @Description Allows the author of the vacancy or an HR from the same department to update it
 // @Tags Vacancies
 // @Accept json
 // @Produce json
@@ -84,26 +86,19 @@ func (h *VacancyHandler) UpdateVacancy(c *gin.Context) {
 		return
 	}
 
-	userID := c.GetInt("user_id")
-	vacancy, err := h.VacancyService.GetVacancyById(vacancyID, true)
-	if err != nil {
-		logger.Log.Error("Vacancy not found", slog.Int("vacancy_id", vacancyID), slog.String("error", err.Error()))
-		c.JSON(http.StatusNotFound, gin.H{"error": "Vacancy not found"})
-		return
-	}
-
-	if vacancy.CreatedBy != userID {
-		logger.Log.Warn("Unauthorized vacancy update attempt",
-			slog.Int("vacancy_id", vacancyID), slog.Int("user_id", userID))
-		c.JSON(http.StatusForbidden, gin.H{"error": "You can only update your own vacancies"})
+	vacancy, err := h.Policy.EditVacancy(c.Request.Context(), policy.SubjectFromContext(c), vacancyID)
+	if !authorized(c, err) {
 		return
 	}
 
+	id, createdBy, companyID := vacancy.ID, vacancy.CreatedBy, vacancy.CompanyId
 	if err := c.ShouldBindJSON(&vacancy); err != nil {
 		logger.Log.Error("Invalid vacancy update input", slog.String("error", err.Error()))
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
 		return
 	}
+	// Тело запроса не может перенести вакансию к другому автору или компании
+	vacancy.ID, vacancy.CreatedBy, vacancy.CompanyId = id, createdBy, companyID
 
 	if err := h.VacancyService.UpdateVacancy(vacancy); err != nil {
 		logger.Log.Error("Failed to update vacancy", slog.String("error", err.Error()))
@@ -118,7 +113,7 @@ func (h *VacancyHandler) UpdateVacancy(c *gin.Context) {
 // DeleteVacancy godoc
 //
 // @Summary Delete a vacancy
-// @Description Allows an employer to delete their own vacancy
+// @Description Allows the author of the vacancy or an HR from the same department to delete it
 // @Tags Vacancies
 // @Accept json
 // @Produce json
@@ -143,16 +138,12 @@ func (h *VacancyHandler) DeleteVacancy(c *gin.Context) {
 	logger.Log.Info("Attempting to delete vacancy",
 		slog.Int("vacancy_id", vacancyID), slog.Int("user_id", userID))
 
-	err = h.VacancyService.DeleteVacancy(vacancyID, userID)
-	if err != nil {
-		switch err.Error() {
-		case "vacancy not found":
-			c.JSON(http.StatusNotFound, dto.ErrorResponse{Error: "Vacancy not found"})
-		case "you can only delete your own vacancies":
-			c.JSON(http.StatusForbidden, dto.ErrorResponse{Error: "You can only delete your own vacancies"})
-		default:
-			c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Error: "Failed to delete vacancy"})
-		}
+	if _, err := h.Policy.EditVacancy(c.Request.Context(), policy.SubjectFromContext(c), vacancyID); !authorized(c, err) {
+		return
+	}
+
+	if err := h.VacancyService.DeleteVacancy(vacancyID); err != nil {
+		c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Error: "Failed to delete vacancy"})
 		return
 	}
 
@@ -331,6 +322,7 @@ func (h *VacancyHandler) GetVacancyByIDForUser(c *gin.Context) {
 // @Param        id   path      int  true  "Vacancy ID"
 // @Success      200  {object}  entity.Vacancy
 // @Failure      400  {object}  dto.ErrorResponse  "Invalid vacancy ID"
+// @Failure      403  {object}  dto.ErrorResponse  "Vacancy belongs to another company"
 // @Failure      404  {object}  dto.ErrorResponse  "Vacancy not found"
 // @Router       /vacancies/hr/{id} [get]
 func (h *VacancyHandler) GetVacancyByIDForHr(c *gin.Context) {
@@ -341,6 +333,10 @@ func (h *VacancyHandler) GetVacancyByIDForHr(c *gin.Context) {
 		return
 	}
 
+	if _, err := h.Policy.CompanyVacancy(c.Request.Context(), policy.SubjectFromContext(c), vacancyID); !authorized(c, err) {
+		return
+	}
+
 	vacancy, err := h.VacancyService.GetVacancyById(vacancyID, true)
 	if err != nil {
 		logger.Log.Error("Vacancy not found", slog.Int("vacancy_id", vacancyID), slog.String("error", err.Error()))
@@ -363,6 +359,8 @@ func (h *VacancyHandler) GetVacancyByIDForHr(c *gin.Context) {
 // @Param        request body dto.UpdateVacancyStatusRequest true  "New status"
 // @Success      200 {object} dto.SuccessResponse
 // @Failure      400 {object} dto.ErrorResponse
+// @Failure      403 {object} dto.ErrorResponse
+// @Failure      404 {object} dto.ErrorResponse
 // @Failure      500 {object} dto.ErrorResponse
 // @Router       /vacancies/status/{id} [put]
 func (h *VacancyHandler) UpdateVacancyStatusHandler(c *gin.Context) {
@@ -372,6 +370,11 @@ func (h *VacancyHandler) UpdateVacancyStatusHandler(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, dto.ErrorResponse{Error: "Invalid vacancy ID"})
 		return
 	}
+
+	if _, err := h.Policy.EditVacancy(c.Request.Context(), policy.SubjectFromContext(c), vacancyID); !authorized(c, err) {
+		return
+	}
+
 	var request dto.UpdateVacancyStatusRequest
 
 	if err := c.ShouldBindJSON(&request); err != nil {
@@ -6,6 +6,7 @@ import (
 	_ "jumyste-app-backend/internal/dto"
 	"jumyste-app-backend/internal/manager"
 	"jumyste-app-backend/internal/middleware"
+	"jumyste-app-backend/internal/service"
 	"jumyste-app-backend/pkg/logger"
 	"net/http"
 	"strconv"
@@ -14,12 +15,14 @@ import (
 type WebSocketHandler struct {
 	WSManager      *manager.WebSocketManager
 	AuthMiddleware *middleware.AuthMiddleware
+	ChatService    *service.ChatService
 }
 
-func NewWebSocketHandler(wsManager *manager.WebSocketManager, authMiddleware *middleware.AuthMiddleware) *WebSocketHandler {
+func NewWebSocketHandler(wsManager *manager.WebSocketManager, authMiddleware *middleware.AuthMiddleware, chatService *service.ChatService) *WebSocketHandler {
 	return &WebSocketHandler{
 		WSManager:      wsManager,
 		AuthMiddleware: authMiddleware,
+		ChatService:    chatService,
 	}
 }
 
@@ -32,15 +35,20 @@ var upgrader = websocket.Upgrader{
 
 // HandleWebSocket godoc
 //
-// @Summary WebSocket соединение с чатом
-// @Description Устанавливает WebSocket-соединение с авторизованным пользователем и chat_id в query
+// @Summary WebSocket соединение пользователя
+// @Description Открывает одно мультиплексированное WebSocket-соединение на пользователя.
+// @Description Все кадры — конверт {"v":1,"type":"...","chat_id":N,"payload":{...}}.
+// @Description Сервер присылает message.new, message.read, typing, presence, application.status_changed и vacancy.new.
+// @Description Клиент отправляет subscribe/unsubscribe (с chat_id), typing и message.read.
+// @Description Без chat_id соединение подписывается на все чаты пользователя, с chat_id — только на указанный.
 // @Tags WebSocket
 // @Produce plain
 // @Security BearerAuth
-// @Param chat_id query int true "ID чата"
+// @Param chat_id query int false "ID чата для начальной подписки"
 // @Success 101 {string} string "Switching Protocols – WebSocket connection established"
 // @Failure 400 {object} dto.ErrorResponse "Invalid chat ID"
 // @Failure 401 {object} dto.ErrorResponse "Unauthorized – отсутствует или неверный токен"
+// @Failure 403 {object} dto.ErrorResponse "Пользователь не участник чата"
 // @Failure 500 {object} dto.ErrorResponse "Ошибка при апгрейде соединения"
 // @Router /ws [get]
 func (h *WebSocketHandler) HandleWebSocket(c *gin.Context) {
@@ -58,10 +66,8 @@ func (h *WebSocketHandler) HandleWebSocket(c *gin.Context) {
 
 	userID := claims.UserID
 
-	chatIDStr := c.Query("chat_id")
-	chatID, err := strconv.Atoi(chatIDStr)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid chat ID"})
+	chatIDs, ok := h.initialChats(c, userID)
+	if !ok {
 		return
 	}
 
@@ -72,15 +78,42 @@ func (h *WebSocketHandler) HandleWebSocket(c *gin.Context) {
 		return
 	}
 
-	client := &manager.Client{
-		Conn:   conn,
-		Send:   make(chan []byte),
-		UserID: userID,
-		ChatID: chatID,
-	}
+	client := h.WSManager.NewClient(conn, userID, claims.RoleID, chatIDs)
 
 	h.WSManager.Register <- client
 
 	go client.WriteMessages()
 	h.WSManager.HandleClient(client)
 }
+
+// initialChats определяет чаты, на которые соединение подписывается сразу.
+// Участие проверяется до апгрейда, чтобы отказ пришёл обычным HTTP-ответом.
+func (h *WebSocketHandler) initialChats(c *gin.Context, userID int) ([]int, bool) {
+	chatIDStr := c.Query("chat_id")
+	if chatIDStr == "" {
+		chatIDs, err := h.ChatService.GetChatIDsByUserID(userID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch user chats"})
+			return nil, false
+		}
+		return chatIDs, true
+	}
+
+	chatID, err := strconv.Atoi(chatIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid chat ID"})
+		return nil, false
+	}
+
+	isMember, err := h.ChatService.IsChatMember(chatID, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check chat membership"})
+		return nil, false
+	}
+	if !isMember {
+		logger.Log.Warn("WebSocket connection refused: not a chat member", "chat_id", chatID, "user_id", userID)
+		c.JSON(http.StatusForbidden, gin.H{"error": "You are not a participant of this chat"})
+		return nil, false
+	}
+	return []int{chatID}, true
+}
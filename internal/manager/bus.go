@@ -0,0 +1,74 @@
+package manager
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+)
+
+// Адресаты события в шине
+const (
+	TargetChat  = "chat"
+	TargetUser  = "user"
+	TargetRole  = "role"
+	TargetJoin  = "join"  // подписать соединения UserIDs на чат ID
+	TargetLeave = "leave" // отписать соединения UserIDs от чата ID
+)
+
+// BusEvent — событие, которое каждый экземпляр приложения доставляет своим соединениям
+type BusEvent struct {
+	Target       string          `json:"target"`
+	ID           int             `json:"id"`
+	ExceptUserID int             `json:"except_user_id,omitempty"`
+	UserIDs      []int           `json:"user_ids,omitempty"`
+	Data         json.RawMessage `json:"data,omitempty"`
+}
+
+// Bus рассылает события всем экземплярам приложения, включая отправителя
+type Bus interface {
+	Publish(ctx context.Context, event BusEvent) error
+	// Subscribe регистрирует обработчик до отмены ctx; события приходят и от самого экземпляра
+	Subscribe(ctx context.Context, handler func(BusEvent)) error
+}
+
+// MemoryBus — шина в пределах одного процесса: для одного экземпляра без Redis и для тестов
+type MemoryBus struct {
+	mu       sync.RWMutex
+	nextID   int
+	handlers map[int]func(BusEvent)
+}
+
+func NewMemoryBus() *MemoryBus {
+	return &MemoryBus{handlers: make(map[int]func(BusEvent))}
+}
+
+// Publish синхронно вызывает все обработчики
+func (b *MemoryBus) Publish(ctx context.Context, event BusEvent) error {
+	b.mu.RLock()
+	handlers := make([]func(BusEvent), 0, len(b.handlers))
+	for _, handler := range b.handlers {
+		handlers = append(handlers, handler)
+	}
+	b.mu.RUnlock()
+
+	for _, handler := range handlers {
+		handler(event)
+	}
+	return nil
+}
+
+func (b *MemoryBus) Subscribe(ctx context.Context, handler func(BusEvent)) error {
+	b.mu.Lock()
+	id := b.nextID
+	b.nextID++
+	b.handlers[id] = handler
+	b.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		b.mu.Lock()
+		delete(b.handlers, id)
+		b.mu.Unlock()
+	}()
+	return nil
+}
@@ -0,0 +1,103 @@
+package manager
+
+import (
+	"github.com/gorilla/websocket"
+	"jumyste-app-backend/config"
+	"time"
+)
+
+// Значения по умолчанию для неуказанных или некорректных настроек WebSocket
+const (
+	defaultSendBufferSize  = 64
+	defaultMaxMessageBytes = 64 * 1024
+	defaultPongWait        = 60 * time.Second
+	defaultWriteWait       = 10 * time.Second
+)
+
+// clientSettings — ограничения и таймауты одного соединения
+type clientSettings struct {
+	sendBufferSize  int
+	maxMessageBytes int64
+	pongWait        time.Duration
+	pingPeriod      time.Duration
+	writeWait       time.Duration
+}
+
+func newClientSettings(cfg config.WebSocketConfig) clientSettings {
+	settings := clientSettings{
+		sendBufferSize:  cfg.SendBufferSize,
+		maxMessageBytes: int64(cfg.MaxMessageBytes),
+		pongWait:        time.Duration(cfg.PongWaitSeconds) * time.Second,
+		writeWait:       time.Duration(cfg.WriteWaitSeconds) * time.Second,
+	}
+	if settings.sendBufferSize <= 0 {
+		settings.sendBufferSize = defaultSendBufferSize
+	}
+	if settings.maxMessageBytes <= 0 {
+		settings.maxMessageBytes = defaultMaxMessageBytes
+	}
+	if settings.pongWait <= 0 {
+		settings.pongWait = defaultPongWait
+	}
+	if settings.writeWait <= 0 {
+		settings.writeWait = defaultWriteWait
+	}
+	// ping уходит чаще, чем истекает ожидание pong, чтобы живое соединение не закрылось по таймауту
+	settings.pingPeriod = settings.pongWait * 9 / 10
+	return settings
+}
+
+// Client представляет одно WebSocket-соединение пользователя. Через него приходят
+// события всех чатов, на которые клиент подписан, и личные события пользователя.
+type Client struct {
+	ID     string // уникален среди всех экземпляров, ключ соединения в PresenceStore
+	Conn   *websocket.Conn
+	Send   chan []byte
+	UserID int
+	RoleID int
+	// chats и typing защищены WebSocketManager.mu после регистрации
+	chats  map[int]struct{}
+	typing map[int]struct{}
+
+	settings clientSettings
+	// closeCode и closeText выставляются под WebSocketManager.mu перед закрытием Send
+	closeCode int
+	closeText string
+	done      func()
+}
+
+// WriteMessages — единственный писатель в сокет: отправляет события из очереди и ping
+// каждые pingPeriod. Каждая запись ограничена writeWait, чтобы зависший клиент не держал горутину.
+// Когда менеджер закрывает очередь, клиенту уходит кадр закрытия с причиной.
+func (client *Client) WriteMessages() {
+	ticker := time.NewTicker(client.settings.pingPeriod)
+	defer func() {
+		ticker.Stop()
+		client.Conn.Close()
+		client.done()
+	}()
+
+	for {
+		select {
+		case message, ok := <-client.Send:
+			client.Conn.SetWriteDeadline(time.Now().Add(client.settings.writeWait))
+			if !ok {
+				client.Conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(client.closeCode, client.closeText))
+				return
+			}
+			if err := client.Conn.WriteMessage(websocket.TextMessage, message); err != nil {
+				return
+			}
+
+		case <-ticker.C:
+			client.Conn.SetWriteDeadline(time.Now().Add(client.settings.writeWait))
+			if err := client.Conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func (client *Client) extendReadDeadline() {
+	client.Conn.SetReadDeadline(time.Now().Add(client.settings.pongWait))
+}
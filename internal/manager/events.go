@@ -0,0 +1,107 @@
+package manager
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// ProtocolVersion — версия конверта WebSocket-протокола; клиент может не указывать её
+const ProtocolVersion = 1
+
+// События сервер → клиент
+const (
+	EventMessageNew               = "message.new"
+	EventMessageRead              = "message.read"
+	EventChatRead                 = "chat.read"
+	EventChatUpdated              = "chat.updated"
+	EventChatRemoved              = "chat.removed"
+	EventMessageEdited            = "message.edited"
+	EventMessageDeleted           = "message.deleted"
+	EventTyping                   = "typing"
+	EventPresence                 = "presence"
+	EventApplicationStatusChanged = "application.status_changed"
+	EventVacancyNew               = "vacancy.new"
+	EventSubscribed               = "subscribed"
+	EventUnsubscribed             = "unsubscribed"
+	EventError                    = "error"
+)
+
+// Команды клиент → сервер; typing и message.read клиент отправляет теми же типами, что получает
+const (
+	CommandSubscribe   = "subscribe"
+	CommandUnsubscribe = "unsubscribe"
+)
+
+// Envelope — единый конверт всех кадров в обе стороны
+type Envelope struct {
+	V         int             `json:"v"`
+	Type      string          `json:"type"`
+	ChatID    int             `json:"chat_id,omitempty"`
+	RequestID string          `json:"request_id,omitempty"`
+	Payload   json.RawMessage `json:"payload,omitempty"`
+}
+
+// TypingPayload — пользователь начал или закончил набирать сообщение
+type TypingPayload struct {
+	UserID   int  `json:"user_id"`
+	IsTyping bool `json:"is_typing"`
+}
+
+// PresencePayload — пользователь появился в сети или вышел из неё
+type PresencePayload struct {
+	UserID     int        `json:"user_id"`
+	Online     bool       `json:"online"`
+	LastSeenAt *time.Time `json:"last_seen_at,omitempty"`
+}
+
+// ReadPayload — сообщение прочитано пользователем
+type ReadPayload struct {
+	MessageID int `json:"message_id"`
+	UserID    int `json:"user_id"`
+}
+
+// ChatReadPayload — пользователь прочитал чат до сообщения включительно
+type ChatReadPayload struct {
+	UserID        int `json:"user_id"`
+	UpToMessageID int `json:"up_to_message_id"`
+}
+
+// ChatRemovedPayload — пользователь больше не участник группового чата
+type ChatRemovedPayload struct {
+	ChatID int `json:"chat_id"`
+}
+
+// MessageDeletedPayload — сообщение удалено для всех участников или только у текущего пользователя
+// (во втором случае событие получают лишь его устройства)
+type MessageDeletedPayload struct {
+	MessageID int    `json:"message_id"`
+	ChatID    int    `json:"chat_id"`
+	Scope     string `json:"scope"`
+}
+
+// ApplicationStatusPayload — статус отклика соискателя изменился
+type ApplicationStatusPayload struct {
+	ApplicationID int    `json:"application_id"`
+	VacancyID     int    `json:"vacancy_id"`
+	From          string `json:"from"`
+	To            string `json:"to"`
+	StageID       *int   `json:"stage_id,omitempty"`
+}
+
+// ErrorPayload — ошибка обработки команды клиента
+type ErrorPayload struct {
+	Message string `json:"message"`
+}
+
+// Encode упаковывает событие в конверт текущей версии
+func Encode(eventType string, chatID int, requestID string, payload interface{}) ([]byte, error) {
+	envelope := Envelope{V: ProtocolVersion, Type: eventType, ChatID: chatID, RequestID: requestID}
+	if payload != nil {
+		raw, err := json.Marshal(payload)
+		if err != nil {
+			return nil, err
+		}
+		envelope.Payload = raw
+	}
+	return json.Marshal(envelope)
+}
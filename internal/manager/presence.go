@@ -0,0 +1,206 @@
+package manager
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log"
+	"sync"
+	"time"
+)
+
+// presenceTimeout ограничивает обращения к хранилищу присутствия
+const presenceTimeout = 2 * time.Second
+
+// PresenceStore учитывает открытые соединения пользователей на всех экземплярах.
+// Пользователь в сети, пока у него есть хотя бы одно соединение, продлённое не позже ttl назад.
+type PresenceStore interface {
+	// Connect регистрирует соединение; first — это первое живое соединение пользователя
+	Connect(ctx context.Context, userID int, connID string, ttl time.Duration) (first bool, err error)
+	// Refresh продлевает соединение, вызывается на каждый pong
+	Refresh(ctx context.Context, userID int, connID string, ttl time.Duration) error
+	// Disconnect снимает соединение; last — живых соединений у пользователя не осталось
+	Disconnect(ctx context.Context, userID int, connID string) (last bool, err error)
+	Online(ctx context.Context, userIDs []int) (map[int]bool, error)
+}
+
+// LastSeenRecorder сохраняет время, когда пользователь последний раз был в сети
+type LastSeenRecorder interface {
+	UpdateLastSeen(userID int, at time.Time) error
+}
+
+// MemoryPresence — учёт присутствия в пределах одного процесса
+type MemoryPresence struct {
+	mu    sync.Mutex
+	conns map[int]map[string]time.Time // пользователь → соединение → срок действия
+}
+
+func NewMemoryPresence() *MemoryPresence {
+	return &MemoryPresence{conns: make(map[int]map[string]time.Time)}
+}
+
+func (p *MemoryPresence) Connect(ctx context.Context, userID int, connID string, ttl time.Duration) (bool, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	conns := p.alive(userID)
+	if conns == nil {
+		conns = make(map[string]time.Time)
+		p.conns[userID] = conns
+	}
+	conns[connID] = time.Now().Add(ttl)
+	return len(conns) == 1, nil
+}
+
+func (p *MemoryPresence) Refresh(ctx context.Context, userID int, connID string, ttl time.Duration) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if conns, ok := p.conns[userID]; ok {
+		conns[connID] = time.Now().Add(ttl)
+	}
+	return nil
+}
+
+func (p *MemoryPresence) Disconnect(ctx context.Context, userID int, connID string) (bool, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	conns := p.alive(userID)
+	if conns == nil {
+		return true, nil
+	}
+	delete(conns, connID)
+	if len(conns) == 0 {
+		delete(p.conns, userID)
+		return true, nil
+	}
+	return false, nil
+}
+
+func (p *MemoryPresence) Online(ctx context.Context, userIDs []int) (map[int]bool, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	online := make(map[int]bool, len(userIDs))
+	for _, userID := range userIDs {
+		online[userID] = len(p.alive(userID)) > 0
+	}
+	return online, nil
+}
+
+// alive удаляет просроченные соединения пользователя и возвращает оставшиеся
+func (p *MemoryPresence) alive(userID int) map[string]time.Time {
+	conns, ok := p.conns[userID]
+	if !ok {
+		return nil
+	}
+	now := time.Now()
+	for connID, expiresAt := range conns {
+		if expiresAt.Before(now) {
+			delete(conns, connID)
+		}
+	}
+	return conns
+}
+
+// goOnline регистрирует соединение в хранилище присутствия; о первом соединении пользователя
+// узнают участники всех его чатов
+func (manager *WebSocketManager) goOnline(client *Client) {
+	ctx, cancel := context.WithTimeout(context.Background(), presenceTimeout)
+	defer cancel()
+
+	first, err := manager.presence.Connect(ctx, client.UserID, client.ID, manager.presenceTTL())
+	if err != nil {
+		log.Println("Не удалось отметить пользователя в сети:", client.UserID, err)
+		return
+	}
+	if first {
+		manager.announcePresence(client.UserID, PresencePayload{UserID: client.UserID, Online: true})
+	}
+}
+
+// goOffline снимает соединение, завершает начатый набор текста и, если это было последнее
+// соединение пользователя, сохраняет last_seen_at и рассылает offline
+func (manager *WebSocketManager) goOffline(client *Client) {
+	for _, chatID := range manager.stopTyping(client) {
+		manager.publishToChat(chatID, client.UserID, EventTyping, TypingPayload{UserID: client.UserID, IsTyping: false})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), presenceTimeout)
+	defer cancel()
+
+	last, err := manager.presence.Disconnect(ctx, client.UserID, client.ID)
+	if err != nil {
+		log.Println("Не удалось снять пользователя из сети:", client.UserID, err)
+		return
+	}
+	if !last {
+		return
+	}
+
+	seenAt := time.Now()
+	if err := manager.lastSeen.UpdateLastSeen(client.UserID, seenAt); err != nil {
+		log.Println("Не удалось сохранить last_seen_at:", client.UserID, err)
+	}
+	manager.announcePresence(client.UserID, PresencePayload{UserID: client.UserID, Online: false, LastSeenAt: &seenAt})
+}
+
+func (manager *WebSocketManager) refreshPresence(client *Client) {
+	ctx, cancel := context.WithTimeout(context.Background(), presenceTimeout)
+	defer cancel()
+
+	if err := manager.presence.Refresh(ctx, client.UserID, client.ID, manager.presenceTTL()); err != nil {
+		log.Println("Не удалось продлить присутствие пользователя:", client.UserID, err)
+	}
+}
+
+// presenceTTL — соединение без pong дольше двух интервалов ожидания считается мёртвым
+func (manager *WebSocketManager) presenceTTL() time.Duration {
+	return 2 * manager.settings.pongWait
+}
+
+// announcePresence рассылает событие присутствия во все чаты пользователя, кроме его собственных соединений
+func (manager *WebSocketManager) announcePresence(userID int, payload PresencePayload) {
+	chatIDs, err := manager.Chats.GetChatIDsByUserID(userID)
+	if err != nil {
+		log.Println("Не удалось получить чаты пользователя для рассылки присутствия:", userID, err)
+		return
+	}
+	for _, chatID := range chatIDs {
+		manager.publishToChat(chatID, userID, EventPresence, payload)
+	}
+}
+
+func (manager *WebSocketManager) setTyping(client *Client, chatID int, isTyping bool) {
+	manager.mu.Lock()
+	defer manager.mu.Unlock()
+
+	if isTyping {
+		client.typing[chatID] = struct{}{}
+	} else {
+		delete(client.typing, chatID)
+	}
+}
+
+// stopTyping сбрасывает признак набора и возвращает чаты, в которых клиент печатал
+func (manager *WebSocketManager) stopTyping(client *Client) []int {
+	manager.mu.Lock()
+	defer manager.mu.Unlock()
+
+	chatIDs := make([]int, 0, len(client.typing))
+	for chatID := range client.typing {
+		chatIDs = append(chatIDs, chatID)
+		delete(client.typing, chatID)
+	}
+	return chatIDs
+}
+
+// newInstanceID отличает соединения разных экземпляров приложения в общем хранилище присутствия
+func newInstanceID() string {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return time.Now().Format("20060102150405.000000000")
+	}
+	return hex.EncodeToString(buf)
+}
@@ -0,0 +1,58 @@
+package manager
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/redis/go-redis/v9"
+	"log"
+)
+
+// RedisBus рассылает события между экземплярами через Redis pub/sub.
+// Каждый экземпляр подписан на один канал и сам доставляет событие своим соединениям.
+type RedisBus struct {
+	client  *redis.Client
+	channel string
+}
+
+func NewRedisBus(client *redis.Client, channel string) *RedisBus {
+	return &RedisBus{client: client, channel: channel}
+}
+
+func (b *RedisBus) Publish(ctx context.Context, event BusEvent) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	return b.client.Publish(ctx, b.channel, payload).Err()
+}
+
+// Subscribe дожидается подтверждения подписки и читает канал в отдельной горутине до отмены ctx
+func (b *RedisBus) Subscribe(ctx context.Context, handler func(BusEvent)) error {
+	pubsub := b.client.Subscribe(ctx, b.channel)
+	if _, err := pubsub.Receive(ctx); err != nil {
+		pubsub.Close()
+		return err
+	}
+
+	go func() {
+		defer pubsub.Close()
+		messages := pubsub.Channel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case msg, ok := <-messages:
+				if !ok {
+					return
+				}
+				var event BusEvent
+				if err := json.Unmarshal([]byte(msg.Payload), &event); err != nil {
+					log.Println("Некорректное событие в шине WebSocket:", err)
+					continue
+				}
+				handler(event)
+			}
+		}
+	}()
+	return nil
+}
@@ -0,0 +1,91 @@
+package manager
+
+import (
+	"context"
+	"fmt"
+	"github.com/redis/go-redis/v9"
+	"strconv"
+	"time"
+)
+
+// RedisPresence хранит соединения пользователя в sorted set presence:user:<id>,
+// где score — unix-время истечения. Соединения упавшего экземпляра истекают сами.
+type RedisPresence struct {
+	client *redis.Client
+}
+
+func NewRedisPresence(client *redis.Client) *RedisPresence {
+	return &RedisPresence{client: client}
+}
+
+func presenceKey(userID int) string {
+	return fmt.Sprintf("presence:user:%d", userID)
+}
+
+func (p *RedisPresence) Connect(ctx context.Context, userID int, connID string, ttl time.Duration) (bool, error) {
+	key := presenceKey(userID)
+	now := time.Now()
+
+	var alive *redis.IntCmd
+	_, err := p.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.ZRemRangeByScore(ctx, key, "-inf", strconv.FormatInt(now.Unix(), 10))
+		pipe.ZAdd(ctx, key, redis.Z{Score: float64(now.Add(ttl).Unix()), Member: connID})
+		alive = pipe.ZCard(ctx, key)
+		pipe.Expire(ctx, key, ttl)
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+	return alive.Val() == 1, nil
+}
+
+func (p *RedisPresence) Refresh(ctx context.Context, userID int, connID string, ttl time.Duration) error {
+	key := presenceKey(userID)
+	_, err := p.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.ZAdd(ctx, key, redis.Z{Score: float64(time.Now().Add(ttl).Unix()), Member: connID})
+		pipe.Expire(ctx, key, ttl)
+		return nil
+	})
+	return err
+}
+
+func (p *RedisPresence) Disconnect(ctx context.Context, userID int, connID string) (bool, error) {
+	key := presenceKey(userID)
+
+	var alive *redis.IntCmd
+	_, err := p.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.ZRem(ctx, key, connID)
+		pipe.ZRemRangeByScore(ctx, key, "-inf", strconv.FormatInt(time.Now().Unix(), 10))
+		alive = pipe.ZCard(ctx, key)
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+	return alive.Val() == 0, nil
+}
+
+func (p *RedisPresence) Online(ctx context.Context, userIDs []int) (map[int]bool, error) {
+	online := make(map[int]bool, len(userIDs))
+	if len(userIDs) == 0 {
+		return online, nil
+	}
+
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+	counts := make(map[int]*redis.IntCmd, len(userIDs))
+	_, err := p.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, userID := range userIDs {
+			counts[userID] = pipe.ZCount(ctx, presenceKey(userID), "("+now, "+inf")
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for userID, count := range counts {
+		online[userID] = count.Val() > 0
+	}
+	return online, nil
+}
@@ -1,46 +1,93 @@
 package manager
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
 	"github.com/gorilla/websocket"
+	"jumyste-app-backend/config"
+	"jumyste-app-backend/internal/entity"
 	"log"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
-// Client представляет соединение WebSocket с пользователем
-type Client struct {
-	Conn   *websocket.Conn
-	Send   chan []byte
-	UserID int
-	ChatID int
+// busPublishTimeout ограничивает ожидание шины при публикации события
+const busPublishTimeout = 2 * time.Second
+
+// Политики для клиента, очередь отправки которого переполнена
+const (
+	SlowClientDisconnect = "disconnect" // закрыть соединение, клиент переподключится и догрузит историю
+	SlowClientDrop       = "drop"       // пропустить событие для этого клиента
+)
+
+// ChatDirectory — участие пользователей в чатах: для команды subscribe и рассылки присутствия
+type ChatDirectory interface {
+	IsChatMember(chatID, userID int) (bool, error)
+	GetChatIDsByUserID(userID int) ([]int, error)
+}
+
+// ReadMarker сохраняет отметку о прочтении, присланную по WebSocket
+type ReadMarker interface {
+	GetMessageByID(messageID int) (*entity.Message, error)
+	MarkMessageAsRead(ctx context.Context, messageID, userID int) error
 }
 
-// WebSocketManager управляет всеми соединениями
+// WebSocketManager управляет соединениями этого экземпляра. Индексы пользователь → клиенты и
+// чат → клиенты позволяют доставлять событие только тем, кому оно адресовано. События публикуются
+// в шину, и каждый экземпляр, получив их, доставляет своим соединениям.
 type WebSocketManager struct {
-	Clients    map[*Client]bool
-	Broadcast  chan []byte
-	MarkAsRead chan ReadMessage
+	clients    map[*Client]struct{}
+	users      map[int]map[*Client]struct{}
+	chats      map[int]map[*Client]struct{}
 	Register   chan *Client
 	Unregister chan *Client
-	mu         sync.Mutex
+	Chats      ChatDirectory
+	Reads      ReadMarker
+	bus        Bus
+	presence   PresenceStore
+	lastSeen   LastSeenRecorder
+	instanceID string
+	nextConnID uint64
+	settings   clientSettings
+	dropSlow   bool
+	closing    bool
+	writers    sync.WaitGroup
+	mu         sync.RWMutex
 }
 
-// ReadMessage структура для события "прочтено"
-type ReadMessage struct {
-	MessageID int `json:"message_id"`
-	UserID    int `json:"user_id"`
-	ChatID    int `json:"chat_id"`
-}
-
-// NewWebSocketManager инициализирует менеджер WebSocket
-func NewWebSocketManager() *WebSocketManager {
-	return &WebSocketManager{
-		Clients:    make(map[*Client]bool),
-		Broadcast:  make(chan []byte),
-		MarkAsRead: make(chan ReadMessage),
+// NewWebSocketManager инициализирует менеджер WebSocket и подписывает его на шину. Если подписаться
+// не удалось, менеджер работает с шиной в памяти: события получат только соединения этого экземпляра.
+func NewWebSocketManager(chats ChatDirectory,
+	reads ReadMarker,
+	bus Bus,
+	presence PresenceStore,
+	lastSeen LastSeenRecorder,
+	cfg config.WebSocketConfig,
+) *WebSocketManager {
+	manager := &WebSocketManager{
+		clients:    make(map[*Client]struct{}),
+		users:      make(map[int]map[*Client]struct{}),
+		chats:      make(map[int]map[*Client]struct{}),
 		Register:   make(chan *Client),
 		Unregister: make(chan *Client),
+		Chats:      chats,
+		Reads:      reads,
+		bus:        bus,
+		presence:   presence,
+		lastSeen:   lastSeen,
+		instanceID: newInstanceID(),
+		settings:   newClientSettings(cfg),
+		dropSlow:   cfg.SlowClientPolicy == SlowClientDrop,
+	}
+
+	if err := bus.Subscribe(context.Background(), manager.dispatch); err != nil {
+		log.Println("Не удалось подписаться на шину WebSocket, события доставляются только локально:", err)
+		manager.bus = NewMemoryBus()
+		manager.bus.Subscribe(context.Background(), manager.dispatch)
 	}
+	return manager
 }
 
 // Run запускает WebSocket менеджер
@@ -48,90 +95,445 @@ func (manager *WebSocketManager) Run() {
 	for {
 		select {
 		case client := <-manager.Register:
-			manager.mu.Lock()
-			manager.Clients[client] = true
-			manager.mu.Unlock()
+			manager.add(client)
 
 		case client := <-manager.Unregister:
-			manager.mu.Lock()
-			if _, ok := manager.Clients[client]; ok {
-				delete(manager.Clients, client)
-				close(client.Send)
-			}
-			manager.mu.Unlock()
-
-		case message := <-manager.Broadcast:
-			manager.mu.Lock()
-			for client := range manager.Clients {
-				select {
-				case client.Send <- message:
-				default:
-					close(client.Send)
-					delete(manager.Clients, client)
-				}
-			}
-			manager.mu.Unlock()
-
-		case readMsg := <-manager.MarkAsRead:
-			manager.mu.Lock()
-			// Рассылаем событие "прочитано" всем клиентам в этом чате
-			readEvent, _ := json.Marshal(map[string]interface{}{
-				"type":       "message_read",
-				"message_id": readMsg.MessageID,
-				"user_id":    readMsg.UserID,
-				"chat_id":    readMsg.ChatID,
-			})
-
-			for client := range manager.Clients {
-				if client.ChatID == readMsg.ChatID {
-					client.Send <- readEvent
-				}
-			}
-			manager.mu.Unlock()
+			manager.remove(client)
 		}
 	}
 }
 
-// HandleClient обрабатывает сообщения от клиента
+// PublishToChat доставляет событие всем подписчикам чата
+func (manager *WebSocketManager) PublishToChat(chatID int, eventType string, payload interface{}) {
+	manager.publishToChat(chatID, 0, eventType, payload)
+}
+
+// PublishToUser доставляет событие во все соединения пользователя
+func (manager *WebSocketManager) PublishToUser(userID int, eventType string, payload interface{}) {
+	if data, ok := encode(eventType, 0, "", payload); ok {
+		manager.publish(BusEvent{Target: TargetUser, ID: userID, Data: data})
+	}
+}
+
+// PublishToRole доставляет событие всем подключённым пользователям роли
+func (manager *WebSocketManager) PublishToRole(roleID int, eventType string, payload interface{}) {
+	if data, ok := encode(eventType, 0, "", payload); ok {
+		manager.publish(BusEvent{Target: TargetRole, ID: roleID, Data: data})
+	}
+}
+
+// JoinChat подписывает уже открытые соединения пользователей на новый чат на всех экземплярах
+func (manager *WebSocketManager) JoinChat(chatID int, userIDs ...int) {
+	manager.publish(BusEvent{Target: TargetJoin, ID: chatID, UserIDs: userIDs})
+}
+
+// LeaveChat отписывает соединения пользователей от чата на всех экземплярах, например после исключения из группы
+func (manager *WebSocketManager) LeaveChat(chatID int, userIDs ...int) {
+	manager.publish(BusEvent{Target: TargetLeave, ID: chatID, UserIDs: userIDs})
+}
+
+// NewClient создаёт клиента с очередью отправки и таймаутами из настроек менеджера
+// и начальным списком подписок
+func (manager *WebSocketManager) NewClient(conn *websocket.Conn, userID, roleID int, chatIDs []int) *Client {
+	chats := make(map[int]struct{}, len(chatIDs))
+	for _, chatID := range chatIDs {
+		chats[chatID] = struct{}{}
+	}
+
+	client := &Client{
+		ID:        fmt.Sprintf("%s:%d", manager.instanceID, atomic.AddUint64(&manager.nextConnID, 1)),
+		Conn:      conn,
+		Send:      make(chan []byte, manager.settings.sendBufferSize),
+		UserID:    userID,
+		RoleID:    roleID,
+		chats:     chats,
+		typing:    make(map[int]struct{}),
+		settings:  manager.settings,
+		closeCode: websocket.CloseNormalClosure,
+		done:      func() {},
+	}
+
+	// После Shutdown писателей не ждём: такой клиент будет закрыт сразу при регистрации
+	manager.mu.Lock()
+	if !manager.closing {
+		manager.writers.Add(1)
+		client.done = manager.writers.Done
+	}
+	manager.mu.Unlock()
+	return client
+}
+
+// Shutdown закрывает все соединения с кодом 1001 (going away) и ждёт, пока клиентам
+// уйдут кадры закрытия, но не дольше ctx. Новые соединения после этого сразу закрываются.
+func (manager *WebSocketManager) Shutdown(ctx context.Context) error {
+	manager.mu.Lock()
+	manager.closing = true
+	clients := collect(manager.clients, nil)
+	manager.mu.Unlock()
+
+	for _, client := range clients {
+		manager.disconnect(client, websocket.CloseGoingAway, "server shutdown")
+	}
+
+	done := make(chan struct{})
+	go func() {
+		manager.writers.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// ChatClientCount возвращает число соединений, подписанных на чат
+func (manager *WebSocketManager) ChatClientCount(chatID int) int {
+	manager.mu.RLock()
+	defer manager.mu.RUnlock()
+	return len(manager.chats[chatID])
+}
+
+// publishToChat рассылает событие подписчикам чата, пропуская соединения exceptUserID
+func (manager *WebSocketManager) publishToChat(chatID, exceptUserID int, eventType string, payload interface{}) {
+	if data, ok := encode(eventType, chatID, "", payload); ok {
+		manager.publish(BusEvent{Target: TargetChat, ID: chatID, ExceptUserID: exceptUserID, Data: data})
+	}
+}
+
+// publish отправляет событие в шину; если шина недоступна, событие получат хотя бы локальные соединения
+func (manager *WebSocketManager) publish(event BusEvent) {
+	ctx, cancel := context.WithTimeout(context.Background(), busPublishTimeout)
+	defer cancel()
+
+	if err := manager.bus.Publish(ctx, event); err != nil {
+		log.Println("Не удалось опубликовать событие в шину WebSocket, доставляем локально:", err)
+		manager.dispatch(event)
+	}
+}
+
+// dispatch доставляет событие из шины соединениям этого экземпляра
+func (manager *WebSocketManager) dispatch(event BusEvent) {
+	switch event.Target {
+	case TargetJoin:
+		manager.joinLocal(event.ID, event.UserIDs)
+		return
+	case TargetLeave:
+		manager.leaveLocal(event.ID, event.UserIDs)
+		return
+	}
+
+	manager.mu.RLock()
+	var recipients []*Client
+	switch event.Target {
+	case TargetChat:
+		recipients = collect(manager.chats[event.ID], func(client *Client) bool { return client.UserID != event.ExceptUserID })
+	case TargetUser:
+		recipients = collect(manager.users[event.ID], nil)
+	case TargetRole:
+		recipients = collect(manager.clients, func(client *Client) bool { return client.RoleID == event.ID })
+	}
+	manager.mu.RUnlock()
+	manager.deliver(recipients, event.Data)
+}
+
+func (manager *WebSocketManager) joinLocal(chatID int, userIDs []int) {
+	manager.mu.Lock()
+	defer manager.mu.Unlock()
+
+	for _, userID := range userIDs {
+		for client := range manager.users[userID] {
+			manager.subscribeLocked(client, chatID)
+		}
+	}
+}
+
+func (manager *WebSocketManager) leaveLocal(chatID int, userIDs []int) {
+	manager.mu.Lock()
+	defer manager.mu.Unlock()
+
+	for _, userID := range userIDs {
+		for client := range manager.users[userID] {
+			delete(client.chats, chatID)
+			manager.unindex(client, chatID)
+		}
+	}
+}
+
+func (manager *WebSocketManager) add(client *Client) {
+	manager.mu.Lock()
+	if manager.closing {
+		client.closeCode, client.closeText = websocket.CloseGoingAway, "server shutdown"
+		close(client.Send)
+		manager.mu.Unlock()
+		return
+	}
+	manager.clients[client] = struct{}{}
+	connections, ok := manager.users[client.UserID]
+	if !ok {
+		connections = make(map[*Client]struct{})
+		manager.users[client.UserID] = connections
+	}
+	connections[client] = struct{}{}
+	for chatID := range client.chats {
+		manager.index(client, chatID)
+	}
+	manager.mu.Unlock()
+}
+
+// remove отключает клиента; повторный вызов для уже удалённого клиента ничего не делает
+func (manager *WebSocketManager) remove(client *Client) {
+	manager.disconnect(client, websocket.CloseNormalClosure, "")
+}
+
+// disconnect снимает клиента с учёта и закрывает очередь отправки; запись кадра закрытия
+// с кодом code остаётся за WriteMessages
+func (manager *WebSocketManager) disconnect(client *Client, code int, reason string) {
+	manager.mu.Lock()
+	if _, ok := manager.clients[client]; !ok {
+		manager.mu.Unlock()
+		return
+	}
+	delete(manager.clients, client)
+	for chatID := range client.chats {
+		manager.unindex(client, chatID)
+	}
+	if connections, ok := manager.users[client.UserID]; ok {
+		delete(connections, client)
+		if len(connections) == 0 {
+			delete(manager.users, client.UserID)
+		}
+	}
+	client.closeCode, client.closeText = code, reason
+	close(client.Send)
+	manager.mu.Unlock()
+}
+
+func (manager *WebSocketManager) subscribe(client *Client, chatID int) {
+	manager.mu.Lock()
+	defer manager.mu.Unlock()
+	manager.subscribeLocked(client, chatID)
+}
+
+func (manager *WebSocketManager) subscribeLocked(client *Client, chatID int) {
+	if _, ok := manager.clients[client]; !ok {
+		return
+	}
+	client.chats[chatID] = struct{}{}
+	manager.index(client, chatID)
+}
+
+func (manager *WebSocketManager) unsubscribe(client *Client, chatID int) {
+	manager.mu.Lock()
+	defer manager.mu.Unlock()
+
+	delete(client.chats, chatID)
+	manager.unindex(client, chatID)
+}
+
+func (manager *WebSocketManager) isSubscribed(client *Client, chatID int) bool {
+	manager.mu.RLock()
+	defer manager.mu.RUnlock()
+	_, ok := client.chats[chatID]
+	return ok
+}
+
+func (manager *WebSocketManager) index(client *Client, chatID int) {
+	members, ok := manager.chats[chatID]
+	if !ok {
+		members = make(map[*Client]struct{})
+		manager.chats[chatID] = members
+	}
+	members[client] = struct{}{}
+}
+
+func (manager *WebSocketManager) unindex(client *Client, chatID int) {
+	if members, ok := manager.chats[chatID]; ok {
+		delete(members, client)
+		if len(members) == 0 {
+			delete(manager.chats, chatID)
+		}
+	}
+}
+
+// deliver отправляет данные без блокировки. Клиенту с переполненной очередью событие
+// не доставляется; по политике disconnect его соединение ещё и закрывается.
+// Список получателей должен быть скопирован под RLock заранее.
+func (manager *WebSocketManager) deliver(recipients []*Client, data []byte) {
+	var slow []*Client
+	for _, client := range recipients {
+		if !manager.trySend(client, data) {
+			slow = append(slow, client)
+		}
+	}
+	for _, client := range slow {
+		if manager.dropSlow {
+			log.Println("Очередь клиента переполнена, событие пропущено:", client.UserID)
+			continue
+		}
+		log.Println("Клиент не успевает читать сообщения, соединение закрыто:", client.UserID)
+		manager.disconnect(client, websocket.ClosePolicyViolation, "send queue overflow")
+	}
+}
+
+// trySend кладёт данные в очередь клиента. Канал Send закрывается в remove под mu,
+// поэтому проверка регистрации и отправка выполняются под RLock.
+func (manager *WebSocketManager) trySend(client *Client, data []byte) bool {
+	manager.mu.RLock()
+	defer manager.mu.RUnlock()
+
+	if _, ok := manager.clients[client]; !ok {
+		return true
+	}
+	select {
+	case client.Send <- data:
+		return true
+	default:
+		return false
+	}
+}
+
+func (manager *WebSocketManager) reply(client *Client, eventType string, chatID int, requestID string, payload interface{}) {
+	data, err := Encode(eventType, chatID, requestID, payload)
+	if err != nil {
+		log.Println("Ошибка кодирования события:", err)
+		return
+	}
+	manager.deliver([]*Client{client}, data)
+}
+
+func (manager *WebSocketManager) replyError(client *Client, env Envelope, message string) {
+	manager.reply(client, EventError, env.ChatID, env.RequestID, ErrorPayload{Message: message})
+}
+
+// HandleClient читает команды клиента до закрытия соединения. Соединение считается
+// мёртвым, если за pongWait не пришло ни одного кадра, включая pong на наш ping.
 func (manager *WebSocketManager) HandleClient(client *Client) {
+	manager.goOnline(client)
 	defer func() {
 		manager.Unregister <- client
 		client.Conn.Close()
+		manager.goOffline(client)
 	}()
 
+	client.Conn.SetReadLimit(client.settings.maxMessageBytes)
+	client.extendReadDeadline()
+	client.Conn.SetPongHandler(func(string) error {
+		client.extendReadDeadline()
+		manager.refreshPresence(client)
+		return nil
+	})
+
 	for {
 		_, message, err := client.Conn.ReadMessage()
 		if err != nil {
-			log.Println("Ошибка чтения сообщения:", err)
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseNormalClosure) {
+				log.Println("Ошибка чтения сообщения:", err)
+			}
 			break
 		}
+		client.extendReadDeadline()
 
-		// Попробуем распарсить сообщение как "прочитано"
-		var readMsg ReadMessage
-		if err := json.Unmarshal(message, &readMsg); err == nil && readMsg.MessageID > 0 {
-			readMsg.UserID = client.UserID
-			readMsg.ChatID = client.ChatID
-			manager.MarkAsRead <- readMsg
+		var env Envelope
+		if err := json.Unmarshal(message, &env); err != nil || env.Type == "" {
+			manager.replyError(client, env, "invalid envelope")
+			continue
+		}
+		if env.V != 0 && env.V != ProtocolVersion {
+			manager.replyError(client, env, "unsupported protocol version")
 			continue
 		}
+		manager.handleCommand(client, env)
+	}
+}
+
+func (manager *WebSocketManager) handleCommand(client *Client, env Envelope) {
+	switch env.Type {
+	case CommandSubscribe:
+		if env.ChatID <= 0 {
+			manager.replyError(client, env, "chat_id is required")
+			return
+		}
+		isMember, err := manager.Chats.IsChatMember(env.ChatID, client.UserID)
+		if err != nil {
+			manager.replyError(client, env, "failed to check chat membership")
+			return
+		}
+		if !isMember {
+			manager.replyError(client, env, "not a chat member")
+			return
+		}
+		manager.subscribe(client, env.ChatID)
+		manager.reply(client, EventSubscribed, env.ChatID, env.RequestID, nil)
+
+	case CommandUnsubscribe:
+		manager.unsubscribe(client, env.ChatID)
+		manager.reply(client, EventUnsubscribed, env.ChatID, env.RequestID, nil)
 
-		// Если не "прочитано", то отправляем в чат
-		manager.mu.Lock()
-		for c := range manager.Clients {
-			if c.ChatID == client.ChatID {
-				c.Send <- message
+	case EventTyping:
+		if !manager.isSubscribed(client, env.ChatID) {
+			manager.replyError(client, env, "not subscribed to chat")
+			return
+		}
+		var typing TypingPayload
+		if len(env.Payload) > 0 {
+			if err := json.Unmarshal(env.Payload, &typing); err != nil {
+				manager.replyError(client, env, "invalid payload")
+				return
 			}
 		}
-		manager.mu.Unlock()
+		typing.UserID = client.UserID
+		manager.setTyping(client, env.ChatID, typing.IsTyping)
+		manager.publishToChat(env.ChatID, client.UserID, EventTyping, typing)
+
+	case EventMessageRead:
+		var read ReadPayload
+		if err := json.Unmarshal(env.Payload, &read); err != nil || read.MessageID <= 0 {
+			manager.replyError(client, env, "message_id is required")
+			return
+		}
+		manager.markRead(client, env, read.MessageID)
+
+	default:
+		manager.replyError(client, env, "unknown command")
 	}
 }
 
-// WriteMessages отправляет сообщения клиенту
-func (client *Client) WriteMessages() {
-	defer client.Conn.Close()
-	for message := range client.Send {
-		if err := client.Conn.WriteMessage(websocket.TextMessage, message); err != nil {
-			break
+// markRead сохраняет прочтение и рассылает его участникам чата сообщения
+func (manager *WebSocketManager) markRead(client *Client, env Envelope, messageID int) {
+	message, err := manager.Reads.GetMessageByID(messageID)
+	if err != nil || message == nil {
+		manager.replyError(client, env, "message not found")
+		return
+	}
+	isMember, err := manager.Chats.IsChatMember(message.ChatID, client.UserID)
+	if err != nil || !isMember {
+		manager.replyError(client, env, "not a chat member")
+		return
+	}
+	if err := manager.Reads.MarkMessageAsRead(context.Background(), messageID, client.UserID); err != nil {
+		manager.replyError(client, env, "failed to mark message as read")
+		return
+	}
+	manager.PublishToChat(message.ChatID, EventMessageRead, ReadPayload{MessageID: messageID, UserID: client.UserID})
+}
+
+func encode(eventType string, chatID int, requestID string, payload interface{}) ([]byte, bool) {
+	data, err := Encode(eventType, chatID, requestID, payload)
+	if err != nil {
+		log.Println("Ошибка кодирования события:", eventType, err)
+		return nil, false
+	}
+	return data, true
+}
+
+func collect(set map[*Client]struct{}, keep func(*Client) bool) []*Client {
+	recipients := make([]*Client, 0, len(set))
+	for client := range set {
+		if keep == nil || keep(client) {
+			recipients = append(recipients, client)
 		}
 	}
+	return recipients
 }
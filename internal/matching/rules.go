@@ -0,0 +1,175 @@
+package matching
+
+import (
+	"jumyste-app-backend/internal/entity"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Веса частных оценок в итоговом rule_score
+const (
+	skillsWeight     = 50
+	experienceWeight = 25
+	locationWeight   = 25
+)
+
+// Result — итог правил: общий балл и объяснение, из чего он сложился
+type Result struct {
+	Score     int
+	Breakdown entity.RuleScoreBreakdown
+}
+
+// Score детерминированно сравнивает резюме с вакансией по навыкам, опыту, формату работы и локации.
+// Не зависит от AI и при одинаковых входных данных всегда даёт одинаковый результат (с точностью до текущей даты для стажа).
+func Score(resume *entity.Resume, vacancy *entity.Vacancy, now time.Time) Result {
+	matched, missing := matchSkills(resume, vacancy.Skills)
+	skillScore := 100
+	if total := len(matched) + len(missing); total > 0 {
+		skillScore = len(matched) * 100 / total
+	}
+
+	months := ExperienceMonths(resume.Experiences, now)
+	requiredYears := RequiredExperienceYears(vacancy.Experience)
+	experienceScore := 100
+	if requiredYears > 0 && months < requiredYears*12 {
+		experienceScore = months * 100 / (requiredYears * 12)
+	}
+
+	locationScore := scoreLocation(resume.City, vacancy)
+
+	return Result{
+		Score: (skillScore*skillsWeight + experienceScore*experienceWeight + locationScore*locationWeight) / 100,
+		Breakdown: entity.RuleScoreBreakdown{
+			Skills:           skillScore,
+			Experience:       experienceScore,
+			Location:         locationScore,
+			MatchedSkills:    matched,
+			MissingSkills:    missing,
+			ExperienceMonths: months,
+		},
+	}
+}
+
+// matchSkills ищет навыки вакансии в списке навыков резюме, а затем в описании опыта работы;
+// неоднозначные написания (см. freeTextSpellings) засчитываются только из списка навыков
+func matchSkills(resume *entity.Resume, vacancySkills []string) (matched, missing []string) {
+	have := make(map[string]bool)
+	for _, skill := range NormalizeSkills(resume.Skills) {
+		have[skill] = true
+	}
+
+	var experienceText strings.Builder
+	for _, exp := range resume.Experiences {
+		experienceText.WriteString(strings.ToLower(exp.Position + " " + exp.Description + " "))
+	}
+
+	matched, missing = []string{}, []string{}
+	for _, skill := range NormalizeSkills(vacancySkills) {
+		if have[skill] || mentions(experienceText.String(), freeTextSpellings(skill)) {
+			matched = append(matched, skill)
+		} else {
+			missing = append(missing, skill)
+		}
+	}
+	return matched, missing
+}
+
+// mentions проверяет вхождение любого из терминов как отдельного слова
+func mentions(text string, terms []string) bool {
+	for _, term := range terms {
+		for offset := 0; ; {
+			idx := strings.Index(text[offset:], term)
+			if idx < 0 {
+				break
+			}
+			start, end := offset+idx, offset+idx+len(term)
+			if isBoundary(text, start-1) && isBoundary(text, end) {
+				return true
+			}
+			offset = start + 1
+		}
+	}
+	return false
+}
+
+func isBoundary(text string, pos int) bool {
+	if pos < 0 || pos >= len(text) {
+		return true
+	}
+	r := rune(text[pos])
+	return r < unicode.MaxASCII && !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '+' && r != '#'
+}
+
+var yearsPattern = regexp.MustCompile(`\d+`)
+
+// RequiredExperienceYears извлекает минимальный стаж из строки вакансии: "Без опыта" — 0, "1-3 года" — 1, "Более 6 лет" — 6
+func RequiredExperienceYears(experience string) int {
+	lower := strings.ToLower(experience)
+	if strings.Contains(lower, "без опыта") || strings.Contains(lower, "no experience") {
+		return 0
+	}
+	match := yearsPattern.FindString(lower)
+	if match == "" {
+		return 0
+	}
+	years, _ := strconv.Atoi(match)
+	return years
+}
+
+var dateLayouts = []string{"2006-01-02", "2006-01", "01.2006", "02.01.2006", "2006"}
+
+// ExperienceMonths суммирует стаж по всем местам работы; пустая или "по настоящее время" дата окончания — текущий момент
+func ExperienceMonths(experiences []entity.WorkExperience, now time.Time) int {
+	total := 0
+	for _, exp := range experiences {
+		start, ok := parseDate(exp.StartDate)
+		if !ok {
+			continue
+		}
+		end, ok := parseDate(exp.EndDate)
+		if !ok {
+			end = now
+		}
+		if months := (end.Year()-start.Year())*12 + int(end.Month()-start.Month()); months > 0 {
+			total += months
+		}
+	}
+	return total
+}
+
+func parseDate(value string) (time.Time, bool) {
+	value = strings.TrimSpace(value)
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// scoreLocation: удалёнка подходит всем, иначе город кандидата сравнивается с локацией вакансии
+func scoreLocation(city string, vacancy *entity.Vacancy) int {
+	format := strings.ToLower(vacancy.WorkFormat)
+	if strings.Contains(format, "удал") || strings.Contains(format, "remote") {
+		return 100
+	}
+	if vacancy.Location == nil || strings.TrimSpace(*vacancy.Location) == "" {
+		return 100
+	}
+
+	city = strings.ToLower(strings.TrimSpace(city))
+	if city == "" {
+		return 50
+	}
+	location := strings.ToLower(*vacancy.Location)
+	if strings.Contains(location, city) || strings.Contains(city, location) {
+		return 100
+	}
+	if strings.Contains(format, "гибрид") || strings.Contains(format, "hybrid") {
+		return 30
+	}
+	return 0
+}
@@ -0,0 +1,111 @@
+package matching
+
+import (
+	"strings"
+	"unicode"
+)
+
+// skillSynonyms сопоставляет варианты написания навыка с каноническим именем
+var skillSynonyms = map[string][]string{
+	"javascript": {"js", "java script", "ecmascript", "es6"},
+	"typescript": {"ts"},
+	"postgresql": {"postgres", "psql", "pg", "postgre sql"},
+	"go":         {"golang"},
+	"python":     {"py", "python3"},
+	"node.js":    {"node", "nodejs", "node js"},
+	"react":      {"reactjs", "react.js"},
+	"vue":        {"vuejs", "vue.js"},
+	"angular":    {"angularjs", "angular.js"},
+	"kubernetes": {"k8s"},
+	"mongodb":    {"mongo"},
+	"mssql":      {"ms sql", "sql server", "microsoft sql server"},
+	"c#":         {"csharp", "c sharp"},
+	"c++":        {"cpp"},
+	"html":       {"html5"},
+	"css":        {"css3"},
+	"1c":         {"1с", "1с:предприятие", "1c:enterprise"},
+	"rest api":   {"rest", "restful", "restful api"},
+	"aws":        {"amazon web services"},
+	"gcp":        {"google cloud", "google cloud platform"},
+	"ci/cd":      {"cicd", "ci cd"},
+}
+
+// commonWordSpellings — написания навыков, совпадающие с обычными словами ("rest of the team");
+// в свободном тексте опыта работы они не ищутся, только в списке навыков
+var commonWordSpellings = map[string]bool{
+	"rest":  true,
+	"node":  true,
+	"react": true,
+}
+
+var aliasToCanonical = buildAliasIndex()
+
+func buildAliasIndex() map[string]string {
+	index := make(map[string]string)
+	for canonical, aliases := range skillSynonyms {
+		index[canonical] = canonical
+		for _, alias := range aliases {
+			index[alias] = canonical
+		}
+	}
+	return index
+}
+
+// NormalizeSkill приводит навык к каноническому виду: "JS" и "JavaScript" дают "javascript"
+func NormalizeSkill(skill string) string {
+	key := strings.Join(strings.Fields(strings.ToLower(skill)), " ")
+	if canonical, ok := aliasToCanonical[key]; ok {
+		return canonical
+	}
+	return key
+}
+
+// NormalizeSkills нормализует список навыков и убирает дубликаты
+func NormalizeSkills(skills []string) []string {
+	seen := make(map[string]bool, len(skills))
+	result := make([]string, 0, len(skills))
+	for _, skill := range skills {
+		normalized := NormalizeSkill(skill)
+		if normalized == "" || seen[normalized] {
+			continue
+		}
+		seen[normalized] = true
+		result = append(result, normalized)
+	}
+	return result
+}
+
+// ExpandSkills возвращает все известные написания навыков в нижнем регистре — для поиска по сырым данным в БД
+func ExpandSkills(skills []string) []string {
+	var result []string
+	for _, canonical := range NormalizeSkills(skills) {
+		result = append(result, spellings(canonical)...)
+	}
+	return result
+}
+
+func spellings(canonical string) []string {
+	return append([]string{canonical}, skillSynonyms[canonical]...)
+}
+
+// freeTextSpellings — написания навыка, по которым его можно искать в свободном тексте: без общеупотребительных
+// слов и коротких буквенных сокращений вроде "go", "ts", "pg", которые дают ложные совпадения ("go to market")
+func freeTextSpellings(canonical string) []string {
+	var result []string
+	for _, term := range spellings(canonical) {
+		if commonWordSpellings[term] || (len([]rune(term)) <= 2 && isAllLetters(term)) {
+			continue
+		}
+		result = append(result, term)
+	}
+	return result
+}
+
+func isAllLetters(term string) bool {
+	for _, r := range term {
+		if !unicode.IsLetter(r) {
+			return false
+		}
+	}
+	return true
+}
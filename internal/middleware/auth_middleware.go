@@ -1,9 +1,10 @@
 package middleware
 
 import (
+	"context"
 	"errors"
 	"github.com/golang-jwt/jwt/v5"
-	"jumyste-app-backend/config"
+	"jumyste-app-backend/internal/repository"
 	"jumyste-app-backend/pkg/logger"
 	"jumyste-app-backend/utils"
 	"log/slog"
@@ -15,15 +16,26 @@ import (
 )
 
 type AuthMiddleware struct {
-	secretKey string
+	revocations repository.TokenRevocationStore
 }
 
-func NewAuthMiddleware(cfg config.Config) *AuthMiddleware {
-	return &AuthMiddleware{secretKey: cfg.JWT.Secret}
+// NewAuthMiddleware проверяет токены ключами, заданными utils.InitJWT
+func NewAuthMiddleware() *AuthMiddleware {
+	return &AuthMiddleware{}
 }
 
 var ErrInvalidToken = errors.New("invalid token")
 var ErrExpiredToken = errors.New("token is expired")
+var ErrRevokedToken = errors.New("token has been revoked")
+var ErrRevocationUnavailable = errors.New("token revocation check is unavailable")
+
+// revocationCheckTimeout ограничивает проверку отзыва токена, чтобы медленный Redis не подвешивал запросы
+const revocationCheckTimeout = 2 * time.Second
+
+// SetTokenRevocations подключает хранилище отозванных токенов; до этого проверяются только подпись и срок
+func (m *AuthMiddleware) SetTokenRevocations(store repository.TokenRevocationStore) {
+	m.revocations = store
+}
 
 func (m *AuthMiddleware) VerifyTokenMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -37,6 +49,16 @@ func (m *AuthMiddleware) VerifyTokenMiddleware() gin.HandlerFunc {
 		tokenString := strings.TrimPrefix(authHeader, "Bearer ")
 
 		claims, err := m.validateToken(tokenString)
+		if errors.Is(err, ErrRevocationUnavailable) {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Authentication is temporarily unavailable"})
+			c.Abort()
+			return
+		}
+		if errors.Is(err, ErrRevokedToken) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Token has been revoked"})
+			c.Abort()
+			return
+		}
 		if err != nil {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
 			c.Abort()
@@ -49,16 +71,14 @@ func (m *AuthMiddleware) VerifyTokenMiddleware() gin.HandlerFunc {
 		c.Set("role_id", claims.RoleID)
 		c.Set("company_id", claims.CompanyID)
 		c.Set("dep_id", claims.DepartmentID)
+		c.Set("session_id", claims.SessionID)
 		c.Set("claims", claims)
 		c.Next()
 	}
 }
 
 func (m *AuthMiddleware) validateToken(tokenString string) (*utils.Claims, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &utils.Claims{}, func(token *jwt.Token) (interface{}, error) {
-		return []byte(m.secretKey), nil
-	})
-
+	claims, token, err := utils.ParseClaims(tokenString)
 	if err != nil {
 		if errors.Is(err, jwt.ErrTokenExpired) {
 			return nil, ErrExpiredToken
@@ -66,8 +86,11 @@ func (m *AuthMiddleware) validateToken(tokenString string) (*utils.Claims, error
 		return nil, ErrInvalidToken
 	}
 
-	claims, ok := token.Claims.(*utils.Claims)
-	if !ok || !token.Valid {
+	if !token.Valid {
+		return nil, ErrInvalidToken
+	}
+	// Refresh-токен подписан тем же ключом, но для доступа к API не годится
+	if claims.TokenType == utils.TokenTypeRefresh {
 		return nil, ErrInvalidToken
 	}
 
@@ -75,9 +98,38 @@ func (m *AuthMiddleware) validateToken(tokenString string) (*utils.Claims, error
 		return nil, ErrExpiredToken
 	}
 
+	if err := m.checkRevoked(claims); err != nil {
+		return nil, err
+	}
+
 	return claims, nil
 }
 
+// checkRevoked отклоняет токен из denylist и токены, выданные до сброса пароля, смены роли
+// или исключения из компании. Токен без iat считается выданным до любой такой отметки.
+func (m *AuthMiddleware) checkRevoked(claims *utils.Claims) error {
+	if m.revocations == nil {
+		return nil
+	}
+
+	var issuedAt time.Time
+	if claims.IssuedAt != nil {
+		issuedAt = claims.IssuedAt.Time
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), revocationCheckTimeout)
+	defer cancel()
+	revoked, err := m.revocations.IsRevoked(ctx, claims.ID, claims.UserID, issuedAt)
+	if err != nil {
+		logger.Log.Error("Failed to check token revocation", slog.Int("user_id", claims.UserID), slog.String("error", err.Error()))
+		return ErrRevocationUnavailable
+	}
+	if revoked {
+		return ErrRevokedToken
+	}
+	return nil
+}
+
 func (m *AuthMiddleware) VerifyTokenWithClaims(tokenString string) (*utils.Claims, error) {
 	return m.validateToken(tokenString)
 }
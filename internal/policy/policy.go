@@ -0,0 +1,193 @@
+// Package policy — правила доступа к откликам, вакансиям, резюме и компаниям.
+// Решения принимаются по claims JWT (user_id, role_id, company_id, dep_id) и владельцу ресурса:
+//   - отклик видят сам соискатель и HR компании вакансии, управляет им только HR этой компании;
+//   - вакансию редактирует её автор или HR того же отдела компании;
+//   - компанию меняет и удаляет только её владелец;
+//   - резюме видят владелец и HR компаний, в вакансии которых соискатель откликался.
+package policy
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"jumyste-app-backend/internal/entity"
+	"jumyste-app-backend/internal/repository"
+	"jumyste-app-backend/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	RoleCandidate = 1
+	RoleHR        = 2
+)
+
+var (
+	ErrForbidden = errors.New("access denied")
+	ErrNotFound  = errors.New("resource not found")
+)
+
+// Subject — автор запроса по данным из JWT
+type Subject struct {
+	UserID       int
+	RoleID       int
+	CompanyID    int
+	DepartmentID int
+}
+
+// SubjectFromContext собирает Subject из значений, выставленных VerifyTokenMiddleware
+func SubjectFromContext(c *gin.Context) Subject {
+	return Subject{
+		UserID:       c.GetInt("user_id"),
+		RoleID:       c.GetInt("role_id"),
+		CompanyID:    c.GetInt("company_id"),
+		DepartmentID: c.GetInt("dep_id"),
+	}
+}
+
+func (s Subject) IsHR() bool {
+	return s.RoleID == RoleHR
+}
+
+// InCompany — HR, работающий в компании companyID
+func (s Subject) InCompany(companyID int) bool {
+	return s.IsHR() && s.CompanyID != 0 && s.CompanyID == companyID
+}
+
+type Policy struct {
+	VacancyRepo *repository.VacancyRepository
+	JobAppRepo  *repository.JobApplicationRepository
+	CompanyRepo *repository.CompanyRepository
+	HrRepo      *repository.HrRepository
+}
+
+func NewPolicy(vacancyRepo *repository.VacancyRepository,
+	jobAppRepo *repository.JobApplicationRepository,
+	companyRepo *repository.CompanyRepository,
+	hrRepo *repository.HrRepository,
+) *Policy {
+	return &Policy{VacancyRepo: vacancyRepo, JobAppRepo: jobAppRepo, CompanyRepo: companyRepo, HrRepo: hrRepo}
+}
+
+// CompanyVacancy разрешает HR доступ к вакансии своей компании и её откликам
+func (p *Policy) CompanyVacancy(ctx context.Context, sub Subject, vacancyID int) (*entity.Vacancy, error) {
+	vacancy, err := p.vacancy(vacancyID)
+	if err != nil {
+		return nil, err
+	}
+	if !sub.InCompany(vacancy.CompanyId) {
+		return nil, deny(sub, "vacancy", vacancyID)
+	}
+	return vacancy, nil
+}
+
+// EditVacancy разрешает изменение вакансии автору и HR из того же отдела компании
+func (p *Policy) EditVacancy(ctx context.Context, sub Subject, vacancyID int) (*entity.Vacancy, error) {
+	vacancy, err := p.CompanyVacancy(ctx, sub, vacancyID)
+	if err != nil {
+		return nil, err
+	}
+	if vacancy.CreatedBy == sub.UserID {
+		return vacancy, nil
+	}
+
+	author, err := p.HrRepo.GetHRByUserID(vacancy.CreatedBy)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, deny(sub, "vacancy", vacancyID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if sub.DepartmentID == 0 || author.DepID != sub.DepartmentID || author.CompanyID != sub.CompanyID {
+		return nil, deny(sub, "vacancy", vacancyID)
+	}
+	return vacancy, nil
+}
+
+// ViewApplication разрешает просмотр отклика самому соискателю и HR компании вакансии
+func (p *Policy) ViewApplication(ctx context.Context, sub Subject, applicationID int) (*entity.JobApplication, error) {
+	app, vacancy, err := p.application(ctx, applicationID)
+	if err != nil {
+		return nil, err
+	}
+	if app.UserID != sub.UserID && !sub.InCompany(vacancy.CompanyId) {
+		return nil, deny(sub, "job_application", applicationID)
+	}
+	return app, nil
+}
+
+// ManageApplication разрешает менять статус, этап и удалять отклик только HR компании вакансии
+func (p *Policy) ManageApplication(ctx context.Context, sub Subject, applicationID int) (*entity.JobApplication, error) {
+	app, vacancy, err := p.application(ctx, applicationID)
+	if err != nil {
+		return nil, err
+	}
+	if !sub.InCompany(vacancy.CompanyId) {
+		return nil, deny(sub, "job_application", applicationID)
+	}
+	return app, nil
+}
+
+// ManageCompany разрешает изменение и удаление компании её владельцу
+func (p *Policy) ManageCompany(ctx context.Context, sub Subject, companyID int) (*entity.Company, error) {
+	company, err := p.CompanyRepo.GetByID(companyID)
+	if err != nil {
+		return nil, err
+	}
+	if company == nil {
+		return nil, ErrNotFound
+	}
+	if company.OwnerId != sub.UserID {
+		return nil, deny(sub, "company", companyID)
+	}
+	return company, nil
+}
+
+// ViewResume разрешает просмотр резюме владельцу и HR компании, в которую соискатель откликался
+func (p *Policy) ViewResume(ctx context.Context, sub Subject, ownerID int) error {
+	if ownerID == sub.UserID {
+		return nil
+	}
+	if !sub.IsHR() || sub.CompanyID == 0 {
+		return deny(sub, "resume", ownerID)
+	}
+
+	applied, err := p.JobAppRepo.HasApplicationToCompany(ctx, ownerID, sub.CompanyID)
+	if err != nil {
+		return err
+	}
+	if !applied {
+		return deny(sub, "resume", ownerID)
+	}
+	return nil
+}
+
+func (p *Policy) vacancy(vacancyID int) (*entity.Vacancy, error) {
+	vacancy, err := p.VacancyRepo.GetVacancyById(vacancyID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	return vacancy, err
+}
+
+func (p *Policy) application(ctx context.Context, applicationID int) (*entity.JobApplication, *entity.Vacancy, error) {
+	app, err := p.JobAppRepo.GetJobApplicationByID(ctx, applicationID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	vacancy, err := p.vacancy(app.VacancyID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return app, vacancy, nil
+}
+
+func deny(sub Subject, resource string, id int) error {
+	logger.Log.Warn("Access denied", "resource", resource, "resource_id", id,
+		"user_id", sub.UserID, "role_id", sub.RoleID, "company_id", sub.CompanyID)
+	return ErrForbidden
+}
@@ -60,8 +60,8 @@ func (r *AuthRepository) GetUserByEmail(email string) (*entity.User, error) {
 	logger.Log.Info("Fetching user by email", slog.String("email", email))
 
 	var user entity.User
-	query := "SELECT id, email, password, first_name, last_name, profile_picture, role_id FROM users WHERE email = $1"
-	err := r.db.QueryRow(query, email).Scan(&user.ID, &user.Email, &user.Password, &user.FirstName, &user.LastName, &user.ProfilePicture, &user.RoleId)
+	query := "SELECT id, email, password, first_name, last_name, profile_picture, role_id, email_verified_at IS NOT NULL FROM users WHERE email = $1"
+	err := r.db.QueryRow(query, email).Scan(&user.ID, &user.Email, &user.Password, &user.FirstName, &user.LastName, &user.ProfilePicture, &user.RoleId, &user.EmailVerified)
 	if err != nil {
 		logger.Log.Error("User not found",
 			slog.String("email", email),
@@ -107,3 +107,77 @@ func (r *AuthRepository) DeletePasswordResetCode(userID int) error {
 	_, err := r.db.Exec(query, userID)
 	return err
 }
+
+// EmailVerification — выданный код подтверждения email. Attempts считает неверные вводы текущего кода,
+// WindowAttempts — все неверные вводы пользователя с WindowStartedAt, включая вводы прежних кодов
+type EmailVerification struct {
+	Code            string
+	Attempts        int
+	WindowAttempts  int
+	WindowStartedAt time.Time
+	ExpiresAt       time.Time
+	CreatedAt       time.Time
+}
+
+// SaveEmailVerificationCode заменяет код пользователя; счётчик неверных вводов в окне сохраняется
+func (r *AuthRepository) SaveEmailVerificationCode(userID int, code string, createdAt, expiresAt time.Time) error {
+	query := `
+		INSERT INTO email_verifications(user_id, code, created_at, expires_at, window_started_at)
+		VALUES ($1, $2, $3, $4, $3)
+		ON CONFLICT (user_id) DO UPDATE
+		SET code = EXCLUDED.code, attempts = 0, created_at = EXCLUDED.created_at, expires_at = EXCLUDED.expires_at;
+	`
+	_, err := r.db.Exec(query, userID, code, createdAt, expiresAt)
+	return err
+}
+
+func (r *AuthRepository) GetEmailVerificationCode(userID int) (*EmailVerification, error) {
+	var verification EmailVerification
+
+	query := `SELECT code, attempts, window_attempts, window_started_at, expires_at, created_at
+	          FROM email_verifications WHERE user_id = $1`
+	err := r.db.QueryRow(query, userID).Scan(&verification.Code, &verification.Attempts, &verification.WindowAttempts,
+		&verification.WindowStartedAt, &verification.ExpiresAt, &verification.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &verification, nil
+}
+
+// ConsumeEmailVerificationAttempt атомарно списывает попытку ввода и возвращает код для сравнения.
+// Попытка списывается, только если код не истёк и лимиты maxAttempts на код и maxWindowAttempts
+// в окне не исчерпаны; окно, начатое раньше windowStart, открывается заново с момента now.
+// sql.ErrNoRows — кода нет или попытки исчерпаны. Параллельные запросы сериализуются блокировкой строки.
+func (r *AuthRepository) ConsumeEmailVerificationAttempt(userID int, now, windowStart time.Time, maxAttempts, maxWindowAttempts int) (string, error) {
+	query := `
+		UPDATE email_verifications
+		SET attempts          = attempts + 1,
+		    window_attempts   = CASE WHEN window_started_at < $3 THEN 1 ELSE window_attempts + 1 END,
+		    window_started_at = CASE WHEN window_started_at < $3 THEN $2 ELSE window_started_at END
+		WHERE user_id = $1
+		  AND attempts < $4
+		  AND expires_at > $2
+		  AND (window_started_at < $3 OR window_attempts < $5)
+		RETURNING code`
+	var code string
+	err := r.db.QueryRow(query, userID, now, windowStart, maxAttempts, maxWindowAttempts).Scan(&code)
+	return code, err
+}
+
+// MarkEmailVerified подтверждает email и удаляет код
+func (r *AuthRepository) MarkEmailVerified(userID int) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`UPDATE users SET email_verified_at = now() WHERE id = $1 AND email_verified_at IS NULL`, userID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM email_verifications WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
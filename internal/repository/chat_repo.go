@@ -2,6 +2,7 @@ package repository
 
 import (
 	"database/sql"
+	"errors"
 	"github.com/lib/pq"
 	"jumyste-app-backend/internal/entity"
 	"jumyste-app-backend/pkg/logger"
@@ -9,6 +10,65 @@ import (
 	"time"
 )
 
+// ErrDuplicateApplicationChat — чат для отклика уже создан параллельным запросом
+var ErrDuplicateApplicationChat = errors.New("chat for this application already exists")
+
+// chatDetailColumns — групповые поля и контекст чата; ожидает псевдонимы c (chats), v (vacancies), ja (job_applications)
+const chatDetailColumns = `c.is_group, c.title, c.is_internal, c.company_id,
+	c.vacancy_id, c.application_id, v.title, v.status, ja.status, ja.stage_id`
+
+const chatDetailJoins = `
+		LEFT JOIN vacancies v ON v.id = c.vacancy_id
+		LEFT JOIN job_applications ja ON ja.id = c.application_id`
+
+// chatDetailDest — приёмники для chatDetailColumns; fill заполняет чат после Scan
+type chatDetailDest struct {
+	isGroup, isInternal                 bool
+	title                               sql.NullString
+	companyID                           sql.NullInt64
+	vacancyID, applicationID, stageID   sql.NullInt64
+	vacancyTitle, vacancyStatus, status sql.NullString
+}
+
+func (d *chatDetailDest) targets() []interface{} {
+	return []interface{}{&d.isGroup, &d.title, &d.isInternal, &d.companyID,
+		&d.vacancyID, &d.applicationID, &d.vacancyTitle, &d.vacancyStatus, &d.status, &d.stageID}
+}
+
+func (d *chatDetailDest) fill(chat *entity.Chat) {
+	chat.IsGroup = d.isGroup
+	chat.IsInternal = d.isInternal
+	if d.title.Valid {
+		chat.Title = &d.title.String
+	}
+	if d.companyID.Valid {
+		id := int(d.companyID.Int64)
+		chat.CompanyID = &id
+	}
+	if d.applicationID.Valid {
+		id := int(d.applicationID.Int64)
+		chat.ApplicationID = &id
+	}
+	if !d.vacancyID.Valid {
+		return
+	}
+	vacancyID := int(d.vacancyID.Int64)
+	chat.VacancyID = &vacancyID
+	chat.Context = &entity.ChatContext{
+		VacancyID:     vacancyID,
+		VacancyTitle:  d.vacancyTitle.String,
+		VacancyStatus: d.vacancyStatus.String,
+		ApplicationID: chat.ApplicationID,
+	}
+	if d.status.Valid {
+		chat.Context.ApplicationStatus = &d.status.String
+	}
+	if d.stageID.Valid {
+		stageID := int(d.stageID.Int64)
+		chat.Context.StageID = &stageID
+	}
+}
+
 type ChatRepository struct {
 	DB *sql.DB
 }
@@ -17,25 +77,36 @@ func NewChatRepository(db *sql.DB) *ChatRepository {
 	return &ChatRepository{DB: db}
 }
 
-// CreateChat - Creates a new chat and adds users to chat_users table
+// CreateChat - Creates a new chat and adds users to chat_users table with their ChatRole (member by default)
 func (r *ChatRepository) CreateChat(chat *entity.Chat) (int, error) {
 	tx, err := r.DB.Begin()
 	if err != nil {
 		return 0, err
 	}
 
-	query := "INSERT INTO chats (created_at, updated_at) VALUES ($1, $2) RETURNING id"
+	query := `INSERT INTO chats (created_at, updated_at, vacancy_id, application_id, is_group, title, is_internal, company_id, created_by)
+	          VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING id`
 	var chatID int
-	err = tx.QueryRow(query, time.Now(), time.Now()).Scan(&chatID)
+	err = tx.QueryRow(query, time.Now(), time.Now(), chat.VacancyID, chat.ApplicationID,
+		chat.IsGroup, chat.Title, chat.IsInternal, chat.CompanyID, chat.CreatedBy).Scan(&chatID)
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" && pqErr.Constraint == "uq_chats_application_id" {
+		tx.Rollback()
+		return 0, ErrDuplicateApplicationChat
+	}
 	if err != nil {
 		tx.Rollback()
 		return 0, err
 	}
 	chat.ID = chatID
 
-	userQuery := "INSERT INTO chat_users (chat_id, user_id) VALUES ($1, $2)"
+	userQuery := "INSERT INTO chat_users (chat_id, user_id, role) VALUES ($1, $2, $3)"
 	for _, user := range chat.Users {
-		_, err := tx.Exec(userQuery, chatID, user.ID)
+		role := user.ChatRole
+		if role == "" {
+			role = entity.ChatRoleMember
+		}
+		_, err := tx.Exec(userQuery, chatID, user.ID, role)
 		if err != nil {
 			tx.Rollback()
 			return 0, err
@@ -52,17 +123,22 @@ func (r *ChatRepository) CreateChat(chat *entity.Chat) (int, error) {
 
 // GetChatByID - Fetches a chat by its ID
 func (r *ChatRepository) GetChatByID(chatID uint) (*entity.Chat, error) {
-	query := "SELECT id, created_at, updated_at FROM chats WHERE id = $1"
+	query := `SELECT c.id, c.created_at, c.updated_at, ` + chatDetailColumns + `
+	          FROM chats c` + chatDetailJoins + `
+	          WHERE c.id = $1`
 	row := r.DB.QueryRow(query, chatID)
 
 	var chat entity.Chat
-	err := row.Scan(&chat.ID, &chat.CreatedAt, &chat.UpdatedAt)
+	var detail chatDetailDest
+	err := row.Scan(append([]interface{}{&chat.ID, &chat.CreatedAt, &chat.UpdatedAt}, detail.targets()...)...)
 	if err != nil {
 		return nil, err
 	}
+	detail.fill(&chat)
 
-	userQuery := `SELECT u.id, u.first_name, u.last_name,u.email FROM users u 
-	              JOIN chat_users cu ON u.id = cu.user_id WHERE cu.chat_id = $1`
+	userQuery := `SELECT u.id, u.first_name, u.last_name, u.email, cu.role FROM users u 
+	              JOIN chat_users cu ON u.id = cu.user_id WHERE cu.chat_id = $1
+	              ORDER BY cu.joined_at, u.id`
 	rows, err := r.DB.Query(userQuery, chatID)
 	if err != nil {
 		return nil, err
@@ -72,7 +148,7 @@ func (r *ChatRepository) GetChatByID(chatID uint) (*entity.Chat, error) {
 	var users []entity.UserResponse
 	for rows.Next() {
 		var user entity.UserResponse
-		if err := rows.Scan(&user.ID, &user.FirstName, &user.LastName, &user.Email); err != nil {
+		if err := rows.Scan(&user.ID, &user.FirstName, &user.LastName, &user.Email, &user.ChatRole); err != nil {
 			return nil, err
 		}
 		users = append(users, user)
@@ -102,32 +178,6 @@ func (r *ChatRepository) GetChatByID(chatID uint) (*entity.Chat, error) {
 //	return chats, nil
 //}
 
-func (r *ChatRepository) GetAllChats() ([]entity.Chat, error) {
-	query := "SELECT id, created_at, updated_at FROM chats"
-	rows, err := r.DB.Query(query)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	var chats []entity.Chat
-	for rows.Next() {
-		var chat entity.Chat
-		if err := rows.Scan(&chat.ID, &chat.CreatedAt, &chat.UpdatedAt); err != nil {
-			return nil, err
-		}
-
-		users, err := r.GetUsersByChatID(chat.ID, -1)
-		if err != nil {
-			return nil, err
-		}
-		chat.Users = users
-
-		chats = append(chats, chat)
-	}
-	return chats, nil
-}
-
 func (r *ChatRepository) GetUsersByIDs(userIDs []int) ([]entity.UserResponse, error) {
 	query := "SELECT id, first_name, last_name, email FROM users WHERE id = ANY($1)"
 	rows, err := r.DB.Query(query, pq.Array(userIDs))
@@ -148,8 +198,8 @@ func (r *ChatRepository) GetUsersByIDs(userIDs []int) ([]entity.UserResponse, er
 	return users, nil
 }
 
-// GetChatsByUserID - Получает все чаты, в которых состоит пользователь
-func (r *ChatRepository) GetChatsByUserID(userID int) ([]entity.Chat, error) {
+// GetChatsByUserID - Получает все чаты, в которых состоит пользователь; vacancyID > 0 оставляет чаты этой вакансии
+func (r *ChatRepository) GetChatsByUserID(userID, vacancyID int) ([]entity.Chat, error) {
 	query := `
 		SELECT 
 		    c.id, 
@@ -157,19 +207,22 @@ func (r *ChatRepository) GetChatsByUserID(userID int) ([]entity.Chat, error) {
 		    c.updated_at,
 		    COALESCE(m.content, '') AS last_message, 
 		    m.created_at AS last_message_at,
-		    COALESCE(($1 = ANY(m.read_by)), false) AS is_read 
+		    (SELECT count(*) FROM messages m WHERE m.chat_id = c.id AND ` + unreadCondition + `) AS unread_count,
+		    ` + chatDetailColumns + `
 		FROM chats c
-		JOIN chat_users cu ON c.id = cu.chat_id
+		JOIN chat_users cu ON c.id = cu.chat_id` + chatDetailJoins + `
 		LEFT JOIN (
-		    SELECT DISTINCT ON (chat_id) chat_id, content, created_at, read_by
+		    SELECT DISTINCT ON (chat_id) chat_id,
+		           CASE WHEN deleted_at IS NULL THEN content END AS content, created_at
 		    FROM messages
+		    WHERE NOT EXISTS (SELECT 1 FROM message_hidden h WHERE h.message_id = messages.id AND h.user_id = $1)
 		    ORDER BY chat_id, created_at DESC
 		) m ON c.id = m.chat_id
-		WHERE cu.user_id = $1
+		WHERE cu.user_id = $1 AND ($2 = 0 OR c.vacancy_id = $2)
 		ORDER BY m.created_at DESC NULLS LAST;
 	`
 
-	rows, err := r.DB.Query(query, userID)
+	rows, err := r.DB.Query(query, userID, vacancyID)
 	if err != nil {
 		logger.Log.Error("Failed to fetch chats", slog.Int("user_id", userID), slog.String("error", err.Error()))
 		return nil, err
@@ -181,17 +234,20 @@ func (r *ChatRepository) GetChatsByUserID(userID int) ([]entity.Chat, error) {
 		var chat entity.Chat
 		var lastMessage string
 		var lastMessageAt sql.NullTime
-		var isRead sql.NullBool // ✅ Используем sql.NullBool
 
-		if err := rows.Scan(&chat.ID, &chat.CreatedAt, &chat.UpdatedAt, &lastMessage, &lastMessageAt, &isRead); err != nil {
+		var detail chatDetailDest
+
+		dest := append([]interface{}{&chat.ID, &chat.CreatedAt, &chat.UpdatedAt, &lastMessage, &lastMessageAt, &chat.UnreadCount}, detail.targets()...)
+		if err := rows.Scan(dest...); err != nil {
 			return nil, err
 		}
+		detail.fill(&chat)
 
 		chat.LastMessage = lastMessage
 		if lastMessageAt.Valid {
 			chat.LastMessageAt = lastMessageAt.Time
 		}
-		chat.IsRead = isRead.Valid && isRead.Bool
+		chat.IsRead = chat.UnreadCount == 0
 
 		users, err := r.GetUsersByChatID(chat.ID, userID)
 		if err != nil {
@@ -209,13 +265,70 @@ func (r *ChatRepository) GetChatsByUserID(userID int) ([]entity.Chat, error) {
 	return chats, nil
 }
 
+// IsChatMember - Проверяет, состоит ли пользователь в чате
+func (r *ChatRepository) IsChatMember(chatID, userID int) (bool, error) {
+	var exists bool
+	query := `SELECT EXISTS (SELECT 1 FROM chat_users WHERE chat_id = $1 AND user_id = $2)`
+	if err := r.DB.QueryRow(query, chatID, userID).Scan(&exists); err != nil {
+		logger.Log.Error("Failed to check chat membership", slog.Int("chat_id", chatID), slog.Int("user_id", userID), slog.String("error", err.Error()))
+		return false, err
+	}
+	return exists, nil
+}
+
+// FilterChatContacts - Оставляет из userIDs тех, с кем userID состоит хотя бы в одном общем чате
+func (r *ChatRepository) FilterChatContacts(userID int, userIDs []int) ([]int, error) {
+	query := `
+		SELECT DISTINCT other.user_id
+		FROM chat_users own
+		JOIN chat_users other ON other.chat_id = own.chat_id
+		WHERE own.user_id = $1 AND other.user_id = ANY($2)
+	`
+	rows, err := r.DB.Query(query, userID, pq.Array(userIDs))
+	if err != nil {
+		logger.Log.Error("Failed to filter chat contacts", slog.Int("user_id", userID), slog.String("error", err.Error()))
+		return nil, err
+	}
+	defer rows.Close()
+
+	var contacts []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		contacts = append(contacts, id)
+	}
+	return contacts, rows.Err()
+}
+
+// GetChatIDsByUserID - Возвращает ID всех чатов пользователя
+func (r *ChatRepository) GetChatIDsByUserID(userID int) ([]int, error) {
+	rows, err := r.DB.Query(`SELECT chat_id FROM chat_users WHERE user_id = $1`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var chatIDs []int
+	for rows.Next() {
+		var chatID int
+		if err := rows.Scan(&chatID); err != nil {
+			return nil, err
+		}
+		chatIDs = append(chatIDs, chatID)
+	}
+	return chatIDs, rows.Err()
+}
+
 // GetUsersByChatID - Получает собеседников в чате
 func (r *ChatRepository) GetUsersByChatID(chatID, userID int) ([]entity.UserResponse, error) {
 	query := `
-		SELECT u.id, u.email, u.first_name, u.last_name, u.profile_picture 
+		SELECT u.id, u.email, u.first_name, u.last_name, u.profile_picture, u.last_seen_at, cu.role
 		FROM users u 
 		JOIN chat_users cu ON u.id = cu.user_id 
-		WHERE cu.chat_id = $1 AND u.id != $2`
+		WHERE cu.chat_id = $1 AND u.id != $2
+		ORDER BY cu.joined_at, u.id`
 
 	rows, err := r.DB.Query(query, chatID, userID)
 	if err != nil {
@@ -226,15 +339,32 @@ func (r *ChatRepository) GetUsersByChatID(chatID, userID int) ([]entity.UserResp
 	var users []entity.UserResponse
 	for rows.Next() {
 		var user entity.UserResponse
-		if err := rows.Scan(&user.ID, &user.Email, &user.FirstName, &user.LastName, &user.ProfilePicture); err != nil {
+		var lastSeenAt sql.NullTime
+		if err := rows.Scan(&user.ID, &user.Email, &user.FirstName, &user.LastName, &user.ProfilePicture, &lastSeenAt, &user.ChatRole); err != nil {
 			return nil, err
 		}
+		if lastSeenAt.Valid {
+			user.LastSeenAt = &lastSeenAt.Time
+		}
 		users = append(users, user)
 	}
 
 	return users, nil
 }
 
+// GetChatByApplicationID - Чат соискателя по отклику или nil, если его ещё нет (внутренние чаты HR не учитываются)
+func (r *ChatRepository) GetChatByApplicationID(applicationID int) (*entity.Chat, error) {
+	var chatID int
+	err := r.DB.QueryRow(`SELECT id FROM chats WHERE application_id = $1 AND NOT is_internal`, applicationID).Scan(&chatID)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return r.GetChatByID(uint(chatID))
+}
+
 func (r *ChatRepository) GetChatBetweenUsers(userID1, userID2 int) (*entity.Chat, error) {
 	query := `
 		SELECT c.id, c.created_at, c.updated_at
@@ -264,3 +394,66 @@ func (r *ChatRepository) GetChatBetweenUsers(userID1, userID2 int) (*entity.Chat
 
 	return &chat, nil
 }
+
+// GetChatUserRole - Роль пользователя в чате; пустая строка, если он не участник
+func (r *ChatRepository) GetChatUserRole(chatID, userID int) (string, error) {
+	var role string
+	err := r.DB.QueryRow(`SELECT role FROM chat_users WHERE chat_id = $1 AND user_id = $2`, chatID, userID).Scan(&role)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return role, err
+}
+
+// AddChatUsers - Добавляет участников; возвращает тех, кого в чате ещё не было
+func (r *ChatRepository) AddChatUsers(chatID int, userIDs []int) ([]int, error) {
+	query := `
+		INSERT INTO chat_users (chat_id, user_id, role)
+		SELECT $1, unnest($2::int[]), 'member'
+		ON CONFLICT (chat_id, user_id) DO NOTHING
+		RETURNING user_id`
+	rows, err := r.DB.Query(query, chatID, pq.Array(userIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var added []int
+	for rows.Next() {
+		var userID int
+		if err := rows.Scan(&userID); err != nil {
+			return nil, err
+		}
+		added = append(added, userID)
+	}
+	return added, rows.Err()
+}
+
+// RemoveChatUser - Удаляет участника из чата
+func (r *ChatRepository) RemoveChatUser(chatID, userID int) (bool, error) {
+	result, err := r.DB.Exec(`DELETE FROM chat_users WHERE chat_id = $1 AND user_id = $2`, chatID, userID)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+// SetChatUserRole - Назначает участнику роль member или admin
+func (r *ChatRepository) SetChatUserRole(chatID, userID int, role string) error {
+	_, err := r.DB.Exec(`UPDATE chat_users SET role = $3 WHERE chat_id = $1 AND user_id = $2`, chatID, userID, role)
+	return err
+}
+
+// CountChatAdmins - Число администраторов чата
+func (r *ChatRepository) CountChatAdmins(chatID int) (int, error) {
+	var count int
+	err := r.DB.QueryRow(`SELECT count(*) FROM chat_users WHERE chat_id = $1 AND role = 'admin'`, chatID).Scan(&count)
+	return count, err
+}
+
+// UpdateChatTitle - Переименовывает групповой чат
+func (r *ChatRepository) UpdateChatTitle(chatID int, title string) error {
+	_, err := r.DB.Exec(`UPDATE chats SET title = $2, updated_at = now() WHERE id = $1`, chatID, title)
+	return err
+}
@@ -0,0 +1,307 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"jumyste-app-backend/internal/entity"
+	"jumyste-app-backend/pkg/logger"
+
+	"github.com/lib/pq"
+)
+
+// Ошибки проверки новой воронки в ReplaceStages
+var (
+	ErrUnknownStage       = errors.New("hiring stage not found")
+	ErrStageInUse         = errors.New("hiring stage has applications")
+	ErrStageStatusInUse   = errors.New("hiring stage with applications changes status")
+	ErrStageStatusMissing = errors.New("vacancy has applications in a status without a stage")
+)
+
+type HiringStageRepository struct {
+	DB *sql.DB
+}
+
+func NewHiringStageRepository(db *sql.DB) *HiringStageRepository {
+	return &HiringStageRepository{DB: db}
+}
+
+// GetStages возвращает этапы воронки компании (vacancyID == nil) или переопределение вакансии
+func (r *HiringStageRepository) GetStages(ctx context.Context, companyID int, vacancyID *int) ([]entity.HiringStage, error) {
+	query := `
+        SELECT id, company_id, vacancy_id, name, status, position, created_at
+        FROM hiring_stages
+        WHERE company_id = $1 AND vacancy_id IS NOT DISTINCT FROM $2
+        ORDER BY position, id
+    `
+	rows, err := r.DB.QueryContext(ctx, query, companyID, vacancyID)
+	if err != nil {
+		logger.Log.Error("Failed to get hiring stages", "company_id", companyID, "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	stages := []entity.HiringStage{}
+	for rows.Next() {
+		var stage entity.HiringStage
+		if err := rows.Scan(&stage.ID, &stage.CompanyID, &stage.VacancyID, &stage.Name, &stage.Status, &stage.Position, &stage.CreatedAt); err != nil {
+			logger.Log.Error("Failed to scan hiring stage", "error", err)
+			return nil, err
+		}
+		stages = append(stages, stage)
+	}
+	return stages, rows.Err()
+}
+
+// EnsureDefaultStages создаёт воронку компании из defaults, если её ещё нет. Проверка и вставка
+// выполняются под блокировкой воронки, поэтому параллельные первые обращения не создают её дважды
+func (r *HiringStageRepository) EnsureDefaultStages(ctx context.Context, companyID int, defaults []entity.HiringStage) error {
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := lockPipeline(ctx, tx, companyID); err != nil {
+		return err
+	}
+
+	var exists bool
+	err = tx.QueryRowContext(ctx, `SELECT EXISTS(SELECT 1 FROM hiring_stages WHERE company_id = $1 AND vacancy_id IS NULL)`, companyID).Scan(&exists)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		if _, err := replaceStages(ctx, tx, companyID, nil, defaults); err != nil {
+			return err
+		}
+		logger.Log.Info("Default hiring pipeline created", "company_id", companyID)
+	}
+
+	return tx.Commit()
+}
+
+// ReplaceStages заменяет воронку целиком: этапы с ID обновляются, без ID создаются,
+// отсутствующие в списке удаляются. Позиции выставляются по порядку в списке.
+// Новая воронка проверяется под той же блокировкой, что и запись, см. checkStages.
+func (r *HiringStageRepository) ReplaceStages(ctx context.Context, companyID int, vacancyID *int, stages []entity.HiringStage) ([]entity.HiringStage, error) {
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if err := lockPipeline(ctx, tx, companyID); err != nil {
+		return nil, err
+	}
+	if err := checkStages(ctx, tx, companyID, vacancyID, stages); err != nil {
+		return nil, err
+	}
+	if stages, err = replaceStages(ctx, tx, companyID, vacancyID, stages); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return stages, nil
+}
+
+// lockPipeline сериализует изменения воронок компании до конца транзакции
+func lockPipeline(ctx context.Context, tx *sql.Tx, companyID int) error {
+	_, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock(hashtext('hiring_stages'), $1)`, companyID)
+	if err != nil {
+		logger.Log.Error("Failed to lock hiring pipeline", "company_id", companyID, "error", err)
+	}
+	return err
+}
+
+// checkStages не даёт новой воронке потерять отклики: нельзя удалить этап с откликами или сменить
+// ему статус, а переопределение вакансии должно иметь этап для каждого статуса её откликов
+func checkStages(ctx context.Context, tx *sql.Tx, companyID int, vacancyID *int, stages []entity.HiringStage) error {
+	rows, err := tx.QueryContext(ctx, `
+        SELECT id, status FROM hiring_stages
+        WHERE company_id = $1 AND vacancy_id IS NOT DISTINCT FROM $2
+    `, companyID, vacancyID)
+	if err != nil {
+		return err
+	}
+	existing := make(map[int]string)
+	for rows.Next() {
+		var id int
+		var status string
+		if err := rows.Scan(&id, &status); err != nil {
+			rows.Close()
+			return err
+		}
+		existing[id] = status
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	// kept — этапы, которые остаются в воронке, statuses — статусы новой воронки
+	kept := make(map[int]bool, len(stages))
+	statuses := make([]string, 0, len(stages))
+	var restatused []int
+	for _, stage := range stages {
+		statuses = append(statuses, stage.Status)
+		if stage.ID == 0 {
+			continue
+		}
+		status, ok := existing[stage.ID]
+		if !ok {
+			return ErrUnknownStage
+		}
+		kept[stage.ID] = true
+		if status != stage.Status {
+			restatused = append(restatused, stage.ID)
+		}
+	}
+	var removed []int
+	for id := range existing {
+		if !kept[id] {
+			removed = append(removed, id)
+		}
+	}
+
+	inUse, err := countApplicationsByStages(ctx, tx, removed)
+	if err != nil {
+		return err
+	}
+	if inUse > 0 {
+		return ErrStageInUse
+	}
+
+	// Статус отклика задаётся этапом: смена статуса этапа с откликами рассинхронизировала бы их
+	inUse, err = countApplicationsByStages(ctx, tx, restatused)
+	if err != nil {
+		return err
+	}
+	if inUse > 0 {
+		return ErrStageStatusInUse
+	}
+
+	if vacancyID != nil {
+		// Отклики переезжают на этапы переопределения по статусу, без подходящего этапа они выпали бы из воронки
+		var missing bool
+		err = tx.QueryRowContext(ctx, `
+            SELECT EXISTS(
+                SELECT 1 FROM job_applications
+                WHERE vacancy_id = $1 AND status <> $2 AND NOT (status = ANY($3))
+            )
+        `, *vacancyID, entity.ApplicationStatusWithdrawn, pq.Array(statuses)).Scan(&missing)
+		if err != nil {
+			return err
+		}
+		if missing {
+			return ErrStageStatusMissing
+		}
+	}
+	return nil
+}
+
+// countApplicationsByStages считает отклики, которые стоят на указанных этапах
+func countApplicationsByStages(ctx context.Context, tx *sql.Tx, stageIDs []int) (int, error) {
+	if len(stageIDs) == 0 {
+		return 0, nil
+	}
+	var count int
+	err := tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM job_applications WHERE stage_id = ANY($1)`, pq.Array(stageIDs)).Scan(&count)
+	if err != nil {
+		logger.Log.Error("Failed to count applications by stages", "error", err)
+		return 0, err
+	}
+	return count, nil
+}
+
+func replaceStages(ctx context.Context, tx *sql.Tx, companyID int, vacancyID *int, stages []entity.HiringStage) ([]entity.HiringStage, error) {
+	var err error
+	keep := make([]int, 0, len(stages))
+	for i := range stages {
+		stages[i].CompanyID = companyID
+		stages[i].VacancyID = vacancyID
+		stages[i].Position = i + 1
+
+		if stages[i].ID != 0 {
+			_, err = tx.ExecContext(ctx, `
+                UPDATE hiring_stages SET name = $1, status = $2, position = $3
+                WHERE id = $4 AND company_id = $5 AND vacancy_id IS NOT DISTINCT FROM $6
+            `, stages[i].Name, stages[i].Status, stages[i].Position, stages[i].ID, companyID, vacancyID)
+		} else {
+			err = tx.QueryRowContext(ctx, `
+                INSERT INTO hiring_stages (company_id, vacancy_id, name, status, position)
+                VALUES ($1, $2, $3, $4, $5)
+                RETURNING id, created_at
+            `, companyID, vacancyID, stages[i].Name, stages[i].Status, stages[i].Position).Scan(&stages[i].ID, &stages[i].CreatedAt)
+		}
+		if err != nil {
+			logger.Log.Error("Failed to save hiring stage", "company_id", companyID, "error", err)
+			return nil, err
+		}
+		keep = append(keep, stages[i].ID)
+	}
+
+	if vacancyID != nil {
+		// Отклики вакансии переезжают на этапы переопределения с тем же статусом
+		_, err = tx.ExecContext(ctx, `
+            UPDATE job_applications ja
+            SET stage_id = (
+                SELECT hs.id FROM hiring_stages hs
+                WHERE hs.id = ANY($2) AND hs.status = ja.status
+                ORDER BY hs.position LIMIT 1
+            )
+            WHERE ja.vacancy_id = $1 AND (ja.stage_id IS NULL OR NOT (ja.stage_id = ANY($2)))
+        `, *vacancyID, pq.Array(keep))
+		if err != nil {
+			logger.Log.Error("Failed to move applications to vacancy stages", "vacancy_id", *vacancyID, "error", err)
+			return nil, err
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, `
+        DELETE FROM hiring_stages
+        WHERE company_id = $1 AND vacancy_id IS NOT DISTINCT FROM $2 AND NOT (id = ANY($3))
+    `, companyID, vacancyID, pq.Array(keep))
+	if err != nil {
+		logger.Log.Error("Failed to delete removed hiring stages", "company_id", companyID, "error", err)
+		return nil, err
+	}
+	return stages, nil
+}
+
+// DeleteVacancyStages убирает переопределение вакансии; отклики переезжают
+// на первый этап воронки компании с тем же статусом
+func (r *HiringStageRepository) DeleteVacancyStages(ctx context.Context, companyID, vacancyID int) error {
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := lockPipeline(ctx, tx, companyID); err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+        UPDATE job_applications ja
+        SET stage_id = (
+            SELECT hs.id FROM hiring_stages hs
+            WHERE hs.company_id = $1 AND hs.vacancy_id IS NULL AND hs.status = ja.status
+            ORDER BY hs.position LIMIT 1
+        )
+        WHERE ja.vacancy_id = $2
+    `, companyID, vacancyID)
+	if err != nil {
+		logger.Log.Error("Failed to move applications to company stages", "vacancy_id", vacancyID, "error", err)
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM hiring_stages WHERE vacancy_id = $1`, vacancyID); err != nil {
+		logger.Log.Error("Failed to delete vacancy stages", "vacancy_id", vacancyID, "error", err)
+		return err
+	}
+
+	return tx.Commit()
+}
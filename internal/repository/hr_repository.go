@@ -2,6 +2,7 @@ package repository
 
 import (
 	"database/sql"
+	"github.com/lib/pq"
 	"jumyste-app-backend/internal/entity"
 	"jumyste-app-backend/pkg/logger"
 	"log/slog"
@@ -63,3 +64,30 @@ func (r *HrRepository) DeleteHR(userID int) error {
 	}
 	return nil
 }
+
+// CountCompanyHRs - Сколько из пользователей userIDs работают HR в компании
+func (r *HrRepository) CountCompanyHRs(companyID int, userIDs []int) (int, error) {
+	query := `SELECT COUNT(DISTINCT user_id) FROM hr WHERE company_id = $1 AND user_id = ANY($2)`
+	var count int
+	err := r.DB.QueryRow(query, companyID, pq.Array(userIDs)).Scan(&count)
+	return count, err
+}
+
+// GetUserIDsByCompanyID - Пользователи, работающие HR в компании
+func (r *HrRepository) GetUserIDsByCompanyID(companyID int) ([]int, error) {
+	rows, err := r.DB.Query(`SELECT user_id FROM hr WHERE company_id = $1`, companyID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var userIDs []int
+	for rows.Next() {
+		var userID int
+		if err := rows.Scan(&userID); err != nil {
+			return nil, err
+		}
+		userIDs = append(userIDs, userID)
+	}
+	return userIDs, rows.Err()
+}
@@ -0,0 +1,364 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"jumyste-app-backend/internal/entity"
+	"jumyste-app-backend/pkg/logger"
+	"sort"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type InterviewRepository struct {
+	DB *sql.DB
+}
+
+func NewInterviewRepository(db *sql.DB) *InterviewRepository {
+	return &InterviewRepository{DB: db}
+}
+
+// InterviewFilter — условия выборки интервью; нулевые поля не ограничивают выборку
+type InterviewFilter struct {
+	ApplicationID int
+	ParticipantID int
+	CompanyID     int
+	From          time.Time
+	To            time.Time
+}
+
+const interviewColumns = `i.id, i.application_id, i.organizer_id, i.start_at, i.end_at, i.format, i.location, i.meeting_link,
+        i.notes, i.status, i.sequence, i.reminder_sent_at, i.created_at, i.updated_at`
+
+func scanInterview(scanner interface{ Scan(...interface{}) error }, interview *entity.Interview) error {
+	return scanner.Scan(&interview.ID, &interview.ApplicationID, &interview.OrganizerID, &interview.StartAt, &interview.EndAt,
+		&interview.Format, &interview.Location, &interview.MeetingLink, &interview.Notes, &interview.Status,
+		&interview.Sequence, &interview.ReminderSentAt, &interview.CreatedAt, &interview.UpdatedAt)
+}
+
+// Create сохраняет интервью, если у интервьюеров нет пересекающихся интервью; иначе возвращает пересечения.
+// Проверка и вставка идут в одной транзакции под блокировками интервьюеров
+func (r *InterviewRepository) Create(ctx context.Context, interview *entity.Interview, interviewerIDs []int) ([]entity.InterviewConflict, error) {
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if conflicts, err := lockAndFindConflicts(ctx, tx, interviewerIDs, interview); len(conflicts) > 0 || err != nil {
+		return conflicts, err
+	}
+
+	query := `
+        INSERT INTO interviews (application_id, organizer_id, start_at, end_at, format, location, meeting_link, notes, status)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+        RETURNING id, sequence, created_at, updated_at
+    `
+	err = tx.QueryRowContext(ctx, query, interview.ApplicationID, interview.OrganizerID, interview.StartAt, interview.EndAt,
+		interview.Format, interview.Location, interview.MeetingLink, interview.Notes, interview.Status,
+	).Scan(&interview.ID, &interview.Sequence, &interview.CreatedAt, &interview.UpdatedAt)
+	if err != nil {
+		logger.Log.Error("Failed to create interview", "application_id", interview.ApplicationID, "error", err)
+		return nil, err
+	}
+
+	if err := replaceInterviewers(ctx, tx, interview.ID, interviewerIDs); err != nil {
+		return nil, err
+	}
+	return nil, tx.Commit()
+}
+
+// Update сохраняет изменения интервью и увеличивает sequence для календарей.
+// При переносе времени напоминание отправится заново. Интервью, которое остаётся запланированным,
+// проверяется на пересечения так же, как в Create; interviewerIDs == nil оставляет текущих интервьюеров
+func (r *InterviewRepository) Update(ctx context.Context, interview *entity.Interview, interviewerIDs []int, rescheduled bool) ([]entity.InterviewConflict, error) {
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if interview.Status == entity.InterviewStatusScheduled {
+		checkIDs := interviewerIDs
+		if checkIDs == nil {
+			if checkIDs, err = currentInterviewerIDs(ctx, tx, interview.ID); err != nil {
+				return nil, err
+			}
+		}
+		if conflicts, err := lockAndFindConflicts(ctx, tx, checkIDs, interview); len(conflicts) > 0 || err != nil {
+			return conflicts, err
+		}
+	}
+
+	query := `
+        UPDATE interviews
+        SET start_at = $1, end_at = $2, format = $3, location = $4, meeting_link = $5, notes = $6, status = $7,
+            sequence = sequence + 1, updated_at = NOW(),
+            reminder_sent_at = CASE WHEN $8 THEN NULL ELSE reminder_sent_at END
+        WHERE id = $9
+        RETURNING sequence, updated_at, reminder_sent_at
+    `
+	err = tx.QueryRowContext(ctx, query, interview.StartAt, interview.EndAt, interview.Format, interview.Location,
+		interview.MeetingLink, interview.Notes, interview.Status, rescheduled, interview.ID,
+	).Scan(&interview.Sequence, &interview.UpdatedAt, &interview.ReminderSentAt)
+	if err != nil {
+		logger.Log.Error("Failed to update interview", "interview_id", interview.ID, "error", err)
+		return nil, err
+	}
+
+	if interviewerIDs != nil {
+		if err := replaceInterviewers(ctx, tx, interview.ID, interviewerIDs); err != nil {
+			return nil, err
+		}
+	}
+	return nil, tx.Commit()
+}
+
+// lockAndFindConflicts берёт транзакционные блокировки интервьюеров и ищет пересечения с интервью.
+// Блокировки берутся в порядке возрастания ID, чтобы параллельные транзакции не взаимоблокировались
+func lockAndFindConflicts(ctx context.Context, tx *sql.Tx, interviewerIDs []int, interview *entity.Interview) ([]entity.InterviewConflict, error) {
+	ids := append([]int(nil), interviewerIDs...)
+	sort.Ints(ids)
+	for _, id := range ids {
+		if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock(hashtext('interview_interviewers'), $1)`, id); err != nil {
+			logger.Log.Error("Failed to lock interviewer", "user_id", id, "error", err)
+			return nil, err
+		}
+	}
+	return findConflicts(ctx, tx, ids, interview.StartAt, interview.EndAt, interview.ID)
+}
+
+func currentInterviewerIDs(ctx context.Context, tx *sql.Tx, interviewID int) ([]int, error) {
+	rows, err := tx.QueryContext(ctx, `SELECT user_id FROM interview_interviewers WHERE interview_id = $1`, interviewID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := []int{}
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+func replaceInterviewers(ctx context.Context, tx *sql.Tx, interviewID int, interviewerIDs []int) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM interview_interviewers WHERE interview_id = $1`, interviewID); err != nil {
+		logger.Log.Error("Failed to clear interviewers", "interview_id", interviewID, "error", err)
+		return err
+	}
+	for _, userID := range interviewerIDs {
+		if _, err := tx.ExecContext(ctx, `INSERT INTO interview_interviewers (interview_id, user_id) VALUES ($1, $2)`, interviewID, userID); err != nil {
+			logger.Log.Error("Failed to add interviewer", "interview_id", interviewID, "user_id", userID, "error", err)
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *InterviewRepository) GetByID(ctx context.Context, id int) (*entity.Interview, error) {
+	query := `SELECT ` + interviewColumns + ` FROM interviews i WHERE i.id = $1`
+
+	var interview entity.Interview
+	if err := scanInterview(r.DB.QueryRowContext(ctx, query, id), &interview); err != nil {
+		if err != sql.ErrNoRows {
+			logger.Log.Error("Failed to get interview", "interview_id", id, "error", err)
+		}
+		return nil, err
+	}
+
+	interviewers, err := r.getInterviewers(ctx, []int{id})
+	if err != nil {
+		return nil, err
+	}
+	interview.Interviewers = interviewers[id]
+	return &interview, nil
+}
+
+func (r *InterviewRepository) List(ctx context.Context, filter InterviewFilter) ([]entity.Interview, error) {
+	query := `
+        SELECT ` + interviewColumns + `
+        FROM interviews i
+        JOIN job_applications ja ON ja.id = i.application_id
+        JOIN vacancies v ON v.id = ja.vacancy_id
+        WHERE 1=1
+    `
+	var conditions []string
+	var args []interface{}
+	add := func(condition string, value interface{}) {
+		args = append(args, value)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+
+	if filter.ApplicationID > 0 {
+		add("i.application_id = $%d", filter.ApplicationID)
+	}
+	if filter.CompanyID > 0 {
+		add("v.company_id = $%d", filter.CompanyID)
+	}
+	if filter.ParticipantID > 0 {
+		add("(ja.user_id = $%[1]d OR EXISTS (SELECT 1 FROM interview_interviewers ii WHERE ii.interview_id = i.id AND ii.user_id = $%[1]d))", filter.ParticipantID)
+	}
+	if !filter.From.IsZero() {
+		add("i.end_at >= $%d", filter.From)
+	}
+	if !filter.To.IsZero() {
+		add("i.start_at <= $%d", filter.To)
+	}
+	for _, condition := range conditions {
+		query += " AND " + condition
+	}
+	query += " ORDER BY i.start_at"
+
+	rows, err := r.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		logger.Log.Error("Failed to list interviews", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	interviews := []entity.Interview{}
+	var ids []int
+	for rows.Next() {
+		var interview entity.Interview
+		if err := scanInterview(rows, &interview); err != nil {
+			logger.Log.Error("Failed to scan interview", "error", err)
+			return nil, err
+		}
+		interviews = append(interviews, interview)
+		ids = append(ids, interview.ID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	interviewers, err := r.getInterviewers(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	for i := range interviews {
+		interviews[i].Interviewers = interviewers[interviews[i].ID]
+	}
+	return interviews, nil
+}
+
+func (r *InterviewRepository) getInterviewers(ctx context.Context, interviewIDs []int) (map[int][]entity.UserResponse, error) {
+	result := make(map[int][]entity.UserResponse, len(interviewIDs))
+	if len(interviewIDs) == 0 {
+		return result, nil
+	}
+
+	query := `
+        SELECT ii.interview_id, u.id, u.email, u.first_name, u.last_name, COALESCE(u.profile_picture, '')
+        FROM interview_interviewers ii
+        JOIN users u ON u.id = ii.user_id
+        WHERE ii.interview_id = ANY($1)
+        ORDER BY u.id
+    `
+	rows, err := r.DB.QueryContext(ctx, query, pq.Array(interviewIDs))
+	if err != nil {
+		logger.Log.Error("Failed to get interviewers", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var interviewID int
+		var user entity.UserResponse
+		if err := rows.Scan(&interviewID, &user.ID, &user.Email, &user.FirstName, &user.LastName, &user.ProfilePicture); err != nil {
+			return nil, err
+		}
+		result[interviewID] = append(result[interviewID], user)
+	}
+	return result, rows.Err()
+}
+
+// findConflicts ищет запланированные интервью тех же интервьюеров, пересекающиеся с [start, end)
+func findConflicts(ctx context.Context, tx *sql.Tx, interviewerIDs []int, start, end time.Time, excludeID int) ([]entity.InterviewConflict, error) {
+	query := `
+        SELECT ii.user_id, i.id, i.start_at, i.end_at
+        FROM interviews i
+        JOIN interview_interviewers ii ON ii.interview_id = i.id
+        WHERE ii.user_id = ANY($1)
+          AND i.status = 'scheduled'
+          AND i.id <> $2
+          AND i.start_at < $4
+          AND i.end_at > $3
+        ORDER BY i.start_at
+    `
+	rows, err := tx.QueryContext(ctx, query, pq.Array(interviewerIDs), excludeID, start, end)
+	if err != nil {
+		logger.Log.Error("Failed to find interview conflicts", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var conflicts []entity.InterviewConflict
+	for rows.Next() {
+		var conflict entity.InterviewConflict
+		if err := rows.Scan(&conflict.InterviewerID, &conflict.InterviewID, &conflict.StartAt, &conflict.EndAt); err != nil {
+			return nil, err
+		}
+		conflicts = append(conflicts, conflict)
+	}
+	return conflicts, rows.Err()
+}
+
+// FilterCompanyMembers оставляет из userIDs только HR и владельца компании
+func (r *InterviewRepository) FilterCompanyMembers(ctx context.Context, companyID int, userIDs []int) ([]int, error) {
+	query := `
+        SELECT u.id FROM users u
+        WHERE u.id = ANY($1)
+          AND (EXISTS (SELECT 1 FROM hr WHERE hr.user_id = u.id AND hr.company_id = $2)
+               OR EXISTS (SELECT 1 FROM companies c WHERE c.id = $2 AND c.owner_id = u.id))
+    `
+	rows, err := r.DB.QueryContext(ctx, query, pq.Array(userIDs), companyID)
+	if err != nil {
+		logger.Log.Error("Failed to check interviewers company", "company_id", companyID, "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var members []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		members = append(members, id)
+	}
+	return members, rows.Err()
+}
+
+// ClaimDueReminders помечает напоминание отправленным для интервью, начинающихся до before, и возвращает их ID.
+// Пометка атомарна, поэтому при нескольких инстансах каждое напоминание уходит один раз.
+func (r *InterviewRepository) ClaimDueReminders(ctx context.Context, before time.Time) ([]int, error) {
+	query := `
+        UPDATE interviews
+        SET reminder_sent_at = NOW()
+        WHERE status = 'scheduled' AND reminder_sent_at IS NULL AND start_at > NOW() AND start_at <= $1
+        RETURNING id
+    `
+	rows, err := r.DB.QueryContext(ctx, query, before)
+	if err != nil {
+		logger.Log.Error("Failed to claim interview reminders", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
@@ -3,8 +3,19 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
+	"errors"
 	"jumyste-app-backend/internal/entity"
 	"jumyste-app-backend/pkg/logger"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+var (
+	ErrDuplicateApplication    = errors.New("active application for this vacancy already exists")
+	ErrDuplicateIdempotencyKey = errors.New("idempotency key already used")
+	ErrAIScoringInProgress     = errors.New("application is being scored")
 )
 
 type JobApplicationRepository struct {
@@ -15,13 +26,26 @@ func NewJobApplicationRepository(db *sql.DB) *JobApplicationRepository {
 	return &JobApplicationRepository{DB: db}
 }
 
+// CreateJobApplication сохраняет отклик и первую запись в истории статусов в одной транзакции.
+// Возвращает ErrDuplicateApplication, если у соискателя уже есть активный отклик на вакансию.
 func (r *JobApplicationRepository) CreateJobApplication(ctx context.Context, application *entity.JobApplication) error {
+	ruleBreakdown, err := encodeRuleBreakdown(application.RuleBreakdown)
+	if err != nil {
+		return err
+	}
+
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
 	query := `
-        INSERT INTO job_applications (user_id, vacancy_id, first_name, last_name, email, status, resume_id, ai_matching_score, ai_strengths, ai_weaknesses)
-        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+        INSERT INTO job_applications (user_id, vacancy_id, first_name, last_name, email, status, resume_id, ai_matching_score, ai_strengths, ai_weaknesses, ai_status, rule_score, rule_breakdown, stage_id, idempotency_key)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)
         RETURNING id, applied_at
     `
-	err := r.DB.QueryRowContext(ctx, query,
+	err = tx.QueryRowContext(ctx, query,
 		application.UserID,
 		application.VacancyID,
 		application.FirstName,
@@ -30,15 +54,43 @@ func (r *JobApplicationRepository) CreateJobApplication(ctx context.Context, app
 		application.Status,
 		application.ResumeID,
 		application.AIMatchingScore,
-		application.AIStrengths,
-		application.AIWeaknesses,
+		pq.Array(application.AIStrengths),
+		pq.Array(application.AIWeaknesses),
+		application.AIStatus,
+		application.RuleScore,
+		ruleBreakdown,
+		application.StageID,
+		application.IdempotencyKey,
 	).Scan(&application.ID, &application.AppliedAt)
-	return err
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+		// Уникальные индексы защищают от гонки параллельных откликов
+		switch pqErr.Constraint {
+		case "uq_job_applications_active":
+			return ErrDuplicateApplication
+		case "uq_job_applications_idempotency_key":
+			return ErrDuplicateIdempotencyKey
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	historyQuery := `
+        INSERT INTO job_application_status_history (application_id, from_status, to_status, to_stage_id, actor_id, created_at)
+        VALUES ($1, NULL, $2, $3, $4, $5)
+    `
+	if _, err := tx.ExecContext(ctx, historyQuery, application.ID, application.Status, application.StageID, application.UserID, application.AppliedAt); err != nil {
+		logger.Log.Error("Failed to save initial status history", "application_id", application.ID, "error", err)
+		return err
+	}
+
+	return tx.Commit()
 }
 
 func (r *JobApplicationRepository) GetJobApplicationsByVacancyID(ctx context.Context, vacancyID int) ([]entity.JobApplication, error) {
 	query := `
-        SELECT id, user_id, vacancy_id, first_name, last_name, email, status, applied_at, resume_id, ai_matching_score, ai_strengths, ai_weaknesses
+        SELECT id, user_id, vacancy_id, first_name, last_name, email, status, applied_at, resume_id, ai_matching_score, ai_strengths, ai_weaknesses, ai_breakdown, ai_status, ai_attempts, ai_error, rule_score, rule_breakdown, stage_id
         FROM job_applications
         WHERE vacancy_id = $1
     `
@@ -52,27 +104,152 @@ func (r *JobApplicationRepository) GetJobApplicationsByVacancyID(ctx context.Con
 	var applications []entity.JobApplication
 	for rows.Next() {
 		var application entity.JobApplication
-		if err := rows.Scan(&application.ID, &application.UserID, &application.VacancyID, &application.FirstName, &application.LastName, &application.Email, &application.Status, &application.AppliedAt, &application.ResumeID, &application.AIMatchingScore, &application.AIStrengths, &application.AIWeaknesses); err != nil {
+		var breakdown, ruleBreakdown []byte
+		if err := rows.Scan(&application.ID, &application.UserID, &application.VacancyID, &application.FirstName, &application.LastName, &application.Email, &application.Status, &application.AppliedAt, &application.ResumeID, &application.AIMatchingScore, pq.Array(&application.AIStrengths), pq.Array(&application.AIWeaknesses), &breakdown, &application.AIStatus, &application.AIAttempts, &application.AIError, &application.RuleScore, &ruleBreakdown, &application.StageID); err != nil {
 			logger.Log.Error("Failed to scan job application", "error", err)
 			return nil, err
 		}
+		if application.AIBreakdown, err = decodeAIBreakdown(breakdown); err != nil {
+			return nil, err
+		}
+		if application.RuleBreakdown, err = decodeRuleBreakdown(ruleBreakdown); err != nil {
+			return nil, err
+		}
 		applications = append(applications, application)
 	}
 	return applications, nil
 }
 
-func (r *JobApplicationRepository) UpdateJobApplicationStatus(ctx context.Context, applicationID int, status string) error {
-	query := `
-        UPDATE job_applications
-        SET status = $1
-        WHERE id = $2
-    `
-	_, err := r.DB.ExecContext(ctx, query, status, applicationID)
+// ChangeJobApplicationStatus переводит отклик из статуса/этапа from в to и пишет историю.
+// Возвращает sql.ErrNoRows, если статус или этап отклика уже не равны from (изменены параллельно).
+func (r *JobApplicationRepository) ChangeJobApplicationStatus(ctx context.Context, applicationID int, from, to string, fromStageID, toStageID *int, actorID int, comment *string) error {
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, `
+        UPDATE job_applications SET status = $1, stage_id = $2
+        WHERE id = $3 AND status = $4 AND stage_id IS NOT DISTINCT FROM $5
+    `, to, toStageID, applicationID, from, fromStageID)
 	if err != nil {
 		logger.Log.Error("Failed to update job application status", "error", err)
 		return err
 	}
-	return nil
+	if affected, _ := res.RowsAffected(); affected == 0 {
+		return sql.ErrNoRows
+	}
+
+	historyQuery := `
+        INSERT INTO job_application_status_history (application_id, from_status, to_status, from_stage_id, to_stage_id, actor_id, comment)
+        VALUES ($1, $2, $3, $4, $5, $6, $7)
+    `
+	if _, err := tx.ExecContext(ctx, historyQuery, applicationID, from, to, fromStageID, toStageID, actorID, comment); err != nil {
+		logger.Log.Error("Failed to save status history", "application_id", applicationID, "error", err)
+		return err
+	}
+
+	return tx.Commit()
+}
+
+const statusHistoryQuery = `
+        SELECT h.id, h.application_id, h.from_status, h.to_status,
+               h.from_stage_id, fs.name, h.to_stage_id, ts.name, h.actor_id,
+               CASE WHEN u.id IS NULL THEN NULL ELSE TRIM(u.first_name || ' ' || u.last_name) END,
+               u.role_id, h.comment, h.created_at
+        FROM job_application_status_history h
+        LEFT JOIN users u ON u.id = h.actor_id
+        LEFT JOIN hiring_stages fs ON fs.id = h.from_stage_id
+        LEFT JOIN hiring_stages ts ON ts.id = h.to_stage_id
+    `
+
+func (r *JobApplicationRepository) GetStatusHistory(ctx context.Context, applicationID int) ([]entity.JobApplicationStatusChange, error) {
+	rows, err := r.DB.QueryContext(ctx, statusHistoryQuery+` WHERE h.application_id = $1 ORDER BY h.created_at, h.id`, applicationID)
+	if err != nil {
+		logger.Log.Error("Failed to get status history", "application_id", applicationID, "error", err)
+		return nil, err
+	}
+	return scanStatusHistory(rows)
+}
+
+// GetStatusHistoryByApplicationIDs возвращает истории нескольких откликов одним запросом
+func (r *JobApplicationRepository) GetStatusHistoryByApplicationIDs(ctx context.Context, applicationIDs []int) ([]entity.JobApplicationStatusChange, error) {
+	if len(applicationIDs) == 0 {
+		return []entity.JobApplicationStatusChange{}, nil
+	}
+	rows, err := r.DB.QueryContext(ctx, statusHistoryQuery+` WHERE h.application_id = ANY($1) ORDER BY h.created_at, h.id`, pq.Array(applicationIDs))
+	if err != nil {
+		logger.Log.Error("Failed to get status history", "error", err)
+		return nil, err
+	}
+	return scanStatusHistory(rows)
+}
+
+func scanStatusHistory(rows *sql.Rows) ([]entity.JobApplicationStatusChange, error) {
+	defer rows.Close()
+
+	history := []entity.JobApplicationStatusChange{}
+	for rows.Next() {
+		var change entity.JobApplicationStatusChange
+		if err := rows.Scan(&change.ID, &change.ApplicationID, &change.FromStatus, &change.ToStatus,
+			&change.FromStageID, &change.FromStage, &change.ToStageID, &change.ToStage, &change.ActorID,
+			&change.ActorName, &change.ActorRoleID, &change.Comment, &change.CreatedAt); err != nil {
+			logger.Log.Error("Failed to scan status history", "error", err)
+			return nil, err
+		}
+		history = append(history, change)
+	}
+	return history, rows.Err()
+}
+
+// HasApplicationToCompany проверяет, откликался ли соискатель на вакансии компании
+func (r *JobApplicationRepository) HasApplicationToCompany(ctx context.Context, userID, companyID int) (bool, error) {
+	var exists bool
+	err := r.DB.QueryRowContext(ctx, `
+        SELECT EXISTS (SELECT 1
+                       FROM job_applications ja
+                       JOIN vacancies v ON v.id = ja.vacancy_id
+                       WHERE ja.user_id = $1 AND v.company_id = $2)
+    `, userID, companyID).Scan(&exists)
+	if err != nil {
+		logger.Log.Error("Failed to check candidate applications", "user_id", userID, "company_id", companyID, "error", err)
+		return false, err
+	}
+	return exists, nil
+}
+
+// GetJobApplicationsByUserID возвращает отклики соискателя с вакансией, компанией и текущим этапом, новые первыми
+func (r *JobApplicationRepository) GetJobApplicationsByUserID(ctx context.Context, userID int) ([]entity.CandidateApplication, error) {
+	query := `
+        SELECT ja.id, ja.vacancy_id, v.title, v.company_id, c.name, ja.status, ja.stage_id, hs.name, ja.applied_at
+        FROM job_applications ja
+        JOIN vacancies v ON v.id = ja.vacancy_id
+        LEFT JOIN companies c ON c.id = v.company_id
+        LEFT JOIN hiring_stages hs ON hs.id = ja.stage_id
+        WHERE ja.user_id = $1
+        ORDER BY ja.applied_at DESC, ja.id DESC
+    `
+	rows, err := r.DB.QueryContext(ctx, query, userID)
+	if err != nil {
+		logger.Log.Error("Failed to get candidate applications", "user_id", userID, "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	applications := []entity.CandidateApplication{}
+	for rows.Next() {
+		var app entity.CandidateApplication
+		var companyName sql.NullString
+		if err := rows.Scan(&app.ID, &app.VacancyID, &app.VacancyTitle, &app.CompanyID, &companyName,
+			&app.Status, &app.StageID, &app.Stage, &app.AppliedAt); err != nil {
+			logger.Log.Error("Failed to scan candidate application", "error", err)
+			return nil, err
+		}
+		app.CompanyName = companyName.String
+		applications = append(applications, app)
+	}
+	return applications, rows.Err()
 }
 
 func (r *JobApplicationRepository) DeleteJobApplication(ctx context.Context, applicationID int) error {
@@ -85,16 +262,19 @@ func (r *JobApplicationRepository) DeleteJobApplication(ctx context.Context, app
 	return nil
 }
 
-func (r *JobApplicationRepository) GetJobAppAnalytics(ctx context.Context, hrID int) ([]entity.ApplicationStatusStat, error) {
+// GetJobAppAnalytics группирует отклики на вакансии HR по этапам воронки; vacancyID == 0 — по всем вакансиям
+func (r *JobApplicationRepository) GetJobAppAnalytics(ctx context.Context, hrID, vacancyID int) ([]entity.ApplicationStatusStat, error) {
 	query := `
-		SELECT ja.status, COUNT(*) as count
+		SELECT COALESCE(hs.name, ''), COALESCE(MIN(hs.position), 0), ja.status, COUNT(*) as count
 		FROM job_applications ja
 		JOIN vacancies v ON ja.vacancy_id = v.id
-		WHERE v.created_by = $1
-		GROUP BY ja.status;
+		LEFT JOIN hiring_stages hs ON hs.id = ja.stage_id
+		WHERE v.created_by = $1 AND ($2 = 0 OR v.id = $2)
+		GROUP BY hs.name, ja.status
+		ORDER BY 2, 1;
 	`
 
-	rows, err := r.DB.QueryContext(ctx, query, hrID)
+	rows, err := r.DB.QueryContext(ctx, query, hrID, vacancyID)
 	if err != nil {
 		logger.Log.Error("Failed to get job application status stats", "error", err)
 		return nil, err
@@ -104,7 +284,7 @@ func (r *JobApplicationRepository) GetJobAppAnalytics(ctx context.Context, hrID
 	var stats []entity.ApplicationStatusStat
 	for rows.Next() {
 		var stat entity.ApplicationStatusStat
-		if err := rows.Scan(&stat.Status, &stat.Count); err != nil {
+		if err := rows.Scan(&stat.Stage, &stat.Position, &stat.Status, &stat.Count); err != nil {
 			return nil, err
 		}
 		stats = append(stats, stat)
@@ -118,13 +298,46 @@ func (r *JobApplicationRepository) GetJobAppAnalytics(ctx context.Context, hrID
 }
 
 func (r *JobApplicationRepository) GetJobApplicationByID(ctx context.Context, applicationID int) (*entity.JobApplication, error) {
+	app, err := r.getJobApplication(ctx, `WHERE id = $1`, applicationID)
+	if err != nil {
+		logger.Log.Error("Failed to get job application by ID", "error", err)
+		return nil, err
+	}
+	return app, nil
+}
+
+// GetJobApplicationByIdempotencyKey ищет отклик, созданный соискателем с данным Idempotency-Key
+func (r *JobApplicationRepository) GetJobApplicationByIdempotencyKey(ctx context.Context, userID int, key string) (*entity.JobApplication, error) {
+	return r.getJobApplication(ctx, `WHERE user_id = $1 AND idempotency_key = $2`, userID, key)
+}
+
+// GetLatestJobApplication возвращает последний отклик соискателя на вакансию и время последней смены его статуса
+func (r *JobApplicationRepository) GetLatestJobApplication(ctx context.Context, userID, vacancyID int) (*entity.JobApplication, time.Time, error) {
+	app, err := r.getJobApplication(ctx, `WHERE user_id = $1 AND vacancy_id = $2 ORDER BY applied_at DESC, id DESC LIMIT 1`, userID, vacancyID)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	var changedAt time.Time
+	err = r.DB.QueryRowContext(ctx, `
+        SELECT COALESCE(MAX(created_at), $2)
+        FROM job_application_status_history
+        WHERE application_id = $1
+    `, app.ID, app.AppliedAt).Scan(&changedAt)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	return app, changedAt, nil
+}
+
+func (r *JobApplicationRepository) getJobApplication(ctx context.Context, where string, args ...interface{}) (*entity.JobApplication, error) {
 	query := `
-        SELECT id, user_id, vacancy_id, first_name, last_name, email, status, applied_at, resume_id, ai_matching_score, ai_strengths, ai_weaknesses
+        SELECT id, user_id, vacancy_id, first_name, last_name, email, status, applied_at, resume_id, ai_matching_score, ai_strengths, ai_weaknesses, ai_breakdown, ai_status, ai_attempts, ai_error, rule_score, rule_breakdown, stage_id
         FROM job_applications
-        WHERE id = $1
-    `
+    ` + where
 	var app entity.JobApplication
-	err := r.DB.QueryRowContext(ctx, query, applicationID).Scan(
+	var breakdown, ruleBreakdown []byte
+	err := r.DB.QueryRowContext(ctx, query, args...).Scan(
 		&app.ID,
 		&app.UserID,
 		&app.VacancyID,
@@ -135,12 +348,210 @@ func (r *JobApplicationRepository) GetJobApplicationByID(ctx context.Context, ap
 		&app.AppliedAt,
 		&app.ResumeID,
 		&app.AIMatchingScore,
-		&app.AIStrengths,
-		&app.AIWeaknesses,
+		pq.Array(&app.AIStrengths),
+		pq.Array(&app.AIWeaknesses),
+		&breakdown,
+		&app.AIStatus,
+		&app.AIAttempts,
+		&app.AIError,
+		&app.RuleScore,
+		&ruleBreakdown,
+		&app.StageID,
 	)
 	if err != nil {
-		logger.Log.Error("Failed to get job application by ID", "error", err)
+		return nil, err
+	}
+	if app.AIBreakdown, err = decodeAIBreakdown(breakdown); err != nil {
+		return nil, err
+	}
+	if app.RuleBreakdown, err = decodeRuleBreakdown(ruleBreakdown); err != nil {
 		return nil, err
 	}
 	return &app, nil
 }
+
+// ClaimAIScoring переводит отклик из pending в processing; false — если его уже взял другой воркер
+func (r *JobApplicationRepository) ClaimAIScoring(ctx context.Context, applicationID int) (bool, error) {
+	query := `
+        UPDATE job_applications
+        SET ai_status = 'processing', ai_updated_at = NOW()
+        WHERE id = $1 AND ai_status = 'pending'
+    `
+	res, err := r.DB.ExecContext(ctx, query, applicationID)
+	if err != nil {
+		logger.Log.Error("Failed to claim job application for AI scoring", "application_id", applicationID, "error", err)
+		return false, err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected == 1, nil
+}
+
+func (r *JobApplicationRepository) SaveAIResult(ctx context.Context, applicationID, attempts, score int, strengths, weaknesses []string, breakdown entity.AIScoreBreakdown) error {
+	breakdownJSON, err := json.Marshal(breakdown)
+	if err != nil {
+		return err
+	}
+
+	query := `
+        UPDATE job_applications
+        SET ai_matching_score = $1, ai_strengths = $2, ai_weaknesses = $3, ai_breakdown = $4,
+            ai_status = 'done', ai_attempts = $5, ai_error = NULL, ai_updated_at = NOW()
+        WHERE id = $6
+    `
+	_, err = r.DB.ExecContext(ctx, query, score, pq.Array(strengths), pq.Array(weaknesses), breakdownJSON, attempts, applicationID)
+	if err != nil {
+		logger.Log.Error("Failed to save AI result", "application_id", applicationID, "error", err)
+		return err
+	}
+	return nil
+}
+
+func (r *JobApplicationRepository) SaveRuleScore(ctx context.Context, applicationID, score int, breakdown *entity.RuleScoreBreakdown) error {
+	breakdownJSON, err := encodeRuleBreakdown(breakdown)
+	if err != nil {
+		return err
+	}
+
+	query := `UPDATE job_applications SET rule_score = $1, rule_breakdown = $2 WHERE id = $3`
+	_, err = r.DB.ExecContext(ctx, query, score, breakdownJSON, applicationID)
+	if err != nil {
+		logger.Log.Error("Failed to save rule score", "application_id", applicationID, "error", err)
+		return err
+	}
+	return nil
+}
+
+func (r *JobApplicationRepository) MarkAIFailed(ctx context.Context, applicationID, attempts int, reason string) error {
+	query := `
+        UPDATE job_applications
+        SET ai_status = 'failed', ai_attempts = $1, ai_error = $2, ai_updated_at = NOW()
+        WHERE id = $3
+    `
+	_, err := r.DB.ExecContext(ctx, query, attempts, reason, applicationID)
+	if err != nil {
+		logger.Log.Error("Failed to mark AI scoring as failed", "application_id", applicationID, "error", err)
+		return err
+	}
+	return nil
+}
+
+func (r *JobApplicationRepository) ResetAIStatus(ctx context.Context, applicationID int) error {
+	query := `
+        UPDATE job_applications
+        SET ai_status = 'pending', ai_attempts = 0, ai_error = NULL, ai_updated_at = NOW()
+        WHERE id = $1 AND ai_status <> 'processing'
+    `
+	res, err := r.DB.ExecContext(ctx, query, applicationID)
+	if err != nil {
+		logger.Log.Error("Failed to reset AI status", "application_id", applicationID, "error", err)
+		return err
+	}
+	if affected, _ := res.RowsAffected(); affected > 0 {
+		return nil
+	}
+
+	// Отклик, взятый воркером в работу, не сбрасываем: иначе он будет оценён дважды
+	var exists bool
+	if err := r.DB.QueryRowContext(ctx, `SELECT EXISTS(SELECT 1 FROM job_applications WHERE id = $1)`, applicationID).Scan(&exists); err != nil {
+		return err
+	}
+	if !exists {
+		return sql.ErrNoRows
+	}
+	return ErrAIScoringInProgress
+}
+
+func (r *JobApplicationRepository) ResetAIStatusByVacancyID(ctx context.Context, vacancyID int) ([]int, error) {
+	query := `
+        UPDATE job_applications
+        SET ai_status = 'pending', ai_attempts = 0, ai_error = NULL, ai_updated_at = NOW()
+        WHERE vacancy_id = $1 AND ai_status <> 'processing'
+        RETURNING id
+    `
+	rows, err := r.DB.QueryContext(ctx, query, vacancyID)
+	if err != nil {
+		logger.Log.Error("Failed to reset AI status for vacancy", "vacancy_id", vacancyID, "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// ReleaseStaleAIScoring возвращает в pending отклики, застрявшие в processing (например, после рестарта)
+func (r *JobApplicationRepository) ReleaseStaleAIScoring(ctx context.Context, olderThan time.Time) error {
+	query := `
+        UPDATE job_applications
+        SET ai_status = 'pending', ai_updated_at = NOW()
+        WHERE ai_status = 'processing' AND ai_updated_at < $1
+    `
+	_, err := r.DB.ExecContext(ctx, query, olderThan)
+	if err != nil {
+		logger.Log.Error("Failed to release stale AI scoring", "error", err)
+		return err
+	}
+	return nil
+}
+
+func (r *JobApplicationRepository) GetPendingAIApplicationIDs(ctx context.Context) ([]int, error) {
+	query := `SELECT id FROM job_applications WHERE ai_status = 'pending' ORDER BY applied_at`
+	rows, err := r.DB.QueryContext(ctx, query)
+	if err != nil {
+		logger.Log.Error("Failed to get pending AI applications", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// decodeAIBreakdown разбирает JSONB-колонку ai_breakdown; NULL — оценка ещё не посчитана
+func decodeAIBreakdown(data []byte) (*entity.AIScoreBreakdown, error) {
+	if len(data) == 0 {
+		return nil, nil
+	}
+	var breakdown entity.AIScoreBreakdown
+	if err := json.Unmarshal(data, &breakdown); err != nil {
+		logger.Log.Error("Failed to decode AI breakdown", "error", err)
+		return nil, err
+	}
+	return &breakdown, nil
+}
+
+func encodeRuleBreakdown(breakdown *entity.RuleScoreBreakdown) ([]byte, error) {
+	if breakdown == nil {
+		return nil, nil
+	}
+	return json.Marshal(breakdown)
+}
+
+func decodeRuleBreakdown(data []byte) (*entity.RuleScoreBreakdown, error) {
+	if len(data) == 0 {
+		return nil, nil
+	}
+	var breakdown entity.RuleScoreBreakdown
+	if err := json.Unmarshal(data, &breakdown); err != nil {
+		logger.Log.Error("Failed to decode rule breakdown", "error", err)
+		return nil, err
+	}
+	return &breakdown, nil
+}
@@ -2,9 +2,12 @@ package repository
 
 import (
 	"database/sql"
+	"fmt"
+	"github.com/lib/pq"
 	"jumyste-app-backend/internal/entity"
 	"jumyste-app-backend/pkg/logger"
 	"log/slog"
+	"strings"
 	"time"
 )
 
@@ -16,9 +19,54 @@ func NewMessageRepository(db *sql.DB) *MessageRepository {
 	return &MessageRepository{DB: db}
 }
 
+// messageColumns — колонки сообщения в порядке scanMessage
+const messageColumns = `id, chat_id, sender_id, type, content, file_url, created_at,
+	attachment_key, attachment_name, attachment_mime, attachment_size, thumbnail_key,
+	reply_to_id, edited_at, deleted_at, updated_at`
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanMessage(row rowScanner) (entity.Message, error) {
+	var message entity.Message
+	var key, name, mimeType, thumbnailKey sql.NullString
+	var size sql.NullInt64
+	var replyToID sql.NullInt64
+	var editedAt, deletedAt, updatedAt sql.NullTime
+	err := row.Scan(&message.ID, &message.ChatID, &message.SenderID, &message.Type, &message.Content, &message.FileURL, &message.CreatedAt,
+		&key, &name, &mimeType, &size, &thumbnailKey,
+		&replyToID, &editedAt, &deletedAt, &updatedAt)
+	if err != nil {
+		return message, err
+	}
+
+	if replyToID.Valid {
+		id := int(replyToID.Int64)
+		message.ReplyToID = &id
+	}
+	if editedAt.Valid {
+		message.EditedAt = &editedAt.Time
+	}
+	message.IsDeleted = deletedAt.Valid
+	message.UpdatedAt = message.CreatedAt
+	if updatedAt.Valid {
+		message.UpdatedAt = updatedAt.Time
+	}
+
+	if key.Valid {
+		message.Attachment = &entity.Attachment{Key: key.String, Name: name.String, MimeType: mimeType.String, Size: size.Int64}
+		if thumbnailKey.Valid {
+			message.Attachment.ThumbnailKey = &thumbnailKey.String
+		}
+	}
+	return message, nil
+}
+
 func (r *MessageRepository) CreateMessage(message *entity.Message) (int, error) {
-	query := `INSERT INTO messages (chat_id, sender_id, type, content, file_url, read_by, created_at)
-	          VALUES ($1, $2, $3, $4, $5, '{}', $6) RETURNING id`
+	query := `INSERT INTO messages (chat_id, sender_id, type, content, file_url, created_at, updated_at,
+	                                attachment_key, attachment_name, attachment_mime, attachment_size, thumbnail_key, reply_to_id)
+	          VALUES ($1, $2, $3, $4, $5, $6, $6, $7, $8, $9, $10, $11, $12) RETURNING id`
 
 	var messageID int
 
@@ -32,60 +80,265 @@ func (r *MessageRepository) CreateMessage(message *entity.Message) (int, error)
 		fileURL = sql.NullString{String: *message.FileURL, Valid: true}
 	}
 
-	err := r.DB.QueryRow(query, message.ChatID, message.SenderID, message.Type, content, fileURL, time.Now()).Scan(&messageID)
+	var key, name, mimeType, thumbnailKey sql.NullString
+	var size sql.NullInt64
+	if att := message.Attachment; att != nil {
+		key = sql.NullString{String: att.Key, Valid: true}
+		name = sql.NullString{String: att.Name, Valid: true}
+		mimeType = sql.NullString{String: att.MimeType, Valid: true}
+		size = sql.NullInt64{Int64: att.Size, Valid: true}
+		if att.ThumbnailKey != nil {
+			thumbnailKey = sql.NullString{String: *att.ThumbnailKey, Valid: true}
+		}
+	}
+
+	var replyToID sql.NullInt64
+	if message.ReplyToID != nil {
+		replyToID = sql.NullInt64{Int64: int64(*message.ReplyToID), Valid: true}
+	}
+
+	now := time.Now()
+	err := r.DB.QueryRow(query, message.ChatID, message.SenderID, message.Type, content, fileURL, now,
+		key, name, mimeType, size, thumbnailKey, replyToID).Scan(&messageID)
 	if err != nil {
 		return 0, err
 	}
 
 	message.ID = messageID
+	message.CreatedAt = now
+	message.UpdatedAt = now
 	return messageID, nil
 }
 
-// GetMessagesByChatID - Fetches all messages in a chat
-func (r *MessageRepository) GetMessagesByChatID(chatID int) ([]entity.Message, error) {
-	query := `SELECT id, chat_id, sender_id, type, content, file_url, created_at 
-	          FROM messages WHERE chat_id = $1 ORDER BY created_at`
-	rows, err := r.DB.Query(query, chatID)
+// GetMessagesPage - Fetches up to limit messages of a chat, except those the user deleted for themselves, older than beforeID or newer than afterID
+// (the latest ones without a cursor). Messages are returned in ascending id order; hasMore tells
+// whether the chat has more messages in the requested direction.
+func (r *MessageRepository) GetMessagesPage(chatID, userID, beforeID, afterID, limit int) ([]entity.Message, bool, error) {
+	query := `SELECT ` + messageColumns + `
+	          FROM messages m
+	          WHERE chat_id = $1
+	            AND NOT EXISTS (SELECT 1 FROM message_hidden h WHERE h.message_id = m.id AND h.user_id = $2)`
+	args := []interface{}{chatID, userID}
+
+	descending := afterID == 0
+	switch {
+	case afterID > 0:
+		query += ` AND id > $3 ORDER BY id ASC LIMIT $4`
+		args = append(args, afterID)
+	case beforeID > 0:
+		query += ` AND id < $3 ORDER BY id DESC LIMIT $4`
+		args = append(args, beforeID)
+	default:
+		query += ` ORDER BY id DESC LIMIT $3`
+	}
+	// Лишняя строка показывает, есть ли следующая страница
+	args = append(args, limit+1)
+
+	rows, err := r.DB.Query(query, args...)
 	if err != nil {
-		return nil, err
+		return nil, false, err
 	}
 	defer rows.Close()
 
-	var messages []entity.Message
+	messages := []entity.Message{}
 	for rows.Next() {
-		var message entity.Message
-		err := rows.Scan(&message.ID, &message.ChatID, &message.SenderID, &message.Type, &message.Content, &message.FileURL, &message.CreatedAt)
+		message, err := scanMessage(rows)
 		if err != nil {
-			return nil, err
+			return nil, false, err
 		}
 		messages = append(messages, message)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, false, err
+	}
+
+	hasMore := len(messages) > limit
+	if hasMore {
+		messages = messages[:limit]
+	}
+	if descending {
+		for i, j := 0, len(messages)-1; i < j; i, j = i+1, j-1 {
+			messages[i], messages[j] = messages[j], messages[i]
+		}
+	}
+
+	return messages, hasMore, nil
+}
+
+// SearchMessages - Full-text search over messages of the user's chats, newest first.
+// The snippet is built from HTML-escaped content, so only the <mark> tags are markup.
+func (r *MessageRepository) SearchMessages(userID int, text string, chatID, beforeID, limit int) ([]entity.MessageSearchResult, bool, error) {
+	query := `SELECT ` + prefixColumns("m.", messageColumns) + `,
+	                 ts_headline('russian',
+	                     replace(replace(replace(concat_ws(' ', m.content, m.attachment_name), '&', '&amp;'), '<', '&lt;'), '>', '&gt;'),
+	                     q, 'StartSel=<mark>, StopSel=</mark>, MaxWords=20, MinWords=8, MaxFragments=2')
+	          FROM messages m
+	          JOIN chat_users cu ON cu.chat_id = m.chat_id AND cu.user_id = $1,
+	               plainto_tsquery('russian', $2) q
+	          WHERE m.search_vector @@ q
+	            AND m.deleted_at IS NULL
+	            AND NOT EXISTS (SELECT 1 FROM message_hidden h WHERE h.message_id = m.id AND h.user_id = $1)`
+	args := []interface{}{userID, text}
+	argIndex := 3
+
+	if chatID > 0 {
+		query += fmt.Sprintf(" AND m.chat_id = $%d", argIndex)
+		args = append(args, chatID)
+		argIndex++
+	}
+	if beforeID > 0 {
+		query += fmt.Sprintf(" AND m.id < $%d", argIndex)
+		args = append(args, beforeID)
+		argIndex++
+	}
+	query += fmt.Sprintf(" ORDER BY m.id DESC LIMIT $%d", argIndex)
+	args = append(args, limit+1)
+
+	rows, err := r.DB.Query(query, args...)
+	if err != nil {
+		return nil, false, err
+	}
+	defer rows.Close()
+
+	results := []entity.MessageSearchResult{}
+	for rows.Next() {
+		var result entity.MessageSearchResult
+		message, err := scanMessage(snippetScanner{row: rows, snippet: &result.Snippet})
+		if err != nil {
+			return nil, false, err
+		}
+		result.Message = message
+		results = append(results, result)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, false, err
+	}
+
+	hasMore := len(results) > limit
+	if hasMore {
+		results = results[:limit]
+	}
+	return results, hasMore, nil
+}
 
-	return messages, nil
+// snippetScanner дочитывает фрагмент поиска после колонок сообщения
+type snippetScanner struct {
+	row     rowScanner
+	snippet *string
+}
+
+func (s snippetScanner) Scan(dest ...interface{}) error {
+	return s.row.Scan(append(dest, s.snippet)...)
+}
+
+// prefixColumns добавляет псевдоним таблицы к списку колонок
+func prefixColumns(prefix, columns string) string {
+	parts := strings.Split(columns, ",")
+	for i, part := range parts {
+		parts[i] = prefix + strings.TrimSpace(part)
+	}
+	return strings.Join(parts, ", ")
 }
 
 // GetMessageByID - Retrieves a single message by ID
 func (r *MessageRepository) GetMessageByID(messageID int) (*entity.Message, error) {
-	query := `SELECT id, chat_id, sender_id, type, content, file_url, created_at 
+	query := `SELECT ` + messageColumns + `
 	          FROM messages WHERE id = $1`
-	row := r.DB.QueryRow(query, messageID)
 
-	var message entity.Message
-	err := row.Scan(&message.ID, &message.ChatID, &message.SenderID, &message.Type, &message.Content, &message.FileURL, &message.CreatedAt)
+	message, err := scanMessage(r.DB.QueryRow(query, messageID))
+	if err != nil {
+		return nil, err
+	}
+
+	return &message, nil
+}
+
+// GetMessagesByIDs - Fetches messages by IDs, e.g. the parents of replies
+func (r *MessageRepository) GetMessagesByIDs(messageIDs []int) ([]entity.Message, error) {
+	query := `SELECT ` + messageColumns + `
+	          FROM messages WHERE id = ANY($1)`
+	rows, err := r.DB.Query(query, pq.Array(messageIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var messages []entity.Message
+	for rows.Next() {
+		message, err := scanMessage(rows)
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, message)
+	}
+	return messages, rows.Err()
+}
+
+// UpdateMessageContent - Saves the previous text to the edit history and replaces it
+func (r *MessageRepository) UpdateMessageContent(messageID int, content string) (*entity.Message, error) {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`
+		INSERT INTO message_edits (message_id, content, edited_at)
+		SELECT id, content, now() FROM messages WHERE id = $1 AND deleted_at IS NULL`, messageID)
 	if err != nil {
 		return nil, err
 	}
 
+	query := `UPDATE messages SET content = $2, edited_at = now(), updated_at = now()
+	          WHERE id = $1 AND deleted_at IS NULL
+	          RETURNING ` + messageColumns
+	message, err := scanMessage(tx.QueryRow(query, messageID, content))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
 	return &message, nil
 }
 
+// GetMessageEdits - Returns previous versions of a message, oldest first
+func (r *MessageRepository) GetMessageEdits(messageID int) ([]entity.MessageEdit, error) {
+	rows, err := r.DB.Query(`SELECT id, message_id, content, edited_at FROM message_edits WHERE message_id = $1 ORDER BY id`, messageID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	edits := []entity.MessageEdit{}
+	for rows.Next() {
+		var edit entity.MessageEdit
+		if err := rows.Scan(&edit.ID, &edit.MessageID, &edit.Content, &edit.EditedAt); err != nil {
+			return nil, err
+		}
+		edits = append(edits, edit)
+	}
+	return edits, rows.Err()
+}
+
+// SoftDeleteMessage - Marks a message as deleted for everyone; the row stays for the history
+func (r *MessageRepository) SoftDeleteMessage(messageID int) error {
+	_, err := r.DB.Exec(`UPDATE messages SET deleted_at = now(), updated_at = now() WHERE id = $1 AND deleted_at IS NULL`, messageID)
+	return err
+}
+
+// HideMessage - Deletes a message for one user only
+func (r *MessageRepository) HideMessage(messageID, userID int) error {
+	_, err := r.DB.Exec(`INSERT INTO message_hidden (message_id, user_id) VALUES ($1, $2) ON CONFLICT DO NOTHING`, messageID, userID)
+	return err
+}
+
+// MarkMessageAsRead - Marks a single message as seen by the user
 func (r *MessageRepository) MarkMessageAsRead(messageID, userID int) error {
-	query := `
-		UPDATE messages 
-		SET read_by = array_append(read_by, $1) 
-		WHERE id = $2 AND NOT ($1 = ANY(read_by))`
+	query := `INSERT INTO message_seen_by (message_id, user_id) VALUES ($1, $2) ON CONFLICT DO NOTHING`
 
-	_, err := r.DB.Exec(query, userID, messageID)
+	_, err := r.DB.Exec(query, messageID, userID)
 	if err != nil {
 		logger.Log.Error("Failed to mark message as read", slog.Int("message_id", messageID), slog.Int("user_id", userID), slog.String("error", err.Error()))
 		return err
@@ -93,3 +346,76 @@ func (r *MessageRepository) MarkMessageAsRead(messageID, userID int) error {
 
 	return nil
 }
+
+// MarkChatReadUpTo - Marks every message of the chat up to and including upToID as seen by the user.
+// The user's own messages are not marked: they never count as unread.
+func (r *MessageRepository) MarkChatReadUpTo(chatID, userID, upToID int) (int64, error) {
+	query := `
+		INSERT INTO message_seen_by (message_id, user_id)
+		SELECT id, $2 FROM messages
+		WHERE chat_id = $1 AND id <= $3 AND sender_id <> $2
+		ON CONFLICT DO NOTHING`
+
+	result, err := r.DB.Exec(query, chatID, userID, upToID)
+	if err != nil {
+		logger.Log.Error("Failed to mark chat as read", slog.Int("chat_id", chatID), slog.Int("user_id", userID), slog.String("error", err.Error()))
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// GetReadersByMessageIDs - Returns who has seen each of the messages
+func (r *MessageRepository) GetReadersByMessageIDs(messageIDs []int) (map[int]pq.Int64Array, error) {
+	query := `SELECT message_id, array_agg(user_id ORDER BY seen_at)
+	          FROM message_seen_by WHERE message_id = ANY($1)
+	          GROUP BY message_id`
+	rows, err := r.DB.Query(query, pq.Array(messageIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	readers := make(map[int]pq.Int64Array, len(messageIDs))
+	for rows.Next() {
+		var messageID int
+		var userIDs pq.Int64Array
+		if err := rows.Scan(&messageID, &userIDs); err != nil {
+			return nil, err
+		}
+		readers[messageID] = userIDs
+	}
+	return readers, rows.Err()
+}
+
+// unreadCondition — сообщение чужое, не удалено, не скрыто пользователем и им не просмотрено;
+// $1 — ID пользователя, m — псевдоним messages
+const unreadCondition = `m.sender_id <> $1
+	AND m.deleted_at IS NULL
+	AND NOT EXISTS (SELECT 1 FROM message_seen_by s WHERE s.message_id = m.id AND s.user_id = $1)
+	AND NOT EXISTS (SELECT 1 FROM message_hidden h WHERE h.message_id = m.id AND h.user_id = $1)`
+
+// CountUnread - Total unread messages of the user and the number of chats that have them
+func (r *MessageRepository) CountUnread(userID int) (int, int, error) {
+	query := `
+		SELECT count(*), count(DISTINCT m.chat_id)
+		FROM messages m
+		JOIN chat_users cu ON cu.chat_id = m.chat_id AND cu.user_id = $1
+		WHERE ` + unreadCondition
+
+	var total, chats int
+	if err := r.DB.QueryRow(query, userID).Scan(&total, &chats); err != nil {
+		return 0, 0, err
+	}
+	return total, chats, nil
+}
+
+// CountUnreadInChat - Unread messages of the user in one chat
+func (r *MessageRepository) CountUnreadInChat(chatID, userID int) (int, error) {
+	query := `SELECT count(*) FROM messages m WHERE m.chat_id = $2 AND ` + unreadCondition
+
+	var count int
+	if err := r.DB.QueryRow(query, userID, chatID).Scan(&count); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
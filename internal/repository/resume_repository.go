@@ -9,6 +9,7 @@ import (
 	"github.com/lib/pq"
 	"jumyste-app-backend/internal/dto"
 	"jumyste-app-backend/internal/entity"
+	"jumyste-app-backend/internal/matching"
 	"jumyste-app-backend/pkg/logger"
 )
 
@@ -260,17 +261,18 @@ func (r *ResumeRepository) GetByID(ctx context.Context, resumeId int) (*entity.R
 func (r *ResumeRepository) FilterCandidates(ctx context.Context, filter dto.CandidateFilter) ([]entity.JobApplicationWithResume, error) {
 	query := `
 		SELECT
-			ja.id, ja.user_id, ja.vacancy_id, ja.first_name, ja.last_name, ja.email, ja.status, ja.applied_at, ja.resume_id, ja.ai_matching_score,
+			ja.id, ja.user_id, ja.vacancy_id, ja.first_name, ja.last_name, ja.email, ja.status, ja.applied_at, ja.resume_id, ja.ai_matching_score, ja.rule_score,
 			r.id, r.full_name, r.desired_position, r.skills, r.city, r.about, r.parsed_data, r.created_at,
 			u.id, u.email, u.first_name, u.last_name, u.profile_picture, u.role_id
 		FROM job_applications ja
 		JOIN resume r ON ja.resume_id = r.id
 		JOIN users u ON r.user_id = u.id
-		WHERE 1=1
+		JOIN vacancies v ON ja.vacancy_id = v.id
+		WHERE v.company_id = $1
 	`
 
-	args := []interface{}{}
-	argID := 1
+	args := []interface{}{filter.CompanyID}
+	argID := 2
 
 	if filter.AIMatchMin > 0 {
 		query += fmt.Sprintf(" AND ja.ai_matching_score >= $%d", argID)
@@ -278,9 +280,22 @@ func (r *ResumeRepository) FilterCandidates(ctx context.Context, filter dto.Cand
 		argID++
 	}
 
+	if filter.RuleMin > 0 {
+		query += fmt.Sprintf(" AND ja.rule_score >= $%d", argID)
+		args = append(args, filter.RuleMin)
+		argID++
+	}
+
+	if filter.VacancyID > 0 {
+		query += fmt.Sprintf(" AND ja.vacancy_id = $%d", argID)
+		args = append(args, filter.VacancyID)
+		argID++
+	}
+
 	if len(filter.Skills) > 0 {
-		query += fmt.Sprintf(" AND r.skills && $%d", argID) // PostgreSQL array intersection
-		args = append(args, pq.Array(filter.Skills))
+		// Сравниваем без учёта регистра со всеми синонимами навыка: "JS" найдёт и "JavaScript"
+		query += fmt.Sprintf(" AND EXISTS (SELECT 1 FROM unnest(r.skills) AS s WHERE lower(s) = ANY($%d))", argID)
+		args = append(args, pq.Array(matching.ExpandSkills(filter.Skills)))
 		argID++
 	}
 
@@ -296,6 +311,8 @@ func (r *ResumeRepository) FilterCandidates(ctx context.Context, filter dto.Cand
 		argID++
 	}
 
+	query += " ORDER BY ja.rule_score DESC NULLS LAST, ja.applied_at DESC"
+
 	rows, err := r.DB.QueryContext(ctx, query, args...)
 	if err != nil {
 		logger.Log.Error("Failed to filter candidates", "error", err)
@@ -314,7 +331,7 @@ func (r *ResumeRepository) FilterCandidates(ctx context.Context, filter dto.Cand
 		var parsedData []byte
 
 		err := rows.Scan(
-			&app.ID, &app.UserID, &app.VacancyID, &app.FirstName, &app.LastName, &app.Email, &app.Status, &app.AppliedAt, &app.ResumeID, &app.AIMatchingScore,
+			&app.ID, &app.UserID, &app.VacancyID, &app.FirstName, &app.LastName, &app.Email, &app.Status, &app.AppliedAt, &app.ResumeID, &app.AIMatchingScore, &app.RuleScore,
 			&resume.ID, &resume.FullName, &resume.DesiredPosition, pq.Array(&skills), &resume.City, &resume.About, &parsedData, &resume.CreatedAt,
 			&user.ID, &user.Email, &user.FirstName, &user.LastName, &user.ProfilePicture, &user.RoleId,
 		)
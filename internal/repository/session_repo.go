@@ -0,0 +1,194 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/redis/go-redis/v9"
+	"jumyste-app-backend/internal/entity"
+	"strconv"
+	"time"
+)
+
+var (
+	ErrSessionNotFound = errors.New("session not found")
+	ErrTokenReused     = errors.New("refresh token has already been used")
+)
+
+// SessionRepository хранит сессии в Redis: хеш session:<id> с метаданными и jti текущего
+// refresh-токена и множество user_sessions:<userID> с идентификаторами сессий пользователя
+type SessionRepository struct {
+	redis *redis.Client
+}
+
+func NewSessionRepository(redis *redis.Client) *SessionRepository {
+	return &SessionRepository{redis: redis}
+}
+
+func sessionKey(sessionID string) string {
+	return "session:" + sessionID
+}
+
+func userSessionsKey(userID int) string {
+	return fmt.Sprintf("user_sessions:%d", userID)
+}
+
+// rotateScript атомарно меняет jti сессии, если предъявлен текущий токен:
+// 1 — токен ротирован, 0 — предъявлен уже использованный токен, -1 — сессии нет
+var rotateScript = redis.NewScript(`
+local current = redis.call('HGET', KEYS[1], 'jti')
+if not current then
+	return -1
+end
+if current ~= ARGV[1] then
+	return 0
+end
+redis.call('HSET', KEYS[1], 'jti', ARGV[2], 'ip', ARGV[3], 'user_agent', ARGV[4], 'last_used_at', ARGV[5], 'expires_at', ARGV[6])
+redis.call('PEXPIRE', KEYS[1], ARGV[7])
+return 1
+`)
+
+// CreateSession - Сохраняет новую сессию на время жизни refresh-токена
+func (r *SessionRepository) CreateSession(ctx context.Context, session *entity.Session) error {
+	ttl := time.Until(session.ExpiresAt)
+	_, err := r.redis.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, sessionKey(session.ID), map[string]interface{}{
+			"user_id":      session.UserID,
+			"jti":          session.TokenID,
+			"ip":           session.IP,
+			"user_agent":   session.UserAgent,
+			"created_at":   session.CreatedAt.Unix(),
+			"last_used_at": session.LastUsedAt.Unix(),
+			"expires_at":   session.ExpiresAt.Unix(),
+		})
+		pipe.Expire(ctx, sessionKey(session.ID), ttl)
+		pipe.SAdd(ctx, userSessionsKey(session.UserID), session.ID)
+		pipe.Expire(ctx, userSessionsKey(session.UserID), ttl)
+		return nil
+	})
+	return err
+}
+
+// RotateToken - Заменяет jti сессии, если oldTokenID совпадает с текущим, и продлевает сессию.
+// Возвращает ErrTokenReused для уже ротированного токена и ErrSessionNotFound для отозванной сессии.
+func (r *SessionRepository) RotateToken(ctx context.Context, session *entity.Session, oldTokenID string) error {
+	result, err := rotateScript.Run(ctx, r.redis, []string{sessionKey(session.ID)},
+		oldTokenID, session.TokenID, session.IP, session.UserAgent,
+		session.LastUsedAt.Unix(), session.ExpiresAt.Unix(), time.Until(session.ExpiresAt).Milliseconds()).Int()
+	if err != nil {
+		return err
+	}
+	switch result {
+	case -1:
+		return ErrSessionNotFound
+	case 0:
+		return ErrTokenReused
+	}
+	return r.redis.Expire(ctx, userSessionsKey(session.UserID), time.Until(session.ExpiresAt)).Err()
+}
+
+// GetSessionsByUserID - Активные сессии пользователя; истёкшие идентификаторы вычищаются из множества
+func (r *SessionRepository) GetSessionsByUserID(ctx context.Context, userID int) ([]entity.Session, error) {
+	ids, err := r.redis.SMembers(ctx, userSessionsKey(userID)).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	cmds := make([]*redis.MapStringStringCmd, len(ids))
+	_, err = r.redis.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, id := range ids {
+			cmds[i] = pipe.HGetAll(ctx, sessionKey(id))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sessions := make([]entity.Session, 0, len(ids))
+	var stale []interface{}
+	for i, cmd := range cmds {
+		fields := cmd.Val()
+		if len(fields) == 0 {
+			stale = append(stale, ids[i])
+			continue
+		}
+		sessions = append(sessions, sessionFromHash(ids[i], fields))
+	}
+	if len(stale) > 0 {
+		r.redis.SRem(ctx, userSessionsKey(userID), stale...)
+	}
+	return sessions, nil
+}
+
+// GetSession - Сессия по идентификатору или ErrSessionNotFound
+func (r *SessionRepository) GetSession(ctx context.Context, sessionID string) (*entity.Session, error) {
+	fields, err := r.redis.HGetAll(ctx, sessionKey(sessionID)).Result()
+	if err != nil {
+		return nil, err
+	}
+	if len(fields) == 0 {
+		return nil, ErrSessionNotFound
+	}
+	session := sessionFromHash(sessionID, fields)
+	return &session, nil
+}
+
+// DeleteSession - Отзывает сессию пользователя
+func (r *SessionRepository) DeleteSession(ctx context.Context, userID int, sessionID string) (bool, error) {
+	var deleted *redis.IntCmd
+	_, err := r.redis.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		deleted = pipe.Del(ctx, sessionKey(sessionID))
+		pipe.SRem(ctx, userSessionsKey(userID), sessionID)
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+	return deleted.Val() > 0, nil
+}
+
+// DeleteSessionsByUserID - Отзывает все сессии пользователя и возвращает их число
+func (r *SessionRepository) DeleteSessionsByUserID(ctx context.Context, userID int) (int, error) {
+	ids, err := r.redis.SMembers(ctx, userSessionsKey(userID)).Result()
+	if err != nil {
+		return 0, err
+	}
+
+	keys := make([]string, 0, len(ids))
+	for _, id := range ids {
+		keys = append(keys, sessionKey(id))
+	}
+
+	var deleted *redis.IntCmd
+	_, err = r.redis.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		if len(keys) > 0 {
+			deleted = pipe.Del(ctx, keys...)
+		}
+		pipe.Del(ctx, userSessionsKey(userID))
+		return nil
+	})
+	if err != nil || deleted == nil {
+		return 0, err
+	}
+	return int(deleted.Val()), nil
+}
+
+func sessionFromHash(sessionID string, fields map[string]string) entity.Session {
+	userID, _ := strconv.Atoi(fields["user_id"])
+	return entity.Session{
+		ID:         sessionID,
+		UserID:     userID,
+		TokenID:    fields["jti"],
+		IP:         fields["ip"],
+		UserAgent:  fields["user_agent"],
+		CreatedAt:  unixField(fields["created_at"]),
+		LastUsedAt: unixField(fields["last_used_at"]),
+		ExpiresAt:  unixField(fields["expires_at"]),
+	}
+}
+
+func unixField(value string) time.Time {
+	seconds, _ := strconv.ParseInt(value, 10, 64)
+	return time.Unix(seconds, 0)
+}
@@ -0,0 +1,122 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"github.com/redis/go-redis/v9"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// tokensValidAfterTTL — сколько хранится отметка «токены действительны после»: дольше любого выданного токена
+const tokensValidAfterTTL = 8 * 24 * time.Hour
+
+// TokenRevocationStore — отозванные access-токены (denylist по jti) и момент, раньше которого
+// выданные пользователю токены недействительны
+type TokenRevocationStore interface {
+	RevokeToken(ctx context.Context, tokenID string, expiresAt time.Time) error
+	SetTokensValidAfter(ctx context.Context, userID int, at time.Time) error
+	IsRevoked(ctx context.Context, tokenID string, userID int, issuedAt time.Time) (bool, error)
+}
+
+// RedisTokenRevocations хранит revoked_token:<jti> до истечения токена и tokens_valid_after:<userID>
+type RedisTokenRevocations struct {
+	redis *redis.Client
+}
+
+func NewRedisTokenRevocations(redis *redis.Client) *RedisTokenRevocations {
+	return &RedisTokenRevocations{redis: redis}
+}
+
+func revokedTokenKey(tokenID string) string {
+	return "revoked_token:" + tokenID
+}
+
+func tokensValidAfterKey(userID int) string {
+	return fmt.Sprintf("tokens_valid_after:%d", userID)
+}
+
+func (r *RedisTokenRevocations) RevokeToken(ctx context.Context, tokenID string, expiresAt time.Time) error {
+	ttl := time.Until(expiresAt)
+	if tokenID == "" || ttl <= 0 {
+		return nil
+	}
+	return r.redis.Set(ctx, revokedTokenKey(tokenID), 1, ttl).Err()
+}
+
+func (r *RedisTokenRevocations) SetTokensValidAfter(ctx context.Context, userID int, at time.Time) error {
+	return r.redis.Set(ctx, tokensValidAfterKey(userID), at.Unix(), tokensValidAfterTTL).Err()
+}
+
+// IsRevoked проверяет denylist и отметку пользователя одним запросом
+func (r *RedisTokenRevocations) IsRevoked(ctx context.Context, tokenID string, userID int, issuedAt time.Time) (bool, error) {
+	keys := []string{tokensValidAfterKey(userID)}
+	if tokenID != "" {
+		keys = append(keys, revokedTokenKey(tokenID))
+	}
+	values, err := r.redis.MGet(ctx, keys...).Result()
+	if err != nil {
+		return false, err
+	}
+
+	if len(values) > 1 && values[1] != nil {
+		return true, nil
+	}
+	if raw, ok := values[0].(string); ok {
+		validAfter, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return false, err
+		}
+		return issuedAt.Unix() < validAfter, nil
+	}
+	return false, nil
+}
+
+// MemoryTokenRevocations — отзыв токенов в пределах одного процесса, если Redis недоступен
+type MemoryTokenRevocations struct {
+	mu         sync.Mutex
+	tokens     map[string]time.Time
+	validAfter map[int]time.Time
+}
+
+func NewMemoryTokenRevocations() *MemoryTokenRevocations {
+	return &MemoryTokenRevocations{tokens: make(map[string]time.Time), validAfter: make(map[int]time.Time)}
+}
+
+func (m *MemoryTokenRevocations) RevokeToken(ctx context.Context, tokenID string, expiresAt time.Time) error {
+	if tokenID == "" {
+		return nil
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	for id, until := range m.tokens {
+		if until.Before(now) {
+			delete(m.tokens, id)
+		}
+	}
+	m.tokens[tokenID] = expiresAt
+	return nil
+}
+
+func (m *MemoryTokenRevocations) SetTokensValidAfter(ctx context.Context, userID int, at time.Time) error {
+	m.mu.Lock()
+	m.validAfter[userID] = at.Truncate(time.Second)
+	m.mu.Unlock()
+	return nil
+}
+
+func (m *MemoryTokenRevocations) IsRevoked(ctx context.Context, tokenID string, userID int, issuedAt time.Time) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if until, ok := m.tokens[tokenID]; ok && until.After(time.Now()) {
+		return true, nil
+	}
+	if validAfter, ok := m.validAfter[userID]; ok {
+		return issuedAt.Before(validAfter), nil
+	}
+	return false, nil
+}
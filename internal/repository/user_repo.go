@@ -8,6 +8,7 @@ import (
 	"jumyste-app-backend/pkg/logger"
 	"log/slog"
 	"strings"
+	"time"
 )
 
 type UserRepository struct {
@@ -25,14 +26,47 @@ func (r *UserRepository) CreateUser(user *entity.User) error {
 }
 
 func (r *UserRepository) GetUserByID(id int) (*entity.UserResponse, error) {
-	query := `SELECT id, email, first_name, last_name, profile_picture, created_at, is_owner FROM users WHERE id = $1`
+	query := `SELECT id, email, first_name, last_name, profile_picture, created_at, is_owner, email_verified_at IS NOT NULL
+	          FROM users WHERE id = $1`
 	row := r.DB.QueryRow(query, id)
 
 	var user entity.UserResponse
-	err := row.Scan(&user.ID, &user.Email, &user.FirstName, &user.LastName, &user.ProfilePicture, &user.CreatedAt, &user.IsOwner)
+	err := row.Scan(&user.ID, &user.Email, &user.FirstName, &user.LastName, &user.ProfilePicture, &user.CreatedAt, &user.IsOwner, &user.EmailVerified)
 	return &user, err
 }
 
+// IsEmailVerified сообщает, подтвердил ли пользователь свой email
+func (r *UserRepository) IsEmailVerified(userID int) (bool, error) {
+	var verified bool
+	err := r.DB.QueryRow(`SELECT email_verified_at IS NOT NULL FROM users WHERE id = $1`, userID).Scan(&verified)
+	return verified, err
+}
+
+// UpdateLastSeen сохраняет время, когда пользователь последний раз был в сети
+func (r *UserRepository) UpdateLastSeen(userID int, at time.Time) error {
+	_, err := r.DB.Exec(`UPDATE users SET last_seen_at = $1 WHERE id = $2`, at, userID)
+	return err
+}
+
+// UpdateRole меняет роль пользователя
+func (r *UserRepository) UpdateRole(userID, roleID int) error {
+	res, err := r.DB.Exec(`UPDATE users SET role_id = $1 WHERE id = $2`, roleID, userID)
+	if err != nil {
+		return err
+	}
+	if affected, _ := res.RowsAffected(); affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// updatableUserColumns — колонки, которые можно менять через UpdateUser; ключи подставляются в SQL как имена колонок
+var updatableUserColumns = map[string]bool{
+	"first_name":      true,
+	"last_name":       true,
+	"profile_picture": true,
+}
+
 func (r *UserRepository) UpdateUser(userID int, updates map[string]interface{}) error {
 	if len(updates) == 0 {
 		return errors.New("no fields to update")
@@ -43,6 +77,9 @@ func (r *UserRepository) UpdateUser(userID int, updates map[string]interface{})
 	counter := 1
 
 	for key, value := range updates {
+		if !updatableUserColumns[key] {
+			return fmt.Errorf("field %q cannot be updated", key)
+		}
 		setClauses = append(setClauses, fmt.Sprintf("%s = $%d", key, counter))
 		params = append(params, value)
 		counter++
@@ -50,13 +50,13 @@ func (r *VacancyRepository) UpdateVacancy(v *entity.Vacancy) error {
         SET title = $1, employment_type = $2, work_format = $3, experience = $4, 
             salary_min = $5, salary_max = $6, location = $7, category = $8, 
             skills = $9, description = $10, status = $11
-        WHERE id = $11 AND created_by = $12
+        WHERE id = $12
         RETURNING created_at`
 
 	err := r.db.QueryRow(
 		query, v.Title, v.EmploymentType, v.WorkFormat, v.Experience,
 		v.SalaryMin, v.SalaryMax, v.Location, v.Category, pq.Array(v.Skills), v.Description,
-		v.ID, v.CreatedBy, v.Status,
+		v.Status, v.ID,
 	).Scan(&v.CreatedAt)
 
 	return err
@@ -20,6 +20,9 @@ func SetupRouter(
 	jobApplicationHandler *handler.JobApplicationHandler,
 	departmentHandler *handler.DepartmentsHandler,
 	companyHandler *handler.CompanyHandler,
+	hiringStageHandler *handler.HiringStageHandler,
+	interviewHandler *handler.InterviewHandler,
+	fileHandler *handler.FileHandler,
 ) *gin.Engine {
 	r := gin.Default()
 	r.Use(middleware.CORSMiddleware())
@@ -27,6 +30,9 @@ func SetupRouter(
 	// Swagger documentation route
 	//r.GET("api/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 
+	// Открытые ключи для проверки токенов другими сервисами
+	r.GET("/.well-known/jwks.json", authHandler.JWKS)
+
 	// --- Аутентификация ---
 	auth := r.Group("/api/auth")
 	{
@@ -35,7 +41,12 @@ func SetupRouter(
 		auth.POST("/login", authHandler.Login)
 		auth.POST("/forgot-password", authHandler.RequestPasswordReset)
 		auth.POST("/reset-password", authHandler.ResetPassword)
+		auth.POST("/verify-email", authHandler.VerifyEmail)
+		auth.POST("/resend-verification", authHandler.ResendVerification)
 		auth.POST("/refresh", authHandler.RefreshToken)
+		auth.POST("/logout", authMiddleware.VerifyTokenMiddleware(), authHandler.Logout)
+		auth.POST("/logout-all", authMiddleware.VerifyTokenMiddleware(), authHandler.LogoutAll)
+		auth.GET("/sessions", authMiddleware.VerifyTokenMiddleware(), authHandler.GetSessions)
 	}
 
 	// --- Пользователи ---
@@ -77,6 +88,11 @@ func SetupRouter(
 		chatRoutes.GET("/:chatID", chatHandler.GetChatByIDHandler)
 		chatRoutes.GET("/", chatHandler.GetAllChatsHandler)
 		chatRoutes.GET("/user", chatHandler.GetChatsByUserIDHandler)
+		chatRoutes.POST("/group", chatHandler.CreateGroupChatHandler)
+		chatRoutes.PATCH("/:chatID", chatHandler.UpdateChatHandler)
+		chatRoutes.POST("/:chatID/participants", chatHandler.AddParticipantsHandler)
+		chatRoutes.DELETE("/:chatID/participants/:userID", chatHandler.RemoveParticipantHandler)
+		chatRoutes.PUT("/:chatID/participants/:userID/role", chatHandler.SetParticipantRoleHandler)
 	}
 
 	// --- Сообщения ---
@@ -85,10 +101,19 @@ func SetupRouter(
 	{
 		messageRoutes.POST("/", messageHandler.SendMessageHandler)
 		messageRoutes.GET("/chat/:chatID", messageHandler.GetMessagesByChatIDHandler)
+		messageRoutes.POST("/chat/:chatID/read", messageHandler.MarkChatReadHandler)
+		messageRoutes.GET("/search", messageHandler.SearchMessagesHandler)
+		messageRoutes.GET("/unread", messageHandler.GetUnreadBadgeHandler)
 		messageRoutes.GET("/:messageID", messageHandler.GetMessageByIDHandler)
+		messageRoutes.PATCH("/:messageID", messageHandler.EditMessageHandler)
+		messageRoutes.DELETE("/:messageID", messageHandler.DeleteMessageHandler)
+		messageRoutes.GET("/:messageID/edits", messageHandler.GetMessageEditsHandler)
 		messageRoutes.POST("/read", messageHandler.MarkAsRead)
 	}
 
+	// --- Файлы вложений (доступ по подписанной ссылке) ---
+	r.GET("/api/files/*key", fileHandler.DownloadFile)
+
 	// --- Резюме ---
 	resume := r.Group("/api/resume")
 	resume.Use(authMiddleware.VerifyTokenMiddleware())
@@ -97,7 +122,7 @@ func SetupRouter(
 		resume.POST("/manual", resumeHandler.CreateResume)
 		resume.GET("/:user_id", resumeHandler.GetResumeByUserID)
 		resume.DELETE("/", resumeHandler.DeleteResumeByUserID)
-		resume.GET("/candidates", resumeHandler.FilterCandidates)
+		resume.GET("/candidates", middleware.RequireRole(2), resumeHandler.FilterCandidates)
 		resume.POST("/ai-generate", resumeHandler.GenerateResumeDraft)
 	}
 
@@ -113,11 +138,40 @@ func SetupRouter(
 	jobApp.Use(authMiddleware.VerifyTokenMiddleware())
 	{
 		jobApp.POST("/apply/:vacancy_id", jobApplicationHandler.ApplyForJob)
-		jobApp.GET("/:vacancy_id", jobApplicationHandler.GetJobApplicationsByVacancyID)
-		jobApp.PUT("/:application_id/status/:status", jobApplicationHandler.UpdateJobApplicationStatus)
-		jobApp.DELETE("/:application_id", jobApplicationHandler.DeleteJobApplication)
+		jobApp.GET("/my", middleware.RequireRole(1), jobApplicationHandler.GetMyJobApplications)
+		jobApp.GET("/:vacancy_id", middleware.RequireRole(2), jobApplicationHandler.GetJobApplicationsByVacancyID)
+		jobApp.PUT("/:application_id/status/:status", middleware.RequireRole(2), jobApplicationHandler.UpdateJobApplicationStatus)
+		jobApp.DELETE("/:application_id", middleware.RequireRole(2), jobApplicationHandler.DeleteJobApplication)
 		jobApp.GET("/analytics", jobApplicationHandler.GetJobAppAnalytics)
 		jobApp.GET("/application/:application_id", jobApplicationHandler.GetJobApplicationByID)
+		jobApp.GET("/application/:application_id/timeline", jobApplicationHandler.GetJobApplicationTimeline)
+		jobApp.POST("/application/:application_id/withdraw", middleware.RequireRole(1), jobApplicationHandler.WithdrawJobApplication)
+		jobApp.POST("/application/:application_id/rescore", middleware.RequireRole(2), jobApplicationHandler.RescoreJobApplication)
+		jobApp.POST("/vacancy/:vacancy_id/rescore", middleware.RequireRole(2), jobApplicationHandler.RescoreVacancyApplications)
+		jobApp.PUT("/application/:application_id/stage/:stage_id", middleware.RequireRole(2), jobApplicationHandler.MoveJobApplicationToStage)
+		jobApp.GET("/vacancy/:vacancy_id/board", middleware.RequireRole(2), jobApplicationHandler.GetVacancyBoard)
+	}
+
+	// --- Этапы воронки найма (только для роли 2) ---
+	stages := r.Group("/api/stages")
+	stages.Use(authMiddleware.VerifyTokenMiddleware())
+	stages.Use(middleware.RequireRole(2))
+	{
+		stages.GET("", hiringStageHandler.GetStages)
+		stages.PUT("", hiringStageHandler.ReplaceStages)
+		stages.DELETE("/vacancy/:vacancy_id", hiringStageHandler.DeleteVacancyStages)
+	}
+
+	// --- Интервью ---
+	interviews := r.Group("/api/interviews")
+	interviews.Use(authMiddleware.VerifyTokenMiddleware())
+	{
+		interviews.POST("", middleware.RequireRole(2), interviewHandler.ScheduleInterview)
+		interviews.GET("", interviewHandler.ListInterviews)
+		interviews.GET("/:id", interviewHandler.GetInterview)
+		interviews.GET("/:id/ics", interviewHandler.DownloadInterviewICS)
+		interviews.PUT("/:id", middleware.RequireRole(2), interviewHandler.UpdateInterview)
+		interviews.DELETE("/:id", middleware.RequireRole(2), interviewHandler.CancelInterview)
 	}
 
 	departments := r.Group("/api/departments")
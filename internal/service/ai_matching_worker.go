@@ -0,0 +1,218 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"jumyste-app-backend/config"
+	"jumyste-app-backend/internal/ai"
+	"jumyste-app-backend/internal/entity"
+	"jumyste-app-backend/internal/repository"
+	"jumyste-app-backend/pkg/logger"
+	"strings"
+	"time"
+)
+
+const (
+	matchingQueueSize     = 256
+	matchingSweepInterval = time.Minute
+	matchingStaleAfter    = 10 * time.Minute
+)
+
+// AIMatchingWorker асинхронно считает AI-оценку откликов пулом воркеров.
+// Отклики сохраняются со статусом pending, воркер забирает их из очереди,
+// повторяет запрос с экспоненциальной задержкой и помечает failed после последней попытки.
+type AIMatchingWorker struct {
+	JobApplicationRepo *repository.JobApplicationRepository
+	ResumeRepo         *repository.ResumeRepository
+	VacancyRepo        *repository.VacancyRepository
+	AIClient           ai.Provider
+
+	workers     int
+	maxAttempts int
+	backoff     time.Duration
+	queue       chan int
+}
+
+func NewAIMatchingWorker(
+	repo *repository.JobApplicationRepository,
+	resumeRepo *repository.ResumeRepository,
+	vacancyRepo *repository.VacancyRepository,
+	aiClient ai.Provider,
+	cfg config.AIConfig,
+) *AIMatchingWorker {
+	workers := cfg.MatchingWorkers
+	if workers <= 0 {
+		workers = 1
+	}
+	maxAttempts := cfg.MatchingMaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = 1
+	}
+
+	return &AIMatchingWorker{
+		JobApplicationRepo: repo,
+		ResumeRepo:         resumeRepo,
+		VacancyRepo:        vacancyRepo,
+		AIClient:           aiClient,
+		workers:            workers,
+		maxAttempts:        maxAttempts,
+		backoff:            time.Duration(cfg.MatchingBackoffSeconds) * time.Second,
+		queue:              make(chan int, matchingQueueSize),
+	}
+}
+
+// Start запускает воркеры и периодический обход pending-откликов, которые не попали в очередь
+func (w *AIMatchingWorker) Start(ctx context.Context) {
+	logger.Log.Info("Starting AI matching workers", "workers", w.workers, "max_attempts", w.maxAttempts)
+
+	for i := 0; i < w.workers; i++ {
+		go w.run(ctx)
+	}
+	go w.sweep(ctx)
+}
+
+// Enqueue ставит отклик в очередь; при переполнении он останется pending и будет подобран обходом
+func (w *AIMatchingWorker) Enqueue(applicationID int) {
+	select {
+	case w.queue <- applicationID:
+	default:
+		logger.Log.Warn("AI matching queue is full, application will be picked up by sweep", "application_id", applicationID)
+	}
+}
+
+func (w *AIMatchingWorker) run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case applicationID := <-w.queue:
+			w.process(ctx, applicationID)
+		}
+	}
+}
+
+func (w *AIMatchingWorker) sweep(ctx context.Context) {
+	ticker := time.NewTicker(matchingSweepInterval)
+	defer ticker.Stop()
+
+	for {
+		w.enqueuePending(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (w *AIMatchingWorker) enqueuePending(ctx context.Context) {
+	if err := w.JobApplicationRepo.ReleaseStaleAIScoring(ctx, time.Now().Add(-matchingStaleAfter)); err != nil {
+		return
+	}
+
+	ids, err := w.JobApplicationRepo.GetPendingAIApplicationIDs(ctx)
+	if err != nil {
+		return
+	}
+	for _, id := range ids {
+		w.Enqueue(id)
+	}
+}
+
+func (w *AIMatchingWorker) process(ctx context.Context, applicationID int) {
+	claimed, err := w.JobApplicationRepo.ClaimAIScoring(ctx, applicationID)
+	if err != nil || !claimed {
+		return
+	}
+
+	var lastErr error
+	for attempt := 1; attempt <= w.maxAttempts; attempt++ {
+		result, err := w.score(ctx, applicationID)
+		if err == nil {
+			if err := w.JobApplicationRepo.SaveAIResult(ctx, applicationID, attempt, result.Score, result.Strengths, result.Weaknesses, result.Breakdown); err != nil {
+				lastErr = err
+				break
+			}
+			logger.Log.Info("AI matching completed", "application_id", applicationID, "score", result.Score, "attempt", attempt)
+			return
+		}
+
+		lastErr = err
+		logger.Log.Warn("AI matching attempt failed", "application_id", applicationID, "attempt", attempt, "error", err)
+
+		if attempt == w.maxAttempts {
+			break
+		}
+
+		delay := w.backoff * time.Duration(1<<(attempt-1))
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+	}
+
+	logger.Log.Error("AI matching failed", "application_id", applicationID, "attempts", w.maxAttempts, "error", lastErr)
+	_ = w.JobApplicationRepo.MarkAIFailed(ctx, applicationID, w.maxAttempts, lastErr.Error())
+}
+
+func (w *AIMatchingWorker) score(ctx context.Context, applicationID int) (*ai.MatchingResult, error) {
+	application, err := w.JobApplicationRepo.GetJobApplicationByID(ctx, applicationID)
+	if err != nil {
+		return nil, err
+	}
+
+	resume, err := w.ResumeRepo.GetByUserID(ctx, application.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if resume == nil {
+		return nil, errors.New("resume not found")
+	}
+
+	vacancy, err := w.VacancyRepo.GetVacancyById(application.VacancyID)
+	if err != nil {
+		return nil, err
+	}
+
+	return w.AIClient.GetMatchingScore(buildResumeText(resume), buildVacancyText(vacancy))
+}
+
+func buildResumeText(resume *entity.Resume) string {
+	return fmt.Sprintf("Имя: %s\nДолжность: %s\nНавыки: %s\nГород: %s\nО себе: %s",
+		resume.FullName,
+		resume.DesiredPosition,
+		strings.Join(resume.Skills, ", "),
+		resume.City,
+		resume.About,
+	)
+}
+
+func buildVacancyText(vacancy *entity.Vacancy) string {
+	return fmt.Sprintf("Название: %s\nТип занятости: %s\nФормат работы: %s\nНавыки: %s\nЛокация: %s\nОпыт: %s\nЗарплата: от %d до %d",
+		vacancy.Title,
+		vacancy.EmploymentType,
+		vacancy.WorkFormat,
+		strings.Join(vacancy.Skills, ", "),
+		derefString(vacancy.Location),
+		vacancy.Experience,
+		derefInt(vacancy.SalaryMin),
+		derefInt(vacancy.SalaryMax),
+	)
+}
+
+func derefString(value *string) string {
+	if value == nil {
+		return ""
+	}
+	return *value
+}
+
+func derefInt(value *int) int {
+	if value == nil {
+		return 0
+	}
+	return *value
+}
@@ -0,0 +1,274 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"jumyste-app-backend/config"
+	"jumyste-app-backend/internal/entity"
+	"jumyste-app-backend/pkg/logger"
+	"jumyste-app-backend/pkg/storage"
+	"jumyste-app-backend/pkg/thumbnail"
+	"mime/multipart"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	thumbnailSide = 320
+	sniffLength   = 512
+)
+
+var (
+	ErrUnsupportedMediaType   = errors.New("unsupported file type")
+	ErrFileTooLarge           = errors.New("file is too large")
+	ErrAttachmentTypeMismatch = errors.New("file does not match message type")
+)
+
+// mediaRule — какой тип сообщения допускает MIME, с каким расширением хранится файл и до какого размера
+type mediaRule struct {
+	kind     entity.MessageType
+	ext      string
+	maxBytes int64
+}
+
+const mb = 1 << 20
+
+var allowedMedia = map[string]mediaRule{
+	"image/jpeg": {entity.ImageMessage, ".jpg", 10 * mb},
+	"image/png":  {entity.ImageMessage, ".png", 10 * mb},
+	"image/gif":  {entity.ImageMessage, ".gif", 10 * mb},
+	"image/webp": {entity.ImageMessage, ".webp", 10 * mb},
+
+	"video/mp4":       {entity.VideoMessage, ".mp4", 100 * mb},
+	"video/webm":      {entity.VideoMessage, ".webm", 100 * mb},
+	"video/quicktime": {entity.VideoMessage, ".mov", 100 * mb},
+
+	"audio/mpeg": {entity.AudioMessage, ".mp3", 20 * mb},
+	"audio/ogg":  {entity.AudioMessage, ".ogg", 20 * mb},
+	"audio/wav":  {entity.AudioMessage, ".wav", 20 * mb},
+
+	"application/pdf":    {entity.FileMessage, ".pdf", 25 * mb},
+	"application/msword": {entity.FileMessage, ".doc", 25 * mb},
+	"application/vnd.openxmlformats-officedocument.wordprocessingml.document": {entity.FileMessage, ".docx", 25 * mb},
+	"application/vnd.ms-excel": {entity.FileMessage, ".xls", 25 * mb},
+	"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet": {entity.FileMessage, ".xlsx", 25 * mb},
+	"text/plain": {entity.FileMessage, ".txt", 25 * mb},
+}
+
+// containerTypes — сигнатуры-контейнеры, по которым формат уточняется расширением имени файла
+var containerTypes = map[string]map[string]string{
+	"application/zip": {
+		".docx": "application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+		".xlsx": "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+	},
+	"application/octet-stream": {
+		".doc": "application/msword",
+		".xls": "application/vnd.ms-excel",
+	},
+}
+
+// containerChecks проверяют, что файл, опознанный по контейнеру и расширению, действительно
+// имеет структуру этого формата: иначе под .doc прошёл бы любой двоичный файл, а под .docx — любой zip
+var containerChecks = map[string]func(head []byte, file io.ReaderAt, size int64) bool{
+	"application/msword":       isOLE2,
+	"application/vnd.ms-excel": isOLE2,
+	"application/vnd.openxmlformats-officedocument.wordprocessingml.document": ooxmlPackage("word/"),
+	"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet":       ooxmlPackage("xl/"),
+}
+
+// sniffAliases приводит результат http.DetectContentType к именам из allowedMedia
+var sniffAliases = map[string]string{
+	"audio/wave":      "audio/wav",
+	"application/ogg": "audio/ogg",
+}
+
+// AttachmentUpload — файл из multipart-запроса
+type AttachmentUpload struct {
+	File   multipart.File
+	Header *multipart.FileHeader
+}
+
+// AttachmentService проверяет и сохраняет вложения сообщений и выдаёт на них подписанные ссылки
+type AttachmentService struct {
+	Storage  storage.Storage
+	URLTTL   time.Duration
+	MaxBytes int64
+}
+
+func NewAttachmentService(store storage.Storage, cfg config.StorageConfig) *AttachmentService {
+	return &AttachmentService{
+		Storage:  store,
+		URLTTL:   time.Duration(cfg.URLTTLMinutes) * time.Minute,
+		MaxBytes: int64(cfg.MaxUploadMB) * mb,
+	}
+}
+
+// Upload определяет тип файла по содержимому, проверяет его размер и соответствие типу сообщения
+// (тип file допускает любой разрешённый формат), сохраняет файл и превью для изображений.
+// Возвращает вложение и тип сообщения, если он не был указан.
+func (s *AttachmentService) Upload(ctx context.Context, chatID int, requested entity.MessageType, upload AttachmentUpload) (*entity.Attachment, entity.MessageType, error) {
+	head := make([]byte, sniffLength)
+	n, err := io.ReadFull(upload.File, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return nil, "", err
+	}
+	if _, err := upload.File.Seek(0, io.SeekStart); err != nil {
+		return nil, "", err
+	}
+
+	mimeType := detectMIME(head[:n], upload.Header.Filename)
+	rule, ok := allowedMedia[mimeType]
+	if !ok {
+		return nil, "", fmt.Errorf("%w: %s", ErrUnsupportedMediaType, mimeType)
+	}
+	if check, ok := containerChecks[mimeType]; ok && !check(head[:n], upload.File, upload.Header.Size) {
+		return nil, "", fmt.Errorf("%w: file is not a valid %s document", ErrUnsupportedMediaType, rule.ext)
+	}
+	if upload.Header.Size > rule.maxBytes || upload.Header.Size > s.MaxBytes {
+		return nil, "", fmt.Errorf("%w: limit for %s is %d MB", ErrFileTooLarge, mimeType, min(rule.maxBytes, s.MaxBytes)/mb)
+	}
+
+	kind := requested
+	if kind == "" || kind == entity.TextMessage {
+		kind = rule.kind
+	}
+	if kind != rule.kind && kind != entity.FileMessage {
+		return nil, "", fmt.Errorf("%w: %s cannot be sent as %s", ErrAttachmentTypeMismatch, mimeType, kind)
+	}
+
+	key := fmt.Sprintf("chats/%d/%s%s", chatID, randomHex(16), rule.ext)
+	if err := s.Storage.Put(ctx, key, upload.File, upload.Header.Size, mimeType); err != nil {
+		logger.Log.Error("Failed to store attachment", "chat_id", chatID, "key", key, "error", err)
+		return nil, "", err
+	}
+
+	attachment := &entity.Attachment{
+		Key:      key,
+		Name:     filepath.Base(upload.Header.Filename),
+		MimeType: mimeType,
+		Size:     upload.Header.Size,
+	}
+	if rule.kind == entity.ImageMessage {
+		attachment.ThumbnailKey = s.storeThumbnail(ctx, key, upload.File)
+	}
+	return attachment, kind, nil
+}
+
+// storeThumbnail сохраняет превью рядом с оригиналом; без превью сообщение всё равно отправляется
+func (s *AttachmentService) storeThumbnail(ctx context.Context, key string, file multipart.File) *string {
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return nil
+	}
+	data, err := thumbnail.Make(file, thumbnailSide)
+	if err != nil {
+		logger.Log.Warn("Thumbnail was not generated", "key", key, "error", err)
+		return nil
+	}
+
+	thumbKey := strings.TrimSuffix(key, filepath.Ext(key)) + "_thumb.jpg"
+	if err := s.Storage.Put(ctx, thumbKey, bytes.NewReader(data), int64(len(data)), "image/jpeg"); err != nil {
+		logger.Log.Warn("Failed to store thumbnail", "key", thumbKey, "error", err)
+		return nil
+	}
+	return &thumbKey
+}
+
+// Delete удаляет файлы вложения, например если сообщение не удалось сохранить
+func (s *AttachmentService) Delete(ctx context.Context, attachment *entity.Attachment) {
+	keys := []string{attachment.Key}
+	if attachment.ThumbnailKey != nil {
+		keys = append(keys, *attachment.ThumbnailKey)
+	}
+	for _, key := range keys {
+		if err := s.Storage.Delete(ctx, key); err != nil {
+			logger.Log.Warn("Failed to delete attachment", "key", key, "error", err)
+		}
+	}
+}
+
+// Sign выдаёт свежие ссылки на вложения сообщений; file_url дублирует ссылку для старых клиентов
+func (s *AttachmentService) Sign(ctx context.Context, messages []entity.Message) error {
+	for i := range messages {
+		attachment := messages[i].Attachment
+		if attachment == nil {
+			continue
+		}
+
+		url, err := s.Storage.SignedURL(ctx, attachment.Key, attachment.Name, s.URLTTL)
+		if err != nil {
+			return err
+		}
+		attachment.URL = url
+		attachment.ExpiresAt = time.Now().Add(s.URLTTL)
+		messages[i].FileURL = &attachment.URL
+
+		if attachment.ThumbnailKey != nil {
+			thumbURL, err := s.Storage.SignedURL(ctx, *attachment.ThumbnailKey, "", s.URLTTL)
+			if err != nil {
+				return err
+			}
+			attachment.ThumbnailURL = &thumbURL
+		}
+	}
+	return nil
+}
+
+// detectMIME определяет тип по сигнатуре содержимого, а не по заголовку клиента
+func detectMIME(head []byte, filename string) string {
+	mimeType := http.DetectContentType(head)
+	if i := strings.Index(mimeType, ";"); i >= 0 {
+		mimeType = mimeType[:i]
+	}
+	if alias, ok := sniffAliases[mimeType]; ok {
+		mimeType = alias
+	}
+	if byExt, ok := containerTypes[mimeType]; ok {
+		if refined, ok := byExt[strings.ToLower(filepath.Ext(filename))]; ok {
+			return refined
+		}
+	}
+	return mimeType
+}
+
+// ole2Signature — начало составного документа Microsoft (doc, xls)
+var ole2Signature = []byte{0xD0, 0xCF, 0x11, 0xE0, 0xA1, 0xB1, 0x1A, 0xE1}
+
+func isOLE2(head []byte, _ io.ReaderAt, _ int64) bool {
+	return bytes.HasPrefix(head, ole2Signature)
+}
+
+// ooxmlPackage проверяет, что zip — пакет Office Open XML с частями в каталоге dir
+func ooxmlPackage(dir string) func(head []byte, file io.ReaderAt, size int64) bool {
+	return func(_ []byte, file io.ReaderAt, size int64) bool {
+		archive, err := zip.NewReader(file, size)
+		if err != nil {
+			return false
+		}
+		var contentTypes, parts bool
+		for _, f := range archive.File {
+			switch {
+			case f.Name == "[Content_Types].xml":
+				contentTypes = true
+			case strings.HasPrefix(f.Name, dir):
+				parts = true
+			}
+		}
+		return contentTypes && parts
+	}
+}
+
+func randomHex(n int) string {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return fmt.Sprintf("%x", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(buf)
+}
@@ -4,50 +4,67 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"github.com/redis/go-redis/v9"
 	"jumyste-app-backend/internal/entity"
 	"jumyste-app-backend/internal/repository"
 	"jumyste-app-backend/pkg/logger"
 	"jumyste-app-backend/pkg/mail"
 	"jumyste-app-backend/utils"
 	"log/slog"
-	"strconv"
+	"sort"
 	"time"
 )
 
 type AuthService struct {
 	repo           *repository.AuthRepository
-	redis          *redis.Client
+	sessionRepo    *repository.SessionRepository
 	invitationRepo *repository.InvitationRepository
 	hrRepo         *repository.HrRepository
+	revoker        *TokenRevoker
 }
 
-func NewAuthService(repo *repository.AuthRepository, redis *redis.Client, invitationRepo *repository.InvitationRepository, hrRepo *repository.HrRepository) *AuthService {
+func NewAuthService(repo *repository.AuthRepository, sessionRepo *repository.SessionRepository, invitationRepo *repository.InvitationRepository,
+	hrRepo *repository.HrRepository, revoker *TokenRevoker) *AuthService {
 	return &AuthService{
 		repo:           repo,
-		redis:          redis,
+		sessionRepo:    sessionRepo,
 		invitationRepo: invitationRepo,
 		hrRepo:         hrRepo,
+		revoker:        revoker,
 	}
 }
 
 var (
-	ErrInvalidResetCode = errors.New("invalid or expired reset code")
-	ErrUserNotFound     = errors.New("user not found")
+	ErrInvalidResetCode    = errors.New("invalid or expired reset code")
+	ErrUserNotFound        = errors.New("user not found")
+	ErrInvalidRefreshToken = errors.New("invalid or expired refresh token")
+	ErrRefreshTokenReused  = errors.New("refresh token reuse detected, session revoked")
 )
 
-func (s *AuthService) LoginUser(email, password string) (string, string, error) {
+// ClientInfo — устройство, с которого выполнен вход или обновление токенов
+type ClientInfo struct {
+	UserAgent string
+	IP        string
+}
+
+// TokenPair — токены сессии устройства
+type TokenPair struct {
+	AccessToken  string
+	RefreshToken string
+	SessionID    string
+}
+
+func (s *AuthService) LoginUser(email, password string, client ClientInfo) (*TokenPair, error) {
 	logger.Log.Info("Attempting user login", slog.String("email", email))
 
 	user, err := s.repo.GetUserByEmail(email)
 	if err != nil {
 		logger.Log.Warn("Login failed: user not found", slog.String("email", email), slog.String("error", err.Error()))
-		return "", "", errors.New("invalid credentials")
+		return nil, errors.New("invalid credentials")
 	}
 
 	if !utils.CheckPassword(password, user.Password) {
 		logger.Log.Warn("Login failed: incorrect password", slog.String("email", email))
-		return "", "", errors.New("invalid credentials")
+		return nil, errors.New("invalid credentials")
 	}
 
 	hr, err := s.hrRepo.GetHRByUserID(user.ID)
@@ -60,25 +77,123 @@ func (s *AuthService) LoginUser(email, password string) (string, string, error)
 		user.DepID = hr.DepID
 	}
 
-	accessToken, err := utils.GenerateJWT(user.ID, user.RoleId, user.CompanyID, user.DepID)
+	now := time.Now()
+	session := &entity.Session{
+		ID:         randomHex(16),
+		UserID:     user.ID,
+		UserAgent:  client.UserAgent,
+		IP:         client.IP,
+		CreatedAt:  now,
+		LastUsedAt: now,
+		ExpiresAt:  now.Add(utils.RefreshTokenTTL),
+		TokenID:    randomHex(16),
+	}
+
+	tokens, err := issueTokens(user.ID, user.RoleId, user.CompanyID, user.DepID, session)
 	if err != nil {
-		logger.Log.Error("Failed to generate JWT", slog.String("email", email), slog.String("error", err.Error()))
-		return "", "", err
+		logger.Log.Error("Failed to generate tokens", slog.String("email", email), slog.String("error", err.Error()))
+		return nil, err
+	}
+
+	if err := s.sessionRepo.CreateSession(context.Background(), session); err != nil {
+		logger.Log.Error("Failed to save session to Redis", slog.String("email", email), slog.String("error", err.Error()))
+		return nil, err
 	}
 
-	refreshToken, err := utils.GenerateRefreshToken(user.ID, user.RoleId, user.CompanyID, user.DepID)
+	logger.Log.Info("Session created", slog.Int("user_id", user.ID), slog.String("session_id", session.ID))
+	return tokens, nil
+}
+
+// RefreshTokens - Ротирует refresh-токен сессии и выдаёт новую пару токенов. Повторное предъявление
+// уже ротированного токена означает его утечку: сессия отзывается целиком.
+func (s *AuthService) RefreshTokens(refreshToken string, client ClientInfo) (*TokenPair, error) {
+	claims, err := utils.ValidateRefreshToken(refreshToken)
 	if err != nil {
-		logger.Log.Error("Failed to generate refresh token", slog.String("email", email), slog.String("error", err.Error()))
-		return "", "", err
+		return nil, ErrInvalidRefreshToken
+	}
+
+	ctx := context.Background()
+	now := time.Now()
+	session := &entity.Session{
+		ID:         claims.SessionID,
+		UserID:     claims.UserID,
+		UserAgent:  client.UserAgent,
+		IP:         client.IP,
+		LastUsedAt: now,
+		ExpiresAt:  now.Add(utils.RefreshTokenTTL),
+		TokenID:    randomHex(16),
 	}
 
-	err = s.SaveRefreshToken(user.ID, refreshToken)
+	err = s.sessionRepo.RotateToken(ctx, session, claims.ID)
+	if errors.Is(err, repository.ErrTokenReused) {
+		logger.Log.Warn("Refresh token reuse detected, revoking session",
+			slog.Int("user_id", claims.UserID), slog.String("session_id", claims.SessionID), slog.String("ip", client.IP))
+		if _, err := s.sessionRepo.DeleteSession(ctx, claims.UserID, claims.SessionID); err != nil {
+			logger.Log.Error("Failed to revoke session", slog.String("session_id", claims.SessionID), slog.String("error", err.Error()))
+		}
+		return nil, ErrRefreshTokenReused
+	}
+	if errors.Is(err, repository.ErrSessionNotFound) {
+		return nil, ErrInvalidRefreshToken
+	}
 	if err != nil {
-		logger.Log.Error("Failed to save refresh token to Redis", slog.String("email", email), slog.String("error", err.Error()))
-		return "", "", err
+		logger.Log.Error("Failed to rotate refresh token", slog.String("session_id", claims.SessionID), slog.String("error", err.Error()))
+		return nil, err
 	}
 
-	return accessToken, refreshToken, nil
+	return issueTokens(claims.UserID, claims.RoleID, claims.CompanyID, claims.DepartmentID, session)
+}
+
+// GetSessions - Активные сессии пользователя; currentSessionID отмечает устройство запроса
+func (s *AuthService) GetSessions(userID int, currentSessionID string) ([]entity.Session, error) {
+	sessions, err := s.sessionRepo.GetSessionsByUserID(context.Background(), userID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range sessions {
+		sessions[i].Current = sessions[i].ID == currentSessionID
+	}
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].LastUsedAt.After(sessions[j].LastUsedAt)
+	})
+	return sessions, nil
+}
+
+// Logout - Отзывает access-токен запроса и сессию его устройства; refresh-токены сессии перестают работать
+func (s *AuthService) Logout(claims *utils.Claims) error {
+	ctx := context.Background()
+	if err := s.revoker.RevokeAccessToken(ctx, claims); err != nil {
+		logger.Log.Error("Failed to revoke access token", slog.Int("user_id", claims.UserID), slog.String("error", err.Error()))
+		return err
+	}
+
+	if claims.SessionID != "" {
+		if _, err := s.sessionRepo.DeleteSession(ctx, claims.UserID, claims.SessionID); err != nil {
+			logger.Log.Error("Failed to revoke session", slog.Int("user_id", claims.UserID), slog.String("session_id", claims.SessionID), slog.String("error", err.Error()))
+			return err
+		}
+	}
+
+	logger.Log.Info("User logged out", slog.Int("user_id", claims.UserID), slog.String("session_id", claims.SessionID))
+	return nil
+}
+
+// LogoutAll - Отзывает все сессии и access-токены пользователя и возвращает число сессий
+func (s *AuthService) LogoutAll(userID int) (int, error) {
+	return s.revoker.RevokeUser(context.Background(), userID, "logout from all devices")
+}
+
+// issueTokens подписывает access-токен и refresh-токен с текущим jti сессии
+func issueTokens(userID, roleID, companyID, depID int, session *entity.Session) (*TokenPair, error) {
+	accessToken, err := utils.GenerateJWT(userID, roleID, companyID, depID, session.ID)
+	if err != nil {
+		return nil, err
+	}
+	refreshToken, err := utils.GenerateRefreshToken(userID, roleID, companyID, depID, session.ID, session.TokenID)
+	if err != nil {
+		return nil, err
+	}
+	return &TokenPair{AccessToken: accessToken, RefreshToken: refreshToken, SessionID: session.ID}, nil
 }
 
 func (s *AuthService) RequestPasswordReset(email string) error {
@@ -156,6 +271,11 @@ func (s *AuthService) ResetPassword(email, resetCode, newPassword, confirmPasswo
 
 	_ = s.repo.DeletePasswordResetCode(user.ID)
 
+	// После смены пароля все устройства входят заново
+	if _, err := s.revoker.RevokeUser(context.Background(), user.ID, "password reset"); err != nil {
+		logger.Log.Warn("Failed to revoke sessions after password reset", slog.String("email", email), slog.String("error", err.Error()))
+	}
+
 	logger.Log.Info("Password reset successfully", slog.String("email", email))
 	return nil
 }
@@ -190,6 +310,11 @@ func (s *AuthService) RegisterUser(userReq *entity.User) error {
 		return err
 	}
 
+	// Аккаунт создан; письмо можно запросить повторно, поэтому ошибка отправки не отменяет регистрацию
+	if err := s.sendVerificationCode(user); err != nil {
+		logger.Log.Warn("Verification code not sent on registration", slog.String("email", userReq.Email), slog.String("error", err.Error()))
+	}
+
 	logger.Log.Info("User registered successfully", slog.String("email", userReq.Email))
 	return nil
 }
@@ -247,37 +372,10 @@ func (s *AuthService) RegisterHR(userReq *entity.HRRegistration) error {
 		logger.Log.Warn("Failed to delete invitation", slog.String("email", userReq.Email), slog.String("error", err.Error()))
 	}
 
-	logger.Log.Info("HR registered successfully", slog.String("email", userReq.Email))
-	return nil
-}
-
-func (s *AuthService) SaveRefreshToken(userID int, refreshToken string) error {
-	err := s.redis.Set(context.Background(), s.getRefreshTokenKey(userID), refreshToken, 24*time.Hour*7).Err()
-	if err != nil {
-		return err
-	}
-	return nil
-}
-
-func (s *AuthService) GetRefreshToken(userID int) (string, error) {
-	refreshToken, err := s.redis.Get(context.Background(), s.getRefreshTokenKey(userID)).Result()
-	if errors.Is(err, redis.Nil) {
-		return "", errors.New("refresh token not found")
-	}
-	if err != nil {
-		return "", err
+	if err := s.sendVerificationCode(user); err != nil {
+		logger.Log.Warn("Verification code not sent on registration", slog.String("email", userReq.Email), slog.String("error", err.Error()))
 	}
-	return refreshToken, nil
-}
 
-func (s *AuthService) DeleteRefreshToken(userID int) error {
-	err := s.redis.Del(context.Background(), s.getRefreshTokenKey(userID)).Err()
-	if err != nil {
-		return err
-	}
+	logger.Log.Info("HR registered successfully", slog.String("email", userReq.Email))
 	return nil
 }
-
-func (s *AuthService) getRefreshTokenKey(userID int) string {
-	return "refresh_token:" + strconv.Itoa(userID)
-}
@@ -1,24 +1,64 @@
 package service
 
 import (
+	"context"
+	"database/sql"
 	"errors"
+	"fmt"
+	"jumyste-app-backend/internal/dto"
 	"jumyste-app-backend/internal/entity"
+	"jumyste-app-backend/internal/manager"
+	"jumyste-app-backend/internal/policy"
 	"jumyste-app-backend/internal/repository"
 	"jumyste-app-backend/pkg/logger"
 	"log/slog"
+	"strings"
+	"time"
 )
 
+var (
+	ErrChatNotFound          = errors.New("chat not found")
+	ErrNotGroupChat          = errors.New("operation is allowed only in group chats")
+	ErrChatAdminRequired     = errors.New("only chat admins can do this")
+	ErrParticipantNotFound   = errors.New("user is not a chat participant")
+	ErrInvalidChat           = errors.New("invalid chat")
+	ErrInvalidGroupChat      = errors.New("invalid group chat")
+	ErrLastAdmin             = errors.New("chat must keep at least one admin")
+	ErrParticipantNotAllowed = errors.New("you can only add users you already chat with or HRs of your company")
+)
+
+// PresenceReader сообщает, кто из пользователей сейчас подключён по WebSocket
+type PresenceReader interface {
+	Online(ctx context.Context, userIDs []int) (map[int]bool, error)
+}
+
 type ChatService struct {
-	ChatRepo *repository.ChatRepository
+	ChatRepo    *repository.ChatRepository
+	MessageRepo *repository.MessageRepository
+	HrRepo      *repository.HrRepository
+	UserRepo    *repository.UserRepository
+	Policy      *policy.Policy
+	Presence    PresenceReader
+	// Realtime назначается после создания менеджера WebSocket, которому сам нужен ChatService
+	Realtime RealtimePublisher
 }
 
-func NewChatService(chatRepo *repository.ChatRepository) *ChatService {
-	return &ChatService{ChatRepo: chatRepo}
+func NewChatService(chatRepo *repository.ChatRepository, messageRepo *repository.MessageRepository, hrRepo *repository.HrRepository,
+	userRepo *repository.UserRepository, accessPolicy *policy.Policy, presence PresenceReader) *ChatService {
+	return &ChatService{ChatRepo: chatRepo, MessageRepo: messageRepo, HrRepo: hrRepo, UserRepo: userRepo, Policy: accessPolicy, Presence: presence}
 }
 
-func (s *ChatService) CreateChat(userId, secondUserId int) (*entity.Chat, error) {
+// CreateChat - Личный чат с пользователем, которого автору разрешено добавлять в чаты, см. ensureContacts
+func (s *ChatService) CreateChat(sub policy.Subject, secondUserId int) (*entity.Chat, error) {
+	userId := sub.UserID
 	if userId == 0 || secondUserId == 0 {
-		return nil, errors.New("both users must be provided")
+		return nil, fmt.Errorf("%w: both users must be provided", ErrInvalidChat)
+	}
+	if err := ensureEmailVerified(s.UserRepo, userId); err != nil {
+		return nil, err
+	}
+	if err := s.ensureContacts(sub, []int{secondUserId}); err != nil {
+		return nil, err
 	}
 
 	users, err := s.ChatRepo.GetUsersByIDs([]int{userId, secondUserId})
@@ -26,7 +66,7 @@ func (s *ChatService) CreateChat(userId, secondUserId int) (*entity.Chat, error)
 		return nil, err
 	}
 	if len(users) != 2 {
-		return nil, errors.New("one or both users do not exist")
+		return nil, fmt.Errorf("%w: one or both users do not exist", ErrInvalidChat)
 	}
 
 	chat := &entity.Chat{
@@ -38,10 +78,21 @@ func (s *ChatService) CreateChat(userId, secondUserId int) (*entity.Chat, error)
 		return nil, err
 	}
 
+	joinChat(s.Realtime, chatID, userId, secondUserId)
 	chat.ID = chatID
 	return chat, nil
 }
 
+// IsChatMember - Check that the user participates in the chat
+func (s *ChatService) IsChatMember(chatID, userID int) (bool, error) {
+	return s.ChatRepo.IsChatMember(chatID, userID)
+}
+
+// GetChatIDsByUserID - IDs of all chats the user participates in
+func (s *ChatService) GetChatIDsByUserID(userID int) ([]int, error) {
+	return s.ChatRepo.GetChatIDsByUserID(userID)
+}
+
 // GetChatByID - Fetch a chat by ID
 func (s *ChatService) GetChatByID(chatID uint) (*entity.Chat, error) {
 	chat, err := s.ChatRepo.GetChatByID(chatID)
@@ -51,28 +102,68 @@ func (s *ChatService) GetChatByID(chatID uint) (*entity.Chat, error) {
 	return chat, nil
 }
 
-// GetAllChats - Fetch all chats
-func (s *ChatService) GetAllChats() ([]entity.Chat, error) {
-	chats, err := s.ChatRepo.GetAllChats()
+// GetAllChats - Все чаты пользователя; внутренние чаты HR видны только сотрудникам их компании
+func (s *ChatService) GetAllChats(sub policy.Subject) ([]entity.Chat, error) {
+	chats, err := s.GetChatsByUserID(sub.UserID, 0)
 	if err != nil {
 		return nil, err
 	}
-	return chats, nil
+
+	visible := chats[:0]
+	for _, chat := range chats {
+		if chat.IsInternal && (chat.CompanyID == nil || *chat.CompanyID != sub.CompanyID) {
+			continue
+		}
+		visible = append(visible, chat)
+	}
+	return visible, nil
 }
 
-func (s *ChatService) GetChatsByUserID(userID int) ([]entity.Chat, error) {
-	logger.Log.Info("Fetching chats for user", slog.Int("user_id", userID))
+// GetChatsByUserID - Chats of the user; vacancyID > 0 keeps only chats about that vacancy
+func (s *ChatService) GetChatsByUserID(userID, vacancyID int) ([]entity.Chat, error) {
+	logger.Log.Info("Fetching chats for user", slog.Int("user_id", userID), slog.Int("vacancy_id", vacancyID))
 
-	chats, err := s.ChatRepo.GetChatsByUserID(userID)
+	chats, err := s.ChatRepo.GetChatsByUserID(userID, vacancyID)
 	if err != nil {
 		logger.Log.Error("Failed to fetch user chats", slog.Int("user_id", userID), slog.String("error", err.Error()))
 		return nil, err
 	}
 
+	s.fillPresence(chats)
+
 	logger.Log.Info("Successfully fetched user chats", slog.Int("user_id", userID), slog.Int("chat_count", len(chats)))
 	return chats, nil
 }
 
+// fillPresence отмечает собеседников, которые сейчас в сети. Ошибка хранилища присутствия
+// не мешает отдать список чатов: поле is_online тогда просто не заполняется.
+func (s *ChatService) fillPresence(chats []entity.Chat) {
+	var userIDs []int
+	for _, chat := range chats {
+		for _, user := range chat.Users {
+			userIDs = append(userIDs, user.ID)
+		}
+	}
+	if len(userIDs) == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	online, err := s.Presence.Online(ctx, uniqueInts(userIDs))
+	if err != nil {
+		logger.Log.Warn("Failed to fetch presence", slog.String("error", err.Error()))
+		return
+	}
+
+	for i := range chats {
+		for j := range chats[i].Users {
+			isOnline := online[chats[i].Users[j].ID]
+			chats[i].Users[j].IsOnline = &isOnline
+		}
+	}
+}
+
 //func (s *ChatService) GetOrCreateChatBetweenUsers(userID1, userID2 int) (*entity.Chat, error) {
 //	if userID1 == userID2 {
 //		return nil, errors.New("cannot create chat with yourself")
@@ -88,3 +179,410 @@ func (s *ChatService) GetChatsByUserID(userID int) ([]entity.Chat, error) {
 //
 //	return s.CreateChat(userID1, userID2)
 //}
+
+// applicationChat возвращает чат отклика, создавая его при первом обращении: у каждого отклика
+// свой чат соискателя с автором вакансии, привязанный к вакансии и отклику
+func applicationChat(chatRepo *repository.ChatRepository, realtime RealtimePublisher, app *entity.JobApplication, hrID int) (int, error) {
+	chat, err := chatRepo.GetChatByApplicationID(app.ID)
+	if err != nil {
+		return 0, err
+	}
+	if chat != nil {
+		return chat.ID, nil
+	}
+
+	chatID, err := chatRepo.CreateChat(&entity.Chat{
+		Users:         []entity.UserResponse{{ID: app.UserID}, {ID: hrID}},
+		VacancyID:     &app.VacancyID,
+		ApplicationID: &app.ID,
+		CreatedAt:     time.Now(),
+		UpdatedAt:     time.Now(),
+	})
+	if errors.Is(err, repository.ErrDuplicateApplicationChat) {
+		// Чат успел создать параллельный запрос
+		chat, err = chatRepo.GetChatByApplicationID(app.ID)
+		if err != nil {
+			return 0, err
+		}
+		if chat == nil {
+			return 0, repository.ErrDuplicateApplicationChat
+		}
+		return chat.ID, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	joinChat(realtime, chatID, app.UserID, hrID)
+	return chatID, nil
+}
+
+// CreateGroupChat - Создаёт групповой чат, автор становится его администратором. Внутренний чат
+// доступен только HR компании автора и может быть привязан к отклику, который они обсуждают.
+func (s *ChatService) CreateGroupChat(ctx context.Context, sub policy.Subject, req dto.CreateGroupChatRequest) (*entity.Chat, error) {
+	title := strings.TrimSpace(req.Title)
+	if title == "" {
+		return nil, fmt.Errorf("%w: title is required", ErrInvalidGroupChat)
+	}
+	if err := ensureEmailVerified(s.UserRepo, sub.UserID); err != nil {
+		return nil, err
+	}
+
+	userIDs := uniqueInts(append([]int{sub.UserID}, req.UserIDs...))
+	if !req.Internal {
+		if err := s.ensureContacts(sub, userIDs); err != nil {
+			return nil, err
+		}
+	}
+	users, err := s.ChatRepo.GetUsersByIDs(userIDs)
+	if err != nil {
+		return nil, err
+	}
+	if len(users) != len(userIDs) {
+		return nil, fmt.Errorf("%w: one or more users do not exist", ErrInvalidGroupChat)
+	}
+	for i := range users {
+		if users[i].ID == sub.UserID {
+			users[i].ChatRole = entity.ChatRoleAdmin
+		}
+	}
+
+	chat := &entity.Chat{
+		IsGroup:   true,
+		Title:     &title,
+		CreatedBy: &sub.UserID,
+		Users:     users,
+	}
+
+	if req.Internal {
+		if !sub.IsHR() || sub.CompanyID == 0 {
+			return nil, policy.ErrForbidden
+		}
+		if err := s.ensureCompanyHRs(sub.CompanyID, userIDs); err != nil {
+			return nil, err
+		}
+		chat.IsInternal = true
+		chat.CompanyID = &sub.CompanyID
+	}
+
+	if req.ApplicationID != nil {
+		// Чат с соискателем по отклику только один, поэтому к отклику привязываются лишь внутренние обсуждения
+		if !req.Internal {
+			return nil, fmt.Errorf("%w: only internal chats can be linked to an application", ErrInvalidGroupChat)
+		}
+		app, err := s.Policy.ManageApplication(ctx, sub, *req.ApplicationID)
+		if err != nil {
+			return nil, err
+		}
+		chat.ApplicationID = &app.ID
+		chat.VacancyID = &app.VacancyID
+	}
+
+	chatID, err := s.ChatRepo.CreateChat(chat)
+	if err != nil {
+		logger.Log.Error("Failed to create group chat", slog.Int("user_id", sub.UserID), slog.String("error", err.Error()))
+		return nil, err
+	}
+
+	joinChat(s.Realtime, chatID, userIDs...)
+	s.postSystemMessage(chatID, sub.UserID, fmt.Sprintf("%s создал(а) чат «%s»", fullName(users, sub.UserID), title))
+
+	logger.Log.Info("Group chat created", slog.Int("chat_id", chatID), slog.Int("user_id", sub.UserID), slog.Bool("internal", chat.IsInternal))
+	return s.ChatRepo.GetChatByID(uint(chatID))
+}
+
+// AddParticipants - Администратор добавляет участников; уже состоящие в чате пропускаются
+func (s *ChatService) AddParticipants(ctx context.Context, sub policy.Subject, chatID int, userIDs []int) (*entity.Chat, error) {
+	chat, err := s.adminGroupChat(chatID, sub.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	userIDs = uniqueInts(userIDs)
+	if !chat.IsInternal {
+		if err := s.ensureContacts(sub, userIDs); err != nil {
+			return nil, err
+		}
+	}
+	users, err := s.ChatRepo.GetUsersByIDs(userIDs)
+	if err != nil {
+		return nil, err
+	}
+	if len(users) != len(userIDs) {
+		return nil, fmt.Errorf("%w: one or more users do not exist", ErrInvalidGroupChat)
+	}
+	if chat.IsInternal && chat.CompanyID != nil {
+		if err := s.ensureCompanyHRs(*chat.CompanyID, userIDs); err != nil {
+			return nil, err
+		}
+	}
+
+	added, err := s.ChatRepo.AddChatUsers(chatID, userIDs)
+	if err != nil {
+		logger.Log.Error("Failed to add chat participants", slog.Int("chat_id", chatID), slog.String("error", err.Error()))
+		return nil, err
+	}
+	if len(added) == 0 {
+		return chat, nil
+	}
+
+	names := make([]string, 0, len(added))
+	for _, userID := range added {
+		names = append(names, fullName(users, userID))
+	}
+	joinChat(s.Realtime, chatID, added...)
+	s.postSystemMessage(chatID, sub.UserID, fmt.Sprintf("%s добавил(а) в чат: %s", fullName(chat.Users, sub.UserID), strings.Join(names, ", ")))
+
+	logger.Log.Info("Chat participants added", slog.Int("chat_id", chatID), slog.Int("user_id", sub.UserID), slog.Int("added", len(added)))
+	return s.refreshChat(chatID)
+}
+
+// RemoveParticipant - Администратор исключает участника, любой участник может выйти сам.
+// Последний администратор не может уйти, пока в чате остаются другие участники.
+func (s *ChatService) RemoveParticipant(ctx context.Context, sub policy.Subject, chatID, userID int) error {
+	chat, role, err := s.groupChat(chatID, sub.UserID)
+	if err != nil {
+		return err
+	}
+	leaving := userID == sub.UserID
+	if !leaving && role != entity.ChatRoleAdmin {
+		return ErrChatAdminRequired
+	}
+
+	target := participant(chat.Users, userID)
+	if target == nil {
+		return ErrParticipantNotFound
+	}
+	if target.ChatRole == entity.ChatRoleAdmin && len(chat.Users) > 1 {
+		if err := s.ensureAnotherAdmin(chatID); err != nil {
+			return err
+		}
+	}
+
+	removed, err := s.ChatRepo.RemoveChatUser(chatID, userID)
+	if err != nil {
+		logger.Log.Error("Failed to remove chat participant", slog.Int("chat_id", chatID), slog.Int("user_id", userID), slog.String("error", err.Error()))
+		return err
+	}
+	if !removed {
+		return ErrParticipantNotFound
+	}
+
+	leaveChat(s.Realtime, chatID, userID)
+	if s.Realtime != nil {
+		s.Realtime.PublishToUser(userID, manager.EventChatRemoved, manager.ChatRemovedPayload{ChatID: chatID})
+	}
+
+	text := fmt.Sprintf("%s исключил(а) из чата %s", fullName(chat.Users, sub.UserID), fullName(chat.Users, userID))
+	if leaving {
+		text = fmt.Sprintf("%s покинул(а) чат", fullName(chat.Users, userID))
+	}
+	s.postSystemMessage(chatID, sub.UserID, text)
+
+	logger.Log.Info("Chat participant removed", slog.Int("chat_id", chatID), slog.Int("user_id", userID), slog.Int("by", sub.UserID))
+	return nil
+}
+
+// SetParticipantRole - Администратор назначает или снимает администраторов чата
+func (s *ChatService) SetParticipantRole(ctx context.Context, sub policy.Subject, chatID, userID int, role string) (*entity.Chat, error) {
+	if role != entity.ChatRoleAdmin && role != entity.ChatRoleMember {
+		return nil, fmt.Errorf("%w: unknown role %q", ErrInvalidGroupChat, role)
+	}
+	chat, err := s.adminGroupChat(chatID, sub.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	target := participant(chat.Users, userID)
+	if target == nil {
+		return nil, ErrParticipantNotFound
+	}
+	if target.ChatRole == role {
+		return chat, nil
+	}
+	if target.ChatRole == entity.ChatRoleAdmin {
+		if err := s.ensureAnotherAdmin(chatID); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.ChatRepo.SetChatUserRole(chatID, userID, role); err != nil {
+		logger.Log.Error("Failed to change chat role", slog.Int("chat_id", chatID), slog.Int("user_id", userID), slog.String("error", err.Error()))
+		return nil, err
+	}
+
+	text := fmt.Sprintf("%s назначил(а) администратором %s", fullName(chat.Users, sub.UserID), fullName(chat.Users, userID))
+	if role == entity.ChatRoleMember {
+		text = fmt.Sprintf("%s снял(а) права администратора с %s", fullName(chat.Users, sub.UserID), fullName(chat.Users, userID))
+	}
+	s.postSystemMessage(chatID, sub.UserID, text)
+	return s.refreshChat(chatID)
+}
+
+// UpdateGroupTitle - Администратор переименовывает групповой чат
+func (s *ChatService) UpdateGroupTitle(ctx context.Context, sub policy.Subject, chatID int, title string) (*entity.Chat, error) {
+	title = strings.TrimSpace(title)
+	if title == "" {
+		return nil, fmt.Errorf("%w: title is required", ErrInvalidGroupChat)
+	}
+	chat, err := s.adminGroupChat(chatID, sub.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if chat.Title != nil && *chat.Title == title {
+		return chat, nil
+	}
+
+	if err := s.ChatRepo.UpdateChatTitle(chatID, title); err != nil {
+		logger.Log.Error("Failed to rename chat", slog.Int("chat_id", chatID), slog.String("error", err.Error()))
+		return nil, err
+	}
+
+	s.postSystemMessage(chatID, sub.UserID, fmt.Sprintf("%s переименовал(а) чат в «%s»", fullName(chat.Users, sub.UserID), title))
+	return s.refreshChat(chatID)
+}
+
+// groupChat возвращает групповой чат и роль в нём пользователя userID
+func (s *ChatService) groupChat(chatID, userID int) (*entity.Chat, string, error) {
+	chat, err := s.ChatRepo.GetChatByID(uint(chatID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, "", ErrChatNotFound
+	}
+	if err != nil {
+		return nil, "", err
+	}
+
+	member := participant(chat.Users, userID)
+	if member == nil {
+		return nil, "", ErrNotChatMember
+	}
+	if !chat.IsGroup {
+		return nil, "", ErrNotGroupChat
+	}
+	return chat, member.ChatRole, nil
+}
+
+func (s *ChatService) adminGroupChat(chatID, userID int) (*entity.Chat, error) {
+	chat, role, err := s.groupChat(chatID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if role != entity.ChatRoleAdmin {
+		return nil, ErrChatAdminRequired
+	}
+	return chat, nil
+}
+
+// ensureAnotherAdmin не даёт лишить чат последнего администратора
+func (s *ChatService) ensureAnotherAdmin(chatID int) error {
+	admins, err := s.ChatRepo.CountChatAdmins(chatID)
+	if err != nil {
+		return err
+	}
+	if admins <= 1 {
+		return ErrLastAdmin
+	}
+	return nil
+}
+
+// ensureCompanyHRs проверяет, что все участники внутреннего чата — HR компании
+func (s *ChatService) ensureCompanyHRs(companyID int, userIDs []int) error {
+	count, err := s.HrRepo.CountCompanyHRs(companyID, userIDs)
+	if err != nil {
+		return err
+	}
+	if count != len(userIDs) {
+		return fmt.Errorf("%w: internal chats are only for HRs of the company", ErrInvalidGroupChat)
+	}
+	return nil
+}
+
+// ensureContacts разрешает добавить в личный или обычный групповой чат только тех, с кем пользователь уже состоит
+// в общем чате, а HR — ещё и HR своей компании. Иначе по произвольному ID через состав чата
+// и системное сообщение можно было бы узнать имя любого пользователя.
+func (s *ChatService) ensureContacts(sub policy.Subject, userIDs []int) error {
+	var others []int
+	for _, id := range userIDs {
+		if id != sub.UserID {
+			others = append(others, id)
+		}
+	}
+	if len(others) == 0 {
+		return nil
+	}
+
+	contacts, err := s.ChatRepo.FilterChatContacts(sub.UserID, others)
+	if err != nil {
+		return err
+	}
+	known := make(map[int]bool, len(contacts))
+	for _, id := range contacts {
+		known[id] = true
+	}
+	var strangers []int
+	for _, id := range others {
+		if !known[id] {
+			strangers = append(strangers, id)
+		}
+	}
+	if len(strangers) == 0 {
+		return nil
+	}
+
+	if sub.IsHR() && sub.CompanyID != 0 {
+		count, err := s.HrRepo.CountCompanyHRs(sub.CompanyID, strangers)
+		if err != nil {
+			return err
+		}
+		if count == len(strangers) {
+			return nil
+		}
+	}
+	return ErrParticipantNotAllowed
+}
+
+// refreshChat перечитывает чат после изменения и рассылает его участникам
+func (s *ChatService) refreshChat(chatID int) (*entity.Chat, error) {
+	chat, err := s.ChatRepo.GetChatByID(uint(chatID))
+	if err != nil {
+		return nil, err
+	}
+	if s.Realtime != nil {
+		s.Realtime.PublishToChat(chatID, manager.EventChatUpdated, chat)
+	}
+	return chat, nil
+}
+
+// postSystemMessage записывает в чат служебное сообщение; без него изменение всё равно применяется
+func (s *ChatService) postSystemMessage(chatID, actorID int, text string) {
+	message := &entity.Message{
+		ChatID:   chatID,
+		SenderID: actorID,
+		Type:     entity.SystemMessage,
+		Content:  &text,
+	}
+	if _, err := s.MessageRepo.CreateMessage(message); err != nil {
+		logger.Log.Error("Failed to post system message", slog.Int("chat_id", chatID), slog.String("error", err.Error()))
+		return
+	}
+	publishMessage(s.Realtime, message)
+}
+
+func participant(users []entity.UserResponse, userID int) *entity.UserResponse {
+	for i := range users {
+		if users[i].ID == userID {
+			return &users[i]
+		}
+	}
+	return nil
+}
+
+func fullName(users []entity.UserResponse, userID int) string {
+	if user := participant(users, userID); user != nil {
+		if name := strings.TrimSpace(user.FirstName + " " + user.LastName); name != "" {
+			return name
+		}
+		return user.Email
+	}
+	return fmt.Sprintf("Пользователь %d", userID)
+}
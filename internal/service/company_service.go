@@ -1,6 +1,7 @@
 package service
 
 import (
+	"context"
 	"errors"
 	"jumyste-app-backend/internal/entity"
 	"jumyste-app-backend/internal/repository"
@@ -9,11 +10,13 @@ import (
 )
 
 type CompanyService struct {
-	repo *repository.CompanyRepository
+	repo    *repository.CompanyRepository
+	hrRepo  *repository.HrRepository
+	revoker *TokenRevoker
 }
 
-func NewCompanyService(repo *repository.CompanyRepository) *CompanyService {
-	return &CompanyService{repo: repo}
+func NewCompanyService(repo *repository.CompanyRepository, hrRepo *repository.HrRepository, revoker *TokenRevoker) *CompanyService {
+	return &CompanyService{repo: repo, hrRepo: hrRepo, revoker: revoker}
 }
 
 func (s *CompanyService) CreateCompany(company *entity.Company) error {
@@ -59,12 +62,26 @@ func (s *CompanyService) UpdateCompany(company *entity.Company) error {
 func (s *CompanyService) DeleteCompany(id int) error {
 	logger.Log.Info("Deleting company", slog.Int("company_id", id))
 
-	err := s.repo.Delete(id)
+	// Записи HR удаляются каскадно вместе с компанией, поэтому сотрудников запоминаем заранее
+	hrUserIDs, err := s.hrRepo.GetUserIDsByCompanyID(id)
+	if err != nil {
+		logger.Log.Error("Failed to fetch company HRs", slog.Int("company_id", id), slog.String("error", err.Error()))
+		return err
+	}
+
+	err = s.repo.Delete(id)
 	if err != nil {
 		logger.Log.Error("Failed to delete company", slog.String("error", err.Error()))
 		return err
 	}
 
+	// Токены бывших сотрудников всё ещё несут company_id: отзываем их
+	for _, userID := range hrUserIDs {
+		if _, err := s.revoker.RevokeUser(context.Background(), userID, "removed from company"); err != nil {
+			logger.Log.Warn("Failed to revoke tokens of company HR", slog.Int("company_id", id), slog.Int("user_id", userID))
+		}
+	}
+
 	logger.Log.Info("Company deleted successfully", slog.Int("company_id", id))
 	return nil
 }
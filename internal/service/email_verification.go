@@ -0,0 +1,182 @@
+package service
+
+import (
+	"crypto/subtle"
+	"database/sql"
+	"errors"
+	"fmt"
+	"jumyste-app-backend/internal/entity"
+	"jumyste-app-backend/internal/repository"
+	"jumyste-app-backend/pkg/logger"
+	"jumyste-app-backend/pkg/mail"
+	"jumyste-app-backend/utils"
+	"log/slog"
+	"time"
+)
+
+const (
+	// EmailVerificationTTL — срок действия кода подтверждения
+	EmailVerificationTTL = 30 * time.Minute
+	// EmailVerificationResendInterval — как часто можно запрашивать новый код
+	EmailVerificationResendInterval = time.Minute
+	// EmailVerificationMaxAttempts — после стольких неверных вводов код перестаёт действовать
+	EmailVerificationMaxAttempts = 5
+	// EmailVerificationWindowAttempts — сколько неверных вводов допускается за EmailVerificationWindow
+	// с учётом всех выданных кодов, чтобы повторная отправка не давала новых попыток подбора
+	EmailVerificationWindowAttempts = 15
+	EmailVerificationWindow         = 24 * time.Hour
+)
+
+var (
+	ErrEmailNotVerified        = errors.New("email is not verified")
+	ErrEmailAlreadyVerified    = errors.New("email is already verified")
+	ErrInvalidVerificationCode = errors.New("invalid or expired verification code")
+	ErrVerificationThrottled   = errors.New("too many verification requests")
+)
+
+// VerificationThrottledError — запросить или ввести код подтверждения можно не раньше RetryAt
+type VerificationThrottledError struct {
+	RetryAt time.Time
+}
+
+func (e *VerificationThrottledError) Error() string {
+	return fmt.Sprintf("%s: try again after %s", ErrVerificationThrottled, e.RetryAt.Format(time.RFC3339))
+}
+
+func (e *VerificationThrottledError) Unwrap() error {
+	return ErrVerificationThrottled
+}
+
+// VerifyEmail - Подтверждает email кодом из письма; повторное подтверждение ничего не меняет
+func (s *AuthService) VerifyEmail(email, code string) error {
+	user, err := s.repo.GetUserByEmail(email)
+	if err != nil {
+		logger.Log.Warn("Email verification failed: user not found", slog.String("email", email))
+		return ErrUserNotFound
+	}
+	if user.EmailVerified {
+		return nil
+	}
+
+	// Попытка списывается до сравнения, поэтому параллельные подборы не обходят лимиты
+	now := time.Now()
+	savedCode, err := s.repo.ConsumeEmailVerificationAttempt(user.ID, now, now.Add(-EmailVerificationWindow),
+		EmailVerificationMaxAttempts, EmailVerificationWindowAttempts)
+	if errors.Is(err, sql.ErrNoRows) {
+		return s.rejectedAttempt(user.ID, email, now)
+	}
+	if err != nil {
+		logger.Log.Error("Failed to consume verification attempt", slog.String("email", email), slog.String("error", err.Error()))
+		return err
+	}
+
+	if subtle.ConstantTimeCompare([]byte(code), []byte(savedCode)) != 1 {
+		logger.Log.Warn("Incorrect verification code", slog.String("email", email))
+		return ErrInvalidVerificationCode
+	}
+
+	// Подтверждение удаляет код вместе со счётчиками попыток
+	if err := s.repo.MarkEmailVerified(user.ID); err != nil {
+		logger.Log.Error("Failed to mark email verified", slog.String("email", email), slog.String("error", err.Error()))
+		return err
+	}
+
+	logger.Log.Info("Email verified", slog.Int("user_id", user.ID))
+	return nil
+}
+
+// rejectedAttempt объясняет, почему попытка не была принята: лимит в окне исчерпан или код недействителен
+func (s *AuthService) rejectedAttempt(userID int, email string, now time.Time) error {
+	verification, err := s.repo.GetEmailVerificationCode(userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrInvalidVerificationCode
+	}
+	if err != nil {
+		logger.Log.Error("Failed to get verification code", slog.String("email", email), slog.String("error", err.Error()))
+		return err
+	}
+	if err := verificationWindowOpen(verification, now); err != nil {
+		logger.Log.Warn("Too many verification attempts", slog.String("email", email))
+		return err
+	}
+	logger.Log.Warn("Verification code expired or out of attempts", slog.String("email", email))
+	return ErrInvalidVerificationCode
+}
+
+// ResendVerification - Отправляет новый код подтверждения, не чаще раза в EmailVerificationResendInterval
+func (s *AuthService) ResendVerification(email string) error {
+	user, err := s.repo.GetUserByEmail(email)
+	if err != nil {
+		logger.Log.Warn("Verification resend failed: user not found", slog.String("email", email))
+		return ErrUserNotFound
+	}
+	if user.EmailVerified {
+		return ErrEmailAlreadyVerified
+	}
+
+	previous, err := s.repo.GetEmailVerificationCode(user.ID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		logger.Log.Error("Failed to get verification code", slog.String("email", email), slog.String("error", err.Error()))
+		return err
+	}
+	if previous != nil {
+		now := time.Now()
+		if err := verificationWindowOpen(previous, now); err != nil {
+			return err
+		}
+		retryAt := previous.CreatedAt.Add(EmailVerificationResendInterval)
+		if now.Before(retryAt) {
+			return &VerificationThrottledError{RetryAt: retryAt}
+		}
+	}
+
+	return s.sendVerificationCode(user)
+}
+
+// sendVerificationCode выдаёт пользователю новый код и отправляет его на почту; прежний код перестаёт действовать
+func (s *AuthService) sendVerificationCode(user *entity.User) error {
+	code, err := utils.GenerateVerificationCode()
+	if err != nil {
+		logger.Log.Error("Failed to generate verification code", slog.String("email", user.Email), slog.String("error", err.Error()))
+		return fmt.Errorf("failed to generate verification code")
+	}
+	now := time.Now()
+
+	if err := s.repo.SaveEmailVerificationCode(user.ID, code, now, now.Add(EmailVerificationTTL)); err != nil {
+		logger.Log.Error("Failed to save verification code", slog.String("email", user.Email), slog.String("error", err.Error()))
+		return fmt.Errorf("failed to save verification code")
+	}
+
+	subject := "Email Verification Code"
+	body := fmt.Sprintf("Your email verification code is: %s\nThe code is valid for %d minutes.", code, int(EmailVerificationTTL.Minutes()))
+
+	if err := mail.SendEmail(user.Email, subject, body); err != nil {
+		logger.Log.Error("Failed to send verification email", slog.String("email", user.Email), slog.String("error", err.Error()))
+		return fmt.Errorf("failed to send verification email")
+	}
+
+	logger.Log.Info("Verification email sent", slog.String("email", user.Email))
+	return nil
+}
+
+// verificationWindowOpen возвращает VerificationThrottledError, если лимит неверных вводов в окне исчерпан
+func verificationWindowOpen(verification *repository.EmailVerification, now time.Time) error {
+	windowEnd := verification.WindowStartedAt.Add(EmailVerificationWindow)
+	if verification.WindowAttempts >= EmailVerificationWindowAttempts && now.Before(windowEnd) {
+		return &VerificationThrottledError{RetryAt: windowEnd}
+	}
+	return nil
+}
+
+// ensureEmailVerified запрещает действие пользователю с неподтверждённым email
+func ensureEmailVerified(userRepo *repository.UserRepository, userID int) error {
+	verified, err := userRepo.IsEmailVerified(userID)
+	if err != nil {
+		logger.Log.Error("Failed to check email verification", slog.Int("user_id", userID), slog.String("error", err.Error()))
+		return err
+	}
+	if !verified {
+		return ErrEmailNotVerified
+	}
+	return nil
+}
@@ -0,0 +1,190 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"jumyste-app-backend/internal/dto"
+	"jumyste-app-backend/internal/entity"
+	"jumyste-app-backend/internal/repository"
+	"jumyste-app-backend/pkg/logger"
+	"strings"
+)
+
+var (
+	ErrStageNotFound       = errors.New("hiring stage not found")
+	ErrStageInUse          = errors.New("hiring stage has applications")
+	ErrStageStatusInUse    = errors.New("cannot change status of a hiring stage that has applications")
+	ErrStageStatusMissing  = errors.New("vacancy pipeline must have a stage for every status its applications are in")
+	ErrInvalidPipeline     = errors.New("invalid hiring pipeline")
+	ErrVacancyNotFound     = errors.New("vacancy not found")
+	ErrVacancyNotInCompany = errors.New("vacancy belongs to another company")
+)
+
+// defaultStages — воронка, которую получает компания, пока не настроила свою
+var defaultStages = []entity.HiringStage{
+	{Name: "Новый", Status: entity.ApplicationStatusNew},
+	{Name: "Скрининг", Status: entity.ApplicationStatusScreening},
+	{Name: "Приглашён", Status: entity.ApplicationStatusInvited},
+	{Name: "Интервью", Status: entity.ApplicationStatusInterview},
+	{Name: "Оффер", Status: entity.ApplicationStatusOffer},
+	{Name: "Принят", Status: entity.ApplicationStatusAccepted},
+	{Name: "Отказ", Status: entity.ApplicationStatusRejected},
+}
+
+type HiringStageService struct {
+	StageRepo   *repository.HiringStageRepository
+	VacancyRepo *repository.VacancyRepository
+}
+
+func NewHiringStageService(stageRepo *repository.HiringStageRepository, vacancyRepo *repository.VacancyRepository) *HiringStageService {
+	return &HiringStageService{StageRepo: stageRepo, VacancyRepo: vacancyRepo}
+}
+
+// GetPipeline возвращает воронку компании или действующую воронку вакансии
+func (s *HiringStageService) GetPipeline(ctx context.Context, companyID int, vacancyID *int) ([]entity.HiringStage, error) {
+	if vacancyID == nil {
+		return s.companyStages(ctx, companyID)
+	}
+
+	vacancy, err := s.companyVacancy(companyID, *vacancyID)
+	if err != nil {
+		return nil, err
+	}
+	return s.EffectiveStages(ctx, vacancy)
+}
+
+// EffectiveStages — этапы вакансии: переопределение, если оно есть, иначе воронка компании
+func (s *HiringStageService) EffectiveStages(ctx context.Context, vacancy *entity.Vacancy) ([]entity.HiringStage, error) {
+	stages, err := s.StageRepo.GetStages(ctx, vacancy.CompanyId, &vacancy.ID)
+	if err != nil {
+		return nil, err
+	}
+	if len(stages) > 0 {
+		return stages, nil
+	}
+	return s.companyStages(ctx, vacancy.CompanyId)
+}
+
+// ReplacePipeline заменяет воронку компании или задаёт переопределение для вакансии
+func (s *HiringStageService) ReplacePipeline(ctx context.Context, companyID int, vacancyID *int, input []dto.HiringStageInput) ([]entity.HiringStage, error) {
+	if vacancyID != nil {
+		if _, err := s.companyVacancy(companyID, *vacancyID); err != nil {
+			return nil, err
+		}
+	}
+
+	stages, err := validatePipeline(input)
+	if err != nil {
+		return nil, err
+	}
+
+	saved, err := s.StageRepo.ReplaceStages(ctx, companyID, vacancyID, stages)
+	switch {
+	case errors.Is(err, repository.ErrUnknownStage):
+		return nil, ErrStageNotFound
+	case errors.Is(err, repository.ErrStageInUse):
+		return nil, ErrStageInUse
+	case errors.Is(err, repository.ErrStageStatusInUse):
+		return nil, ErrStageStatusInUse
+	case errors.Is(err, repository.ErrStageStatusMissing):
+		return nil, ErrStageStatusMissing
+	case err != nil:
+		logger.Log.Error("Failed to replace hiring pipeline", "company_id", companyID, "error", err)
+		return nil, err
+	}
+
+	logger.Log.Info("Hiring pipeline updated", "company_id", companyID, "vacancy_id", vacancyID, "stages", len(saved))
+	return saved, nil
+}
+
+// DeleteVacancyOverride возвращает вакансию на воронку компании
+func (s *HiringStageService) DeleteVacancyOverride(ctx context.Context, companyID, vacancyID int) error {
+	if _, err := s.companyVacancy(companyID, vacancyID); err != nil {
+		return err
+	}
+	if _, err := s.companyStages(ctx, companyID); err != nil {
+		return err
+	}
+	return s.StageRepo.DeleteVacancyStages(ctx, companyID, vacancyID)
+}
+
+// companyStages возвращает воронку компании, при первом обращении создавая стандартную
+func (s *HiringStageService) companyStages(ctx context.Context, companyID int) ([]entity.HiringStage, error) {
+	stages, err := s.StageRepo.GetStages(ctx, companyID, nil)
+	if err != nil {
+		return nil, err
+	}
+	if len(stages) > 0 {
+		return stages, nil
+	}
+
+	defaults := make([]entity.HiringStage, len(defaultStages))
+	copy(defaults, defaultStages)
+	if err := s.StageRepo.EnsureDefaultStages(ctx, companyID, defaults); err != nil {
+		logger.Log.Error("Failed to create default hiring pipeline", "company_id", companyID, "error", err)
+		return nil, err
+	}
+	return s.StageRepo.GetStages(ctx, companyID, nil)
+}
+
+func (s *HiringStageService) companyVacancy(companyID, vacancyID int) (*entity.Vacancy, error) {
+	vacancy, err := s.VacancyRepo.GetVacancyById(vacancyID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrVacancyNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if vacancy.CompanyId != companyID {
+		return nil, ErrVacancyNotInCompany
+	}
+	return vacancy, nil
+}
+
+// validatePipeline проверяет названия и статусы этапов; в воронке обязателен этап со статусом new
+func validatePipeline(input []dto.HiringStageInput) ([]entity.HiringStage, error) {
+	if len(input) == 0 {
+		return nil, ErrInvalidPipeline
+	}
+
+	stages := make([]entity.HiringStage, 0, len(input))
+	hasNew := false
+	for _, item := range input {
+		name := strings.TrimSpace(item.Name)
+		if name == "" {
+			return nil, ErrInvalidPipeline
+		}
+		// withdrawn выставляет только сам кандидат, этапа воронки для него нет
+		if _, ok := applicationTransitions[item.Status]; !ok || item.Status == entity.ApplicationStatusWithdrawn {
+			return nil, ErrUnknownApplicationStatus
+		}
+		if item.Status == entity.ApplicationStatusNew {
+			hasNew = true
+		}
+		stages = append(stages, entity.HiringStage{ID: item.ID, Name: name, Status: item.Status})
+	}
+	if !hasNew {
+		return nil, ErrInvalidPipeline
+	}
+	return stages, nil
+}
+
+// firstStageWithStatus возвращает первый по порядку этап с указанным базовым статусом
+func firstStageWithStatus(stages []entity.HiringStage, status string) *entity.HiringStage {
+	for i := range stages {
+		if stages[i].Status == status {
+			return &stages[i]
+		}
+	}
+	return nil
+}
+
+func findStage(stages []entity.HiringStage, stageID int) *entity.HiringStage {
+	for i := range stages {
+		if stages[i].ID == stageID {
+			return &stages[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package service
+
+import (
+	"context"
+	"jumyste-app-backend/config"
+	"jumyste-app-backend/pkg/logger"
+	"time"
+)
+
+const interviewReminderInterval = time.Minute
+
+// InterviewReminderWorker раз в минуту отправляет в чат напоминания о ближайших интервью
+type InterviewReminderWorker struct {
+	InterviewService *InterviewService
+	lead             time.Duration
+}
+
+func NewInterviewReminderWorker(interviewService *InterviewService, cfg config.InterviewConfig) *InterviewReminderWorker {
+	return &InterviewReminderWorker{
+		InterviewService: interviewService,
+		lead:             time.Duration(cfg.ReminderMinutes) * time.Minute,
+	}
+}
+
+func (w *InterviewReminderWorker) Start(ctx context.Context) {
+	if w.lead <= 0 {
+		logger.Log.Info("Interview reminders are disabled")
+		return
+	}
+	logger.Log.Info("Starting interview reminder worker", "lead", w.lead)
+
+	go func() {
+		ticker := time.NewTicker(interviewReminderInterval)
+		defer ticker.Stop()
+
+		for {
+			w.InterviewService.SendDueReminders(ctx, w.lead)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
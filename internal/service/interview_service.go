@@ -0,0 +1,553 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"jumyste-app-backend/config"
+	"jumyste-app-backend/internal/dto"
+	"jumyste-app-backend/internal/entity"
+	"jumyste-app-backend/internal/repository"
+	"jumyste-app-backend/pkg/calendar"
+	"jumyste-app-backend/pkg/logger"
+	"jumyste-app-backend/pkg/mail"
+	"strings"
+	"time"
+	_ "time/tzdata"
+)
+
+var (
+	ErrInterviewNotFound  = errors.New("interview not found")
+	ErrInterviewForbidden = errors.New("access to interview denied")
+	ErrInvalidInterview   = errors.New("invalid interview")
+	ErrInterviewConflict  = errors.New("interviewer has another interview at this time")
+	ErrInterviewClosed    = errors.New("interview is already cancelled or completed")
+)
+
+// InterviewConflictError перечисляет пересечения с другими интервью
+type InterviewConflictError struct {
+	Conflicts []entity.InterviewConflict
+}
+
+func (e *InterviewConflictError) Error() string {
+	return ErrInterviewConflict.Error()
+}
+
+func (e *InterviewConflictError) Unwrap() error {
+	return ErrInterviewConflict
+}
+
+// InterviewActor — пользователь, выполняющий действие с интервью
+type InterviewActor struct {
+	UserID    int
+	RoleID    int
+	CompanyID int
+}
+
+type InterviewService struct {
+	InterviewRepo *repository.InterviewRepository
+	JobAppRepo    *repository.JobApplicationRepository
+	VacancyRepo   *repository.VacancyRepository
+	UserRepo      *repository.UserRepository
+	ChatRepo      *repository.ChatRepository
+	MessageRepo   *repository.MessageRepository
+	JobAppService *JobApplicationService
+	Realtime      RealtimePublisher
+
+	location *time.Location
+}
+
+func NewInterviewService(
+	interviewRepo *repository.InterviewRepository,
+	jobAppRepo *repository.JobApplicationRepository,
+	vacancyRepo *repository.VacancyRepository,
+	userRepo *repository.UserRepository,
+	chatRepo *repository.ChatRepository,
+	messageRepo *repository.MessageRepository,
+	jobAppService *JobApplicationService,
+	realtime RealtimePublisher,
+	cfg config.InterviewConfig,
+) *InterviewService {
+	location, err := time.LoadLocation(cfg.Timezone)
+	if err != nil {
+		logger.Log.Warn("Unknown interview timezone, using UTC", "timezone", cfg.Timezone, "error", err)
+		location = time.UTC
+	}
+
+	return &InterviewService{
+		InterviewRepo: interviewRepo,
+		JobAppRepo:    jobAppRepo,
+		VacancyRepo:   vacancyRepo,
+		UserRepo:      userRepo,
+		ChatRepo:      chatRepo,
+		MessageRepo:   messageRepo,
+		JobAppService: jobAppService,
+		Realtime:      realtime,
+		location:      location,
+	}
+}
+
+// ScheduleInterview назначает интервью по отклику: проверяет пересечения у интервьюеров,
+// переводит отклик в interview, пишет подтверждение в чат и рассылает приглашения .ics
+func (s *InterviewService) ScheduleInterview(ctx context.Context, actor InterviewActor, req dto.CreateInterviewRequest) (*entity.Interview, error) {
+	app, vacancy, err := s.applicationForManager(ctx, actor, req.ApplicationID)
+	if err != nil {
+		return nil, err
+	}
+
+	if app.Status == entity.ApplicationStatusWithdrawn {
+		return nil, fmt.Errorf("%w: application was withdrawn by the candidate", ErrInvalidInterview)
+	}
+
+	interviewerIDs := uniqueInts(req.InterviewerIDs)
+	if len(interviewerIDs) == 0 {
+		interviewerIDs = []int{actor.UserID}
+	}
+
+	interview := &entity.Interview{
+		ApplicationID: app.ID,
+		OrganizerID:   &actor.UserID,
+		StartAt:       req.StartAt,
+		EndAt:         req.EndAt,
+		Format:        req.Format,
+		Location:      trimmed(req.Location),
+		MeetingLink:   trimmed(req.MeetingLink),
+		Notes:         trimmed(req.Notes),
+		Status:        entity.InterviewStatusScheduled,
+	}
+	if !interview.StartAt.After(time.Now()) {
+		return nil, fmt.Errorf("%w: start_at must be in the future", ErrInvalidInterview)
+	}
+	if err := s.validate(ctx, vacancy.CompanyId, interview, interviewerIDs); err != nil {
+		return nil, err
+	}
+
+	conflicts, err := s.InterviewRepo.Create(ctx, interview, interviewerIDs)
+	if err != nil {
+		return nil, err
+	}
+	if len(conflicts) > 0 {
+		return nil, &InterviewConflictError{Conflicts: conflicts}
+	}
+	logger.Log.Info("Interview scheduled", "interview_id", interview.ID, "application_id", app.ID, "start_at", interview.StartAt)
+
+	if app.Status != entity.ApplicationStatusInterview && canTransition(app.Status, entity.ApplicationStatusInterview) {
+		comment := "Назначено интервью на " + s.formatTime(interview.StartAt)
+		if err := s.JobAppService.ChangeJobApplicationStatus(ctx, app.ID, entity.ApplicationStatusInterview, actor.UserID, comment); err != nil {
+			logger.Log.Warn("Failed to move application to interview", "application_id", app.ID, "error", err)
+		}
+	}
+
+	return s.afterChange(ctx, interview.ID, app, vacancy, actor.UserID, "Вам назначено интервью")
+}
+
+// UpdateInterview переносит интервью или меняет его детали; завершённые и отменённые не редактируются
+func (s *InterviewService) UpdateInterview(ctx context.Context, actor InterviewActor, interviewID int, req dto.UpdateInterviewRequest) (*entity.Interview, error) {
+	interview, app, vacancy, err := s.interviewForManager(ctx, actor, interviewID)
+	if err != nil {
+		return nil, err
+	}
+	if interview.Status != entity.InterviewStatusScheduled {
+		return nil, ErrInterviewClosed
+	}
+
+	previousStart, previousEnd := interview.StartAt, interview.EndAt
+	if req.StartAt != nil {
+		interview.StartAt = *req.StartAt
+	}
+	if req.EndAt != nil {
+		interview.EndAt = *req.EndAt
+	}
+	if req.Format != nil {
+		interview.Format = *req.Format
+	}
+	if req.Location != nil {
+		interview.Location = trimmed(req.Location)
+	}
+	if req.MeetingLink != nil {
+		interview.MeetingLink = trimmed(req.MeetingLink)
+	}
+	if req.Notes != nil {
+		interview.Notes = trimmed(req.Notes)
+	}
+	if req.Status != nil {
+		interview.Status = *req.Status
+	}
+
+	interviewerIDs := uniqueInts(req.InterviewerIDs)
+	currentIDs := make([]int, 0, len(interview.Interviewers))
+	for _, user := range interview.Interviewers {
+		currentIDs = append(currentIDs, user.ID)
+	}
+	if req.InterviewerIDs == nil {
+		interviewerIDs = currentIDs
+	} else if len(interviewerIDs) == 0 {
+		return nil, fmt.Errorf("%w: at least one interviewer is required", ErrInvalidInterview)
+	}
+
+	rescheduled := !interview.StartAt.Equal(previousStart) || !interview.EndAt.Equal(previousEnd)
+	if rescheduled && !interview.StartAt.After(time.Now()) {
+		return nil, fmt.Errorf("%w: start_at must be in the future", ErrInvalidInterview)
+	}
+	if interview.Status == entity.InterviewStatusScheduled {
+		if err := s.validate(ctx, vacancy.CompanyId, interview, interviewerIDs); err != nil {
+			return nil, err
+		}
+	}
+
+	var replaceIDs []int
+	if req.InterviewerIDs != nil {
+		replaceIDs = interviewerIDs
+	}
+	conflicts, err := s.InterviewRepo.Update(ctx, interview, replaceIDs, rescheduled)
+	if err != nil {
+		return nil, err
+	}
+	if len(conflicts) > 0 {
+		return nil, &InterviewConflictError{Conflicts: conflicts}
+	}
+	logger.Log.Info("Interview updated", "interview_id", interview.ID, "rescheduled", rescheduled, "status", interview.Status)
+
+	if interview.Status != entity.InterviewStatusScheduled {
+		return s.InterviewRepo.GetByID(ctx, interview.ID)
+	}
+	title := "Детали интервью изменены"
+	if rescheduled {
+		title = "Интервью перенесено"
+	}
+	return s.afterChange(ctx, interview.ID, app, vacancy, actor.UserID, title)
+}
+
+// CancelInterview отменяет интервью, сообщает об этом в чате и рассылает отмену в календари
+func (s *InterviewService) CancelInterview(ctx context.Context, actor InterviewActor, interviewID int, reason string) error {
+	interview, app, vacancy, err := s.interviewForManager(ctx, actor, interviewID)
+	if err != nil {
+		return err
+	}
+	if interview.Status != entity.InterviewStatusScheduled {
+		return ErrInterviewClosed
+	}
+
+	return s.cancel(ctx, interview, app, vacancy, actor.UserID, reason)
+}
+
+// CancelApplicationInterviews отменяет все назначенные интервью отклика так же, как CancelInterview:
+// с сообщением в чат и рассылкой CANCEL .ics
+func (s *InterviewService) CancelApplicationInterviews(ctx context.Context, app *entity.JobApplication, senderID int, reason string) error {
+	interviews, err := s.InterviewRepo.List(ctx, repository.InterviewFilter{ApplicationID: app.ID})
+	if err != nil {
+		return err
+	}
+
+	var vacancy *entity.Vacancy
+	for i := range interviews {
+		if interviews[i].Status != entity.InterviewStatusScheduled {
+			continue
+		}
+		if vacancy == nil {
+			if vacancy, err = s.VacancyRepo.GetVacancyById(app.VacancyID); err != nil {
+				return err
+			}
+		}
+		if err := s.cancel(ctx, &interviews[i], app, vacancy, senderID, reason); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// cancel отменяет интервью, пишет об этом в чат отклика и рассылает участникам CANCEL .ics
+func (s *InterviewService) cancel(ctx context.Context, interview *entity.Interview, app *entity.JobApplication, vacancy *entity.Vacancy, senderID int, reason string) error {
+	interview.Status = entity.InterviewStatusCancelled
+	if _, err := s.InterviewRepo.Update(ctx, interview, nil, false); err != nil {
+		return err
+	}
+	logger.Log.Info("Interview cancelled", "interview_id", interview.ID)
+
+	text := fmt.Sprintf("Интервью на %s отменено.", s.formatTime(interview.StartAt))
+	if reason = strings.TrimSpace(reason); reason != "" {
+		text += " Причина: " + reason
+	}
+	s.postToChat(app, vacancy, senderID, text)
+	s.sendInvites(interview, app, vacancy)
+	return nil
+}
+
+func (s *InterviewService) GetInterview(ctx context.Context, actor InterviewActor, interviewID int) (*entity.Interview, error) {
+	interview, _, _, err := s.interviewForViewer(ctx, actor, interviewID)
+	return interview, err
+}
+
+// ListInterviews: HR видят интервью своей компании, кандидаты — только свои
+func (s *InterviewService) ListInterviews(ctx context.Context, actor InterviewActor, filter dto.InterviewListFilter) ([]entity.Interview, error) {
+	repoFilter := repository.InterviewFilter{
+		ApplicationID: filter.ApplicationID,
+		From:          filter.From,
+		To:            filter.To,
+	}
+	if actor.RoleID == 2 && actor.CompanyID > 0 {
+		repoFilter.CompanyID = actor.CompanyID
+	} else {
+		repoFilter.ParticipantID = actor.UserID
+	}
+	return s.InterviewRepo.List(ctx, repoFilter)
+}
+
+// GetInterviewICS формирует .ics для участника интервью
+func (s *InterviewService) GetInterviewICS(ctx context.Context, actor InterviewActor, interviewID int) ([]byte, error) {
+	interview, app, vacancy, err := s.interviewForViewer(ctx, actor, interviewID)
+	if err != nil {
+		return nil, err
+	}
+	return s.calendarEvent(interview, app, vacancy).ICS(), nil
+}
+
+// SendDueReminders отправляет в чат напоминания об интервью, которые начнутся в ближайшие lead
+func (s *InterviewService) SendDueReminders(ctx context.Context, lead time.Duration) {
+	ids, err := s.InterviewRepo.ClaimDueReminders(ctx, time.Now().Add(lead))
+	if err != nil {
+		return
+	}
+
+	for _, id := range ids {
+		interview, err := s.InterviewRepo.GetByID(ctx, id)
+		if err != nil {
+			continue
+		}
+		app, err := s.JobAppRepo.GetJobApplicationByID(ctx, interview.ApplicationID)
+		if err != nil {
+			continue
+		}
+		vacancy, err := s.VacancyRepo.GetVacancyById(app.VacancyID)
+		if err != nil {
+			continue
+		}
+
+		senderID := vacancy.CreatedBy
+		if interview.OrganizerID != nil {
+			senderID = *interview.OrganizerID
+		}
+		s.postToChat(app, vacancy, senderID, "Напоминание: "+s.describe(interview, vacancy))
+		logger.Log.Info("Interview reminder sent", "interview_id", id)
+	}
+}
+
+// validate проверяет время, формат и интервьюеров; пересечения проверяет репозиторий
+// в транзакции сохранения, чтобы параллельные бронирования не прошли обе
+func (s *InterviewService) validate(ctx context.Context, companyID int, interview *entity.Interview, interviewerIDs []int) error {
+	if !interview.EndAt.After(interview.StartAt) {
+		return fmt.Errorf("%w: end_at must be after start_at", ErrInvalidInterview)
+	}
+	switch interview.Format {
+	case entity.InterviewFormatOnline:
+		if interview.MeetingLink == nil {
+			return fmt.Errorf("%w: meeting_link is required for online interviews", ErrInvalidInterview)
+		}
+	case entity.InterviewFormatOffline:
+		if interview.Location == nil {
+			return fmt.Errorf("%w: location is required for offline interviews", ErrInvalidInterview)
+		}
+	case entity.InterviewFormatPhone:
+	default:
+		return fmt.Errorf("%w: unknown format %q", ErrInvalidInterview, interview.Format)
+	}
+
+	members, err := s.InterviewRepo.FilterCompanyMembers(ctx, companyID, interviewerIDs)
+	if err != nil {
+		return err
+	}
+	if len(members) != len(interviewerIDs) {
+		return fmt.Errorf("%w: interviewers must be members of the vacancy company", ErrInvalidInterview)
+	}
+	return nil
+}
+
+// afterChange перечитывает интервью, пишет сообщение в чат и рассылает обновлённый .ics
+func (s *InterviewService) afterChange(ctx context.Context, interviewID int, app *entity.JobApplication, vacancy *entity.Vacancy, senderID int, title string) (*entity.Interview, error) {
+	interview, err := s.InterviewRepo.GetByID(ctx, interviewID)
+	if err != nil {
+		return nil, err
+	}
+
+	s.postToChat(app, vacancy, senderID, title+": "+s.describe(interview, vacancy))
+	s.sendInvites(interview, app, vacancy)
+	return interview, nil
+}
+
+func (s *InterviewService) applicationForManager(ctx context.Context, actor InterviewActor, applicationID int) (*entity.JobApplication, *entity.Vacancy, error) {
+	app, err := s.JobAppRepo.GetJobApplicationByID(ctx, applicationID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil, ErrJobApplicationNotFound
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	vacancy, err := s.VacancyRepo.GetVacancyById(app.VacancyID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if actor.RoleID != 2 || vacancy.CompanyId != actor.CompanyID {
+		return nil, nil, ErrInterviewForbidden
+	}
+	return app, vacancy, nil
+}
+
+func (s *InterviewService) interviewForManager(ctx context.Context, actor InterviewActor, interviewID int) (*entity.Interview, *entity.JobApplication, *entity.Vacancy, error) {
+	interview, err := s.InterviewRepo.GetByID(ctx, interviewID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil, nil, ErrInterviewNotFound
+	}
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	app, vacancy, err := s.applicationForManager(ctx, actor, interview.ApplicationID)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return interview, app, vacancy, nil
+}
+
+// interviewForViewer разрешает просмотр HR компании вакансии, интервьюерам и самому кандидату
+func (s *InterviewService) interviewForViewer(ctx context.Context, actor InterviewActor, interviewID int) (*entity.Interview, *entity.JobApplication, *entity.Vacancy, error) {
+	interview, err := s.InterviewRepo.GetByID(ctx, interviewID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil, nil, ErrInterviewNotFound
+	}
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	app, err := s.JobAppRepo.GetJobApplicationByID(ctx, interview.ApplicationID)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	vacancy, err := s.VacancyRepo.GetVacancyById(app.VacancyID)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	allowed := app.UserID == actor.UserID || (actor.RoleID == 2 && vacancy.CompanyId == actor.CompanyID)
+	for _, user := range interview.Interviewers {
+		if user.ID == actor.UserID {
+			allowed = true
+		}
+	}
+	if !allowed {
+		return nil, nil, nil, ErrInterviewForbidden
+	}
+	return interview, app, vacancy, nil
+}
+
+// postToChat пишет сообщение в чат отклика, создавая чат при необходимости
+func (s *InterviewService) postToChat(app *entity.JobApplication, vacancy *entity.Vacancy, senderID int, text string) {
+	chatID, err := applicationChat(s.ChatRepo, s.Realtime, app, vacancy.CreatedBy)
+	if err != nil {
+		logger.Log.Error("Failed to get application chat", "application_id", app.ID, "error", err)
+		return
+	}
+
+	message := &entity.Message{ChatID: chatID, SenderID: senderID, Type: entity.TextMessage, Content: &text}
+	if message.ID, err = s.MessageRepo.CreateMessage(message); err != nil {
+		logger.Log.Error("Failed to post interview message", "chat_id", chatID, "error", err)
+		return
+	}
+	publishMessage(s.Realtime, message)
+}
+
+// sendInvites асинхронно отправляет .ics кандидату и интервьюерам
+func (s *InterviewService) sendInvites(interview *entity.Interview, app *entity.JobApplication, vacancy *entity.Vacancy) {
+	event := s.calendarEvent(interview, app, vacancy)
+	attachment := mail.Attachment{
+		Filename:    fmt.Sprintf("interview-%d.ics", interview.ID),
+		ContentType: "text/calendar; charset=UTF-8; method=REQUEST",
+		Data:        event.ICS(),
+	}
+	subject := "Интервью: " + vacancy.Title
+	if event.Cancelled {
+		attachment.ContentType = "text/calendar; charset=UTF-8; method=CANCEL"
+		subject = "Интервью отменено: " + vacancy.Title
+	}
+	body := s.describe(interview, vacancy)
+
+	recipients := []string{app.Email}
+	for _, user := range interview.Interviewers {
+		recipients = append(recipients, user.Email)
+	}
+
+	go func() {
+		for _, to := range recipients {
+			if err := mail.SendEmailWithAttachment(to, subject, body, attachment); err != nil {
+				logger.Log.Warn("Failed to send interview invite", "interview_id", interview.ID, "to", to, "error", err)
+			}
+		}
+	}()
+}
+
+func (s *InterviewService) calendarEvent(interview *entity.Interview, app *entity.JobApplication, vacancy *entity.Vacancy) calendar.Event {
+	event := calendar.Event{
+		UID:         fmt.Sprintf("interview-%d@jumyste", interview.ID),
+		Summary:     "Интервью: " + vacancy.Title,
+		Description: s.describe(interview, vacancy),
+		Location:    derefString(interview.Location),
+		URL:         derefString(interview.MeetingLink),
+		Start:       interview.StartAt,
+		End:         interview.EndAt,
+		Sequence:    interview.Sequence,
+		Cancelled:   interview.Status == entity.InterviewStatusCancelled,
+		Attendees:   []calendar.Attendee{{Name: strings.TrimSpace(app.FirstName + " " + app.LastName), Email: app.Email}},
+	}
+	for _, user := range interview.Interviewers {
+		attendee := calendar.Attendee{Name: strings.TrimSpace(user.FirstName + " " + user.LastName), Email: user.Email}
+		if interview.OrganizerID != nil && user.ID == *interview.OrganizerID {
+			event.Organizer = attendee
+		}
+		event.Attendees = append(event.Attendees, attendee)
+	}
+	if event.Organizer.Email == "" && len(interview.Interviewers) > 0 {
+		event.Organizer = event.Attendees[1]
+	}
+	return event
+}
+
+func (s *InterviewService) describe(interview *entity.Interview, vacancy *entity.Vacancy) string {
+	text := fmt.Sprintf("интервью по вакансии «%s» %s–%s", vacancy.Title, s.formatTime(interview.StartAt), interview.EndAt.In(s.location).Format("15:04"))
+	switch interview.Format {
+	case entity.InterviewFormatOnline:
+		text += ", онлайн: " + derefString(interview.MeetingLink)
+	case entity.InterviewFormatOffline:
+		text += ", адрес: " + derefString(interview.Location)
+	case entity.InterviewFormatPhone:
+		text += ", по телефону"
+	}
+	return text
+}
+
+func (s *InterviewService) formatTime(t time.Time) string {
+	return t.In(s.location).Format("02.01.2006 15:04")
+}
+
+func trimmed(value *string) *string {
+	if value == nil {
+		return nil
+	}
+	v := strings.TrimSpace(*value)
+	if v == "" {
+		return nil
+	}
+	return &v
+}
+
+func uniqueInts(values []int) []int {
+	seen := make(map[int]bool, len(values))
+	var result []int
+	for _, v := range values {
+		if !seen[v] {
+			seen[v] = true
+			result = append(result, v)
+		}
+	}
+	return result
+}
@@ -2,14 +2,15 @@ package service
 
 import (
 	"context"
+	"database/sql"
 	"errors"
 	"fmt"
-	"jumyste-app-backend/internal/ai"
+	"jumyste-app-backend/config"
 	"jumyste-app-backend/internal/dto"
 	"jumyste-app-backend/internal/entity"
+	"jumyste-app-backend/internal/matching"
 	"jumyste-app-backend/internal/repository"
 	"jumyste-app-backend/pkg/logger"
-	"strings"
 	"time"
 )
 
@@ -17,142 +18,240 @@ type JobApplicationService struct {
 	JobApplicationRepo *repository.JobApplicationRepository
 	ResumeRepo         *repository.ResumeRepository
 	VacancyRepo        *repository.VacancyRepository
-	AIClient           *ai.OpenAIClient
+	UserRepo           *repository.UserRepository
 	ChatRepo           *repository.ChatRepository
 	MessageRepo        *repository.MessageRepository
+	MatchingWorker     *AIMatchingWorker
+	StageService       *HiringStageService
+	Realtime           RealtimePublisher
+	Interviews         InterviewCanceller
+	ReapplyCooldown    time.Duration
+}
+
+// InterviewCanceller отменяет интервью отклика; InterviewService зависит от JobApplicationService,
+// поэтому реализация назначается после создания обоих сервисов
+type InterviewCanceller interface {
+	CancelApplicationInterviews(ctx context.Context, app *entity.JobApplication, senderID int, reason string) error
+}
+
+var (
+	ErrJobApplicationNotFound  = errors.New("job application not found")
+	ErrJobApplicationForbidden = errors.New("job application belongs to another user")
+	ErrAlreadyApplied          = errors.New("you have already applied for this vacancy")
+	ErrReapplyCooldown         = errors.New("re-apply cooldown has not expired")
+	ErrIdempotencyKeyMismatch  = errors.New("idempotency key was already used for another vacancy")
+	ErrAIScoringInProgress     = errors.New("application is being scored, try again later")
+)
+
+// DuplicateApplicationError — у соискателя уже есть активный отклик на вакансию
+type DuplicateApplicationError struct {
+	ApplicationID int
+}
+
+func (e *DuplicateApplicationError) Error() string {
+	return ErrAlreadyApplied.Error()
+}
+
+func (e *DuplicateApplicationError) Unwrap() error {
+	return ErrAlreadyApplied
+}
+
+// ReapplyCooldownError — после отказа откликнуться снова можно не раньше ReapplyAt
+type ReapplyCooldownError struct {
+	ApplicationID int
+	ReapplyAt     time.Time
+}
+
+func (e *ReapplyCooldownError) Error() string {
+	return fmt.Sprintf("%s: you can apply again after %s", ErrReapplyCooldown, e.ReapplyAt.Format(time.RFC3339))
+}
+
+func (e *ReapplyCooldownError) Unwrap() error {
+	return ErrReapplyCooldown
 }
 
 func NewJobApplicationService(repo *repository.JobApplicationRepository,
 	resumeRepo *repository.ResumeRepository,
 	vacancyRepo *repository.VacancyRepository,
-	aiClient *ai.OpenAIClient,
+	userRepo *repository.UserRepository,
 	chatRepo *repository.ChatRepository,
 	messageRepo *repository.MessageRepository,
+	matchingWorker *AIMatchingWorker,
+	stageService *HiringStageService,
+	realtime RealtimePublisher,
+	cfg config.JobApplicationConfig,
 ) *JobApplicationService {
 	return &JobApplicationService{JobApplicationRepo: repo,
-		ResumeRepo:  resumeRepo,
-		VacancyRepo: vacancyRepo,
-		AIClient:    aiClient,
-		ChatRepo:    chatRepo,
-		MessageRepo: messageRepo,
+		ResumeRepo:      resumeRepo,
+		VacancyRepo:     vacancyRepo,
+		UserRepo:        userRepo,
+		ChatRepo:        chatRepo,
+		MessageRepo:     messageRepo,
+		MatchingWorker:  matchingWorker,
+		StageService:    stageService,
+		Realtime:        realtime,
+		ReapplyCooldown: time.Duration(cfg.ReapplyCooldownDays) * 24 * time.Hour,
 	}
 }
 
+// ApplyForJob создаёт отклик. Повтор запроса с тем же idempotencyKey возвращает уже созданный отклик
+// и replayed = true; на одну вакансию допускается один активный отклик, после отказа — повторный по истечении паузы.
 func (s *JobApplicationService) ApplyForJob(
 	ctx context.Context,
 	userID, vacancyID int,
 	firstName, lastName, email string,
 	resumeID int,
-) (*entity.JobApplication, error) {
+	idempotencyKey string,
+) (application *entity.JobApplication, replayed bool, err error) {
+	if err := ensureEmailVerified(s.UserRepo, userID); err != nil {
+		return nil, false, err
+	}
+	if idempotencyKey != "" {
+		if existing, err := s.replayApplication(ctx, userID, vacancyID, idempotencyKey); existing != nil || err != nil {
+			return existing, existing != nil, err
+		}
+	}
+	if err := s.checkReapply(ctx, userID, vacancyID); err != nil {
+		return nil, false, err
+	}
+
 	// Получаем резюме пользователя
 	resume, err := s.ResumeRepo.GetByUserID(ctx, userID)
 	if err != nil {
 		logger.Log.Error("Failed to get resume", "user_id", userID, "error", err)
-		return nil, err
+		return nil, false, err
 	}
 	if resume == nil {
 		logger.Log.Error("Resume not found for user", "user_id", userID)
-		return nil, errors.New("resume not found")
+		return nil, false, errors.New("resume not found")
 	}
 
 	// Получаем вакансию
 	vacancy, err := s.VacancyRepo.GetVacancyById(vacancyID)
 	if err != nil {
 		logger.Log.Error("Failed to get vacancy", "vacancy_id", vacancyID, "error", err)
-		return nil, err
+		return nil, false, err
 	}
 	if vacancy == nil {
 		logger.Log.Error("Vacancy not found", "vacancy_id", vacancyID)
-		return nil, errors.New("vacancy not found")
-	}
-
-	resumeText := fmt.Sprintf("Имя: %s\nДолжность: %s\nНавыки: %s\nГород: %s\nО себе: %s",
-		resume.FullName,
-		resume.DesiredPosition,
-		strings.Join(resume.Skills, ", "),
-		resume.City,
-		resume.About,
-	)
-
-	vacancyText := fmt.Sprintf("Название: %s\nТип занятости: %s\nФормат работы: %s\nНавыки: %s\nЛокация: %s\nОпыт: %s\nЗарплата: от %d до %d",
-		vacancy.Title,
-		vacancy.EmploymentType,
-		vacancy.WorkFormat,
-		strings.Join(vacancy.Skills, ", "),
-		vacancy.Location,
-		vacancy.Experience,
-		vacancy.SalaryMin,
-		vacancy.SalaryMax,
-	)
-
-	matchScore, err := s.AIClient.GetMatchingScore(resumeText, vacancyText)
-	if err != nil {
-		logger.Log.Error("AI matching failed", "error", err)
-		return nil, err
+		return nil, false, errors.New("vacancy not found")
 	}
 
-	application := &entity.JobApplication{
-		UserID:          userID,
-		VacancyID:       vacancyID,
-		ResumeID:        resumeID,
-		FirstName:       firstName,
-		LastName:        lastName,
-		Email:           email,
-		Status:          "new",
-		AIMatchingScore: matchScore.Score,
-		AIStrengths:     matchScore.Strengths,
-		AIWeaknesses:    matchScore.Weaknesses,
-	}
-
-	err = s.JobApplicationRepo.CreateJobApplication(ctx, application)
+	// Оценка по правилам считается сразу и не зависит от доступности AI
+	experiences, err := s.ResumeRepo.GetWorkExperienceByResumeID(ctx, resume.ID)
 	if err != nil {
-		logger.Log.Error("Failed to save application", "error", err)
-		return nil, err
+		logger.Log.Error("Failed to get work experience", "resume_id", resume.ID, "error", err)
+		return nil, false, err
+	}
+	resume.Experiences = experiences
+	rules := matching.Score(resume, vacancy, time.Now())
+
+	// AI-оценка считается асинхронно воркером, отклик сохраняется сразу
+	application = &entity.JobApplication{
+		UserID:        userID,
+		VacancyID:     vacancyID,
+		ResumeID:      resumeID,
+		FirstName:     firstName,
+		LastName:      lastName,
+		Email:         email,
+		Status:        "new",
+		AIStatus:      entity.AIStatusPending,
+		RuleScore:     &rules.Score,
+		RuleBreakdown: &rules.Breakdown,
+	}
+	if idempotencyKey != "" {
+		application.IdempotencyKey = &idempotencyKey
 	}
 
-	chat, err := s.ChatRepo.GetChatBetweenUsers(userID, vacancy.CreatedBy)
+	// Новый отклик попадает на первый этап воронки вакансии со статусом new
+	stages, err := s.StageService.EffectiveStages(ctx, vacancy)
 	if err != nil {
-		logger.Log.Error("Failed to get chat between users", "user_id", userID, "hr_id", vacancy.CreatedBy, "error", err)
+		logger.Log.Error("Failed to get hiring stages", "vacancy_id", vacancyID, "error", err)
+		return nil, false, err
+	}
+	if stage := firstStageWithStatus(stages, entity.ApplicationStatusNew); stage != nil {
+		application.StageID = &stage.ID
 	}
 
-	if chat == nil {
-		newChat := &entity.Chat{
-			Users: []entity.UserResponse{
-				{ID: userID},
-				{ID: vacancy.CreatedBy},
-			},
-			CreatedAt: time.Now(),
-			UpdatedAt: time.Now(),
-		}
-		chatID, err := s.ChatRepo.CreateChat(newChat)
-		if err != nil {
-			logger.Log.Error("Failed to create chat", "error", err)
-		} else {
-			chat = &entity.Chat{
-				ID:        chatID,
-				Users:     newChat.Users,
-				CreatedAt: newChat.CreatedAt,
-				UpdatedAt: newChat.UpdatedAt,
-			}
+	err = s.JobApplicationRepo.CreateJobApplication(ctx, application)
+	if errors.Is(err, repository.ErrDuplicateIdempotencyKey) {
+		// Параллельный повтор того же запроса успел создать отклик первым
+		existing, err := s.replayApplication(ctx, userID, vacancyID, idempotencyKey)
+		return existing, existing != nil, err
+	}
+	if errors.Is(err, repository.ErrDuplicateApplication) {
+		if err := s.checkReapply(ctx, userID, vacancyID); err != nil {
+			return nil, false, err
 		}
+		return nil, false, ErrAlreadyApplied
 	}
+	if err != nil {
+		logger.Log.Error("Failed to save application", "error", err)
+		return nil, false, err
+	}
+
+	s.MatchingWorker.Enqueue(application.ID)
 
-	if chat != nil {
+	chatID, err := applicationChat(s.ChatRepo, s.Realtime, application, vacancy.CreatedBy)
+	if err != nil {
+		logger.Log.Error("Failed to create application chat", "application_id", application.ID, "error", err)
+	} else {
 		welcomeMessageContent := "Здравствуйте! Я откликнулся на вакансию \"" + vacancy.Title + "\"."
 		message := &entity.Message{
-			ChatID:   chat.ID,
+			ChatID:   chatID,
 			SenderID: userID,
 			Type:     "text",
 			Content:  &welcomeMessageContent,
 		}
 
-		_, err = s.MessageRepo.CreateMessage(message)
+		message.ID, err = s.MessageRepo.CreateMessage(message)
 		if err != nil {
-			logger.Log.Error("Failed to send first message", "chat_id", chat.ID, "error", err)
+			logger.Log.Error("Failed to send first message", "chat_id", chatID, "error", err)
+		} else {
+			publishMessage(s.Realtime, message)
 		}
 	}
 
-	return application, nil
+	return application, false, nil
+}
+
+// replayApplication возвращает отклик, ранее созданный с тем же Idempotency-Key, или nil, если ключ новый
+func (s *JobApplicationService) replayApplication(ctx context.Context, userID, vacancyID int, idempotencyKey string) (*entity.JobApplication, error) {
+	existing, err := s.JobApplicationRepo.GetJobApplicationByIdempotencyKey(ctx, userID, idempotencyKey)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if existing.VacancyID != vacancyID {
+		return nil, ErrIdempotencyKeyMismatch
+	}
+	logger.Log.Info("Replaying idempotent job application", "application_id", existing.ID, "user_id", userID)
+	return existing, nil
+}
+
+// checkReapply проверяет, можно ли соискателю откликнуться на вакансию ещё раз
+func (s *JobApplicationService) checkReapply(ctx context.Context, userID, vacancyID int) error {
+	latest, changedAt, err := s.JobApplicationRepo.GetLatestJobApplication(ctx, userID, vacancyID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	switch latest.Status {
+	case entity.ApplicationStatusWithdrawn:
+		return nil
+	case entity.ApplicationStatusRejected:
+		if reapplyAt := changedAt.Add(s.ReapplyCooldown); time.Now().Before(reapplyAt) {
+			return &ReapplyCooldownError{ApplicationID: latest.ID, ReapplyAt: reapplyAt}
+		}
+		return nil
+	default:
+		return &DuplicateApplicationError{ApplicationID: latest.ID}
+	}
 }
 
 func (s *JobApplicationService) GetJobApplicationsByVacancyID(ctx context.Context, vacancyID int) ([]dto.JobApplicationWithResumeResponse, error) {
@@ -162,6 +261,11 @@ func (s *JobApplicationService) GetJobApplicationsByVacancyID(ctx context.Contex
 		return nil, err
 	}
 
+	stageNames, err := s.stageNames(ctx, vacancyID)
+	if err != nil {
+		return nil, err
+	}
+
 	var response []dto.JobApplicationWithResumeResponse
 	for _, app := range applications {
 		resume, user, err := s.ResumeRepo.GetResumeByUserID(ctx, app.UserID)
@@ -178,10 +282,16 @@ func (s *JobApplicationService) GetJobApplicationsByVacancyID(ctx context.Contex
 			LastName:          user.LastName,
 			Email:             user.Email,
 			Status:            app.Status,
+			StageID:           app.StageID,
+			Stage:             stageName(stageNames, app.StageID),
 			AppliedAt:         app.AppliedAt.Format("2006-01-02 15:04:05"),
 			AIMatchingScore:   app.AIMatchingScore,
 			AIStrengths:       app.AIStrengths,
 			AIMatchWeaknesses: app.AIWeaknesses,
+			AIBreakdown:       app.AIBreakdown,
+			AIStatus:          app.AIStatus,
+			RuleScore:         app.RuleScore,
+			RuleBreakdown:     app.RuleBreakdown,
 			Resume: dto.ResumeResponse{
 				FullName:        resume.FullName,
 				DesiredPosition: resume.DesiredPosition,
@@ -206,15 +316,6 @@ func (s *JobApplicationService) GetJobApplicationsByVacancyID(ctx context.Contex
 	return response, nil
 }
 
-func (s *JobApplicationService) UpdateJobApplicationStatus(ctx context.Context, applicationID int, status string) error {
-	err := s.JobApplicationRepo.UpdateJobApplicationStatus(ctx, applicationID, status)
-	if err != nil {
-		logger.Log.Error("Failed to update job application status", "error", err)
-		return err
-	}
-	return nil
-}
-
 func (s *JobApplicationService) DeleteJobApplication(ctx context.Context, applicationID int) error {
 	err := s.JobApplicationRepo.DeleteJobApplication(ctx, applicationID)
 	if err != nil {
@@ -224,10 +325,10 @@ func (s *JobApplicationService) DeleteJobApplication(ctx context.Context, applic
 	return nil
 }
 
-func (s *JobApplicationService) GetJobAppAnalytics(ctx context.Context, hrID int) ([]entity.ApplicationStatusStat, error) {
-	logger.Log.Info("Fetching job application analytics", "hr_id", hrID)
+func (s *JobApplicationService) GetJobAppAnalytics(ctx context.Context, hrID, vacancyID int) ([]entity.ApplicationStatusStat, error) {
+	logger.Log.Info("Fetching job application analytics", "hr_id", hrID, "vacancy_id", vacancyID)
 
-	stats, err := s.JobApplicationRepo.GetJobAppAnalytics(ctx, hrID)
+	stats, err := s.JobApplicationRepo.GetJobAppAnalytics(ctx, hrID, vacancyID)
 	if err != nil {
 		logger.Log.Error("Failed to get job application analytics", "hr_id", hrID, "error", err)
 		return nil, err
@@ -274,6 +375,11 @@ func (s *JobApplicationService) GetJobApplicationByID(ctx context.Context, appli
 		return nil, fmt.Errorf("resume not found with id %d", app.ResumeID)
 	}
 
+	stageNames, err := s.stageNames(ctx, app.VacancyID)
+	if err != nil {
+		return nil, err
+	}
+
 	response := &dto.JobApplicationWithResumeResponse{
 		ID:                app.ID,
 		UserID:            app.UserID,
@@ -282,10 +388,16 @@ func (s *JobApplicationService) GetJobApplicationByID(ctx context.Context, appli
 		LastName:          user.LastName,
 		Email:             user.Email,
 		Status:            app.Status,
+		StageID:           app.StageID,
+		Stage:             stageName(stageNames, app.StageID),
 		AppliedAt:         app.AppliedAt.Format("2006-01-02 15:04:05"),
 		AIMatchingScore:   app.AIMatchingScore,
 		AIStrengths:       app.AIStrengths,
 		AIMatchWeaknesses: app.AIWeaknesses,
+		AIBreakdown:       app.AIBreakdown,
+		AIStatus:          app.AIStatus,
+		RuleScore:         app.RuleScore,
+		RuleBreakdown:     app.RuleBreakdown,
 		Resume: dto.ResumeResponse{
 			FullName:        resume.FullName,
 			DesiredPosition: resume.DesiredPosition,
@@ -305,3 +417,76 @@ func (s *JobApplicationService) GetJobApplicationByID(ctx context.Context, appli
 	}
 	return response, nil
 }
+
+// RescoreJobApplication пересчитывает оценку по правилам, сбрасывает AI-оценку отклика и ставит его в очередь на пересчёт
+func (s *JobApplicationService) RescoreJobApplication(ctx context.Context, applicationID int) error {
+	err := s.JobApplicationRepo.ResetAIStatus(ctx, applicationID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrJobApplicationNotFound
+	}
+	if errors.Is(err, repository.ErrAIScoringInProgress) {
+		return ErrAIScoringInProgress
+	}
+	if err != nil {
+		logger.Log.Error("Failed to reset AI status", "application_id", applicationID, "error", err)
+		return err
+	}
+
+	// Отклик уже сброшен в pending: без постановки в очередь он так и остался бы без оценки
+	if err := s.refreshRuleScore(ctx, applicationID); err != nil {
+		logger.Log.Warn("Failed to refresh rule score", "application_id", applicationID, "error", err)
+	}
+
+	s.MatchingWorker.Enqueue(applicationID)
+	logger.Log.Info("Job application queued for AI rescoring", "application_id", applicationID)
+	return nil
+}
+
+// RescoreVacancyApplications ставит на пересчёт все отклики вакансии и возвращает их количество
+func (s *JobApplicationService) RescoreVacancyApplications(ctx context.Context, vacancyID int) (int, error) {
+	ids, err := s.JobApplicationRepo.ResetAIStatusByVacancyID(ctx, vacancyID)
+	if err != nil {
+		logger.Log.Error("Failed to reset AI status for vacancy", "vacancy_id", vacancyID, "error", err)
+		return 0, err
+	}
+
+	// Отклики уже сброшены в pending, поэтому ошибка пересчёта по правилам одного из них
+	// не должна оставить остальные без постановки в очередь
+	for _, id := range ids {
+		if err := s.refreshRuleScore(ctx, id); err != nil {
+			logger.Log.Warn("Failed to refresh rule score", "application_id", id, "error", err)
+		}
+		s.MatchingWorker.Enqueue(id)
+	}
+
+	logger.Log.Info("Vacancy applications queued for AI rescoring", "vacancy_id", vacancyID, "count", len(ids))
+	return len(ids), nil
+}
+
+// refreshRuleScore пересчитывает rule_score по текущим резюме и вакансии
+func (s *JobApplicationService) refreshRuleScore(ctx context.Context, applicationID int) error {
+	app, err := s.JobApplicationRepo.GetJobApplicationByID(ctx, applicationID)
+	if err != nil {
+		return err
+	}
+
+	resume, err := s.ResumeRepo.GetByUserID(ctx, app.UserID)
+	if err != nil {
+		return err
+	}
+	if resume == nil {
+		return errors.New("resume not found")
+	}
+	resume.Experiences, err = s.ResumeRepo.GetWorkExperienceByResumeID(ctx, resume.ID)
+	if err != nil {
+		return err
+	}
+
+	vacancy, err := s.VacancyRepo.GetVacancyById(app.VacancyID)
+	if err != nil {
+		return err
+	}
+
+	rules := matching.Score(resume, vacancy, time.Now())
+	return s.JobApplicationRepo.SaveRuleScore(ctx, applicationID, rules.Score, &rules.Breakdown)
+}
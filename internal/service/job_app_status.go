@@ -0,0 +1,333 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"jumyste-app-backend/internal/dto"
+	"jumyste-app-backend/internal/entity"
+	"jumyste-app-backend/internal/manager"
+	"jumyste-app-backend/pkg/logger"
+	"strings"
+)
+
+var (
+	ErrUnknownApplicationStatus = errors.New("unknown application status")
+	ErrStatusTransitionDenied   = errors.New("status transition is not allowed")
+)
+
+// applicationTransitions — допустимые переходы воронки откликов; accepted, rejected и withdrawn финальные.
+// В withdrawn отклик переводит только сам соискатель через WithdrawJobApplication.
+var applicationTransitions = map[string][]string{
+	entity.ApplicationStatusNew:       {entity.ApplicationStatusScreening, entity.ApplicationStatusInvited, entity.ApplicationStatusInterview, entity.ApplicationStatusRejected},
+	entity.ApplicationStatusScreening: {entity.ApplicationStatusInvited, entity.ApplicationStatusInterview, entity.ApplicationStatusRejected},
+	entity.ApplicationStatusInvited:   {entity.ApplicationStatusInterview, entity.ApplicationStatusRejected},
+	entity.ApplicationStatusInterview: {entity.ApplicationStatusOffer, entity.ApplicationStatusAccepted, entity.ApplicationStatusRejected},
+	entity.ApplicationStatusOffer:     {entity.ApplicationStatusAccepted, entity.ApplicationStatusRejected},
+	entity.ApplicationStatusAccepted:  {},
+	entity.ApplicationStatusRejected:  {},
+	entity.ApplicationStatusWithdrawn: {},
+}
+
+// StatusTransitionError описывает запрещённый переход и перечисляет допустимые из текущего статуса
+type StatusTransitionError struct {
+	From    string
+	To      string
+	Allowed []string
+}
+
+func (e *StatusTransitionError) Error() string {
+	return fmt.Sprintf("%s: %s -> %s", ErrStatusTransitionDenied, e.From, e.To)
+}
+
+func (e *StatusTransitionError) Unwrap() error {
+	return ErrStatusTransitionDenied
+}
+
+// AllowedStatusTransitions возвращает статусы, в которые можно перевести отклик из from
+func AllowedStatusTransitions(from string) []string {
+	return applicationTransitions[from]
+}
+
+func canTransition(from, to string) bool {
+	for _, allowed := range applicationTransitions[from] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+// ChangeJobApplicationStatus проверяет переход по правилам воронки и записывает его в историю вместе с автором и комментарием.
+// Отклик переходит на первый этап воронки вакансии с новым статусом.
+func (s *JobApplicationService) ChangeJobApplicationStatus(ctx context.Context, applicationID int, status string, actorID int, comment string) error {
+	if _, ok := applicationTransitions[status]; !ok {
+		return ErrUnknownApplicationStatus
+	}
+
+	app, stages, err := s.applicationWithStages(ctx, applicationID)
+	if err != nil {
+		return err
+	}
+
+	var toStageID *int
+	if stage := firstStageWithStatus(stages, status); stage != nil {
+		toStageID = &stage.ID
+	}
+	return s.transition(ctx, app, status, toStageID, actorID, comment)
+}
+
+// MoveJobApplicationToStage переводит отклик на этап воронки вакансии.
+// Внутри одного базового статуса перемещение свободное, смена статуса проверяется правилами переходов.
+func (s *JobApplicationService) MoveJobApplicationToStage(ctx context.Context, applicationID, stageID, actorID int, comment string) error {
+	app, stages, err := s.applicationWithStages(ctx, applicationID)
+	if err != nil {
+		return err
+	}
+
+	stage := findStage(stages, stageID)
+	if stage == nil {
+		return ErrStageNotFound
+	}
+	return s.transition(ctx, app, stage.Status, &stage.ID, actorID, comment)
+}
+
+// GetVacancyBoard раскладывает отклики вакансии по колонкам-этапам для канбан-доски
+func (s *JobApplicationService) GetVacancyBoard(ctx context.Context, vacancyID int) ([]dto.StageColumn, error) {
+	vacancy, err := s.VacancyRepo.GetVacancyById(vacancyID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrVacancyNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	stages, err := s.StageService.EffectiveStages(ctx, vacancy)
+	if err != nil {
+		return nil, err
+	}
+
+	applications, err := s.GetJobApplicationsByVacancyID(ctx, vacancyID)
+	if err != nil {
+		return nil, err
+	}
+
+	board := make([]dto.StageColumn, len(stages))
+	columnByStage := make(map[int]int, len(stages))
+	extraByStatus := make(map[string]int)
+	for i, stage := range stages {
+		board[i] = dto.StageColumn{Stage: stage, Applications: []dto.JobApplicationWithResumeResponse{}}
+		columnByStage[stage.ID] = i
+	}
+
+	for _, app := range applications {
+		column, ok := -1, false
+		if app.StageID != nil {
+			column, ok = columnByStage[*app.StageID]
+		}
+		if !ok {
+			// Отклики без этапа (например, созданные до настройки воронки) ставим по статусу
+			if stage := firstStageWithStatus(stages, app.Status); stage != nil {
+				column, ok = columnByStage[stage.ID], true
+			}
+		}
+		if !ok {
+			// Статуса нет в воронке вакансии — выводим отдельной колонкой без ID
+			if column, ok = extraByStatus[app.Status]; !ok {
+				board = append(board, dto.StageColumn{
+					Stage:        entity.HiringStage{Name: app.Status, Status: app.Status, Position: len(board) + 1},
+					Applications: []dto.JobApplicationWithResumeResponse{},
+				})
+				column = len(board) - 1
+				extraByStatus[app.Status] = column
+			}
+		}
+		board[column].Applications = append(board[column].Applications, app)
+		board[column].Count++
+	}
+
+	return board, nil
+}
+
+func (s *JobApplicationService) applicationWithStages(ctx context.Context, applicationID int) (*entity.JobApplication, []entity.HiringStage, error) {
+	app, err := s.JobApplicationRepo.GetJobApplicationByID(ctx, applicationID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil, ErrJobApplicationNotFound
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	vacancy, err := s.VacancyRepo.GetVacancyById(app.VacancyID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	stages, err := s.StageService.EffectiveStages(ctx, vacancy)
+	if err != nil {
+		return nil, nil, err
+	}
+	return app, stages, nil
+}
+
+func (s *JobApplicationService) transition(ctx context.Context, app *entity.JobApplication, status string, toStageID *int, actorID int, comment string) error {
+	if status != app.Status && !canTransition(app.Status, status) {
+		return &StatusTransitionError{From: app.Status, To: status, Allowed: AllowedStatusTransitions(app.Status)}
+	}
+	if status == app.Status && sameStage(app.StageID, toStageID) {
+		return nil
+	}
+
+	return s.saveTransition(ctx, app, status, toStageID, actorID, comment)
+}
+
+func (s *JobApplicationService) saveTransition(ctx context.Context, app *entity.JobApplication, status string, toStageID *int, actorID int, comment string) error {
+	var commentPtr *string
+	if comment = strings.TrimSpace(comment); comment != "" {
+		commentPtr = &comment
+	}
+
+	err := s.JobApplicationRepo.ChangeJobApplicationStatus(ctx, app.ID, app.Status, status, app.StageID, toStageID, actorID, commentPtr)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("%w: status was changed concurrently", ErrStatusTransitionDenied)
+	}
+	if err != nil {
+		logger.Log.Error("Failed to change job application status", "application_id", app.ID, "error", err)
+		return err
+	}
+
+	logger.Log.Info("Job application status changed", "application_id", app.ID, "from", app.Status, "to", status, "stage_id", toStageID, "actor_id", actorID)
+
+	if s.Realtime != nil {
+		s.Realtime.PublishToUser(app.UserID, manager.EventApplicationStatusChanged, manager.ApplicationStatusPayload{
+			ApplicationID: app.ID,
+			VacancyID:     app.VacancyID,
+			From:          app.Status,
+			To:            status,
+			StageID:       toStageID,
+		})
+	}
+	return nil
+}
+
+// stageNames возвращает названия этапов вакансии по их ID
+func (s *JobApplicationService) stageNames(ctx context.Context, vacancyID int) (map[int]string, error) {
+	vacancy, err := s.VacancyRepo.GetVacancyById(vacancyID)
+	if err != nil {
+		return nil, err
+	}
+	stages, err := s.StageService.EffectiveStages(ctx, vacancy)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make(map[int]string, len(stages))
+	for _, stage := range stages {
+		names[stage.ID] = stage.Name
+	}
+	return names, nil
+}
+
+func stageName(names map[int]string, stageID *int) *string {
+	if stageID == nil {
+		return nil
+	}
+	if name, ok := names[*stageID]; ok {
+		return &name
+	}
+	return nil
+}
+
+func sameStage(a, b *int) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// GetJobApplicationTimeline возвращает историю статусов отклика в хронологическом порядке
+func (s *JobApplicationService) GetJobApplicationTimeline(ctx context.Context, applicationID int) ([]entity.JobApplicationStatusChange, error) {
+	if _, err := s.JobApplicationRepo.GetJobApplicationByID(ctx, applicationID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrJobApplicationNotFound
+		}
+		return nil, err
+	}
+
+	history, err := s.JobApplicationRepo.GetStatusHistory(ctx, applicationID)
+	if err != nil {
+		logger.Log.Error("Failed to get job application timeline", "application_id", applicationID, "error", err)
+		return nil, err
+	}
+	return history, nil
+}
+
+// WithdrawJobApplication отзывает отклик соискателя: запись остаётся у HR со статусом withdrawn и попадает в историю
+func (s *JobApplicationService) WithdrawJobApplication(ctx context.Context, applicationID, userID int, comment string) error {
+	app, err := s.JobApplicationRepo.GetJobApplicationByID(ctx, applicationID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrJobApplicationNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if app.UserID != userID {
+		return ErrJobApplicationForbidden
+	}
+	if len(applicationTransitions[app.Status]) == 0 {
+		return &StatusTransitionError{From: app.Status, To: entity.ApplicationStatusWithdrawn, Allowed: AllowedStatusTransitions(app.Status)}
+	}
+
+	if err := s.saveTransition(ctx, app, entity.ApplicationStatusWithdrawn, nil, userID, comment); err != nil {
+		return err
+	}
+
+	// Назначенные интервью отзываются, чтобы кандидату и интервьюерам не приходили напоминания
+	if s.Interviews != nil {
+		if err := s.Interviews.CancelApplicationInterviews(ctx, app, userID, "кандидат отозвал отклик"); err != nil {
+			logger.Log.Error("Failed to cancel interviews of withdrawn application", "application_id", app.ID, "error", err)
+		}
+	}
+	return nil
+}
+
+// GetMyJobApplications возвращает отклики соискателя вместе с историей статусов.
+// Комментарии HR в истории скрываются — соискатель видит только свои.
+func (s *JobApplicationService) GetMyJobApplications(ctx context.Context, userID int) ([]entity.CandidateApplication, error) {
+	applications, err := s.JobApplicationRepo.GetJobApplicationsByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]int, len(applications))
+	for i, app := range applications {
+		ids[i] = app.ID
+	}
+	history, err := s.JobApplicationRepo.GetStatusHistoryByApplicationIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	timelines := make(map[int][]entity.JobApplicationStatusChange, len(applications))
+	for _, change := range HideForeignComments(history, userID) {
+		timelines[change.ApplicationID] = append(timelines[change.ApplicationID], change)
+	}
+	for i := range applications {
+		applications[i].Timeline = timelines[applications[i].ID]
+		if applications[i].Timeline == nil {
+			applications[i].Timeline = []entity.JobApplicationStatusChange{}
+		}
+	}
+	return applications, nil
+}
+
+// HideForeignComments убирает из истории комментарии, оставленные не userID (внутренние заметки HR)
+func HideForeignComments(history []entity.JobApplicationStatusChange, userID int) []entity.JobApplicationStatusChange {
+	for i := range history {
+		if history[i].ActorID == nil || *history[i].ActorID != userID {
+			history[i].Comment = nil
+		}
+	}
+	return history
+}
@@ -2,56 +2,181 @@ package service
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"github.com/lib/pq"
+	"jumyste-app-backend/internal/dto"
 	"jumyste-app-backend/internal/entity"
 	"jumyste-app-backend/internal/repository"
 	"jumyste-app-backend/pkg/logger"
 	"log/slog"
 )
 
+var (
+	ErrNotChatMember    = errors.New("user is not a member of this chat")
+	ErrMessageNotFound  = errors.New("message not found")
+	ErrEmptyMessage     = errors.New("message has neither content nor attachment")
+	ErrMessageForbidden = errors.New("only the sender can change this message")
+	ErrMessageDeleted   = errors.New("message has been deleted")
+	ErrInvalidReply     = errors.New("replied message does not belong to this chat")
+)
+
+// DeleteScope — у кого удаляется сообщение
+type DeleteScope string
+
+const (
+	DeleteForEveryone DeleteScope = "everyone"
+	DeleteForSelf     DeleteScope = "self"
+)
+
 type MessageService struct {
 	MessageRepo *repository.MessageRepository
+	ChatRepo    *repository.ChatRepository
+	UserRepo    *repository.UserRepository
+	Attachments *AttachmentService
 }
 
-func NewMessageService(messageRepo *repository.MessageRepository) *MessageService {
-	return &MessageService{MessageRepo: messageRepo}
+func NewMessageService(messageRepo *repository.MessageRepository, chatRepo *repository.ChatRepository, userRepo *repository.UserRepository, attachments *AttachmentService) *MessageService {
+	return &MessageService{MessageRepo: messageRepo, ChatRepo: chatRepo, UserRepo: userRepo, Attachments: attachments}
 }
 
-// SendMessage - Creates a new message
-func (s *MessageService) SendMessage(chatID, senderID int, msgType entity.MessageType, content *string, fileURL *string) (*entity.Message, error) {
+// SendMessage - Creates a new message; upload is optional and replaces file_url of the old API,
+// replyToID quotes another message of the same chat
+func (s *MessageService) SendMessage(ctx context.Context, chatID, senderID int, msgType entity.MessageType, content *string, upload *AttachmentUpload, replyToID *int) (*entity.Message, error) {
+	if err := s.ensureMember(chatID, senderID); err != nil {
+		return nil, err
+	}
+	if err := ensureEmailVerified(s.UserRepo, senderID); err != nil {
+		return nil, err
+	}
+
 	message := &entity.Message{
-		ChatID:   chatID,
-		SenderID: senderID,
-		Type:     msgType,
-		Content:  content,
-		FileURL:  fileURL,
-		ReadBy:   pq.Int64Array{},
-		IsMine:   true,
+		ChatID:    chatID,
+		SenderID:  senderID,
+		Type:      msgType,
+		Content:   content,
+		ReadBy:    pq.Int64Array{},
+		IsMine:    true,
+		ReplyToID: replyToID,
+	}
+
+	if replyToID != nil {
+		parent, err := s.MessageRepo.GetMessageByID(*replyToID)
+		if errors.Is(err, sql.ErrNoRows) || (err == nil && parent.ChatID != chatID) {
+			return nil, ErrInvalidReply
+		}
+		if err != nil {
+			return nil, err
+		}
+		message.ReplyTo = quoteOf(parent)
+	}
+
+	if upload != nil {
+		attachment, kind, err := s.Attachments.Upload(ctx, chatID, msgType, *upload)
+		if err != nil {
+			return nil, err
+		}
+		message.Type = kind
+		message.Attachment = attachment
+	} else {
+		if content == nil || *content == "" {
+			return nil, ErrEmptyMessage
+		}
+		message.Type = entity.TextMessage
 	}
 
 	messageID, err := s.MessageRepo.CreateMessage(message)
 	if err != nil {
 		logger.Log.Error("Failed to create message", slog.Int("chat_id", chatID), slog.Int("sender_id", senderID), slog.String("error", err.Error()))
+		if message.Attachment != nil {
+			s.Attachments.Delete(ctx, message.Attachment)
+		}
 		return nil, err
 	}
-
 	message.ID = messageID
+
+	messages := []entity.Message{*message}
+	if err := s.sign(ctx, messages); err != nil {
+		return nil, err
+	}
+	message = &messages[0]
+
 	logger.Log.Info("Message sent", slog.Int("message_id", messageID), slog.Int("chat_id", chatID), slog.Int("sender_id", senderID))
 	return message, nil
 }
 
-// GetMessagesByChatID - Fetch all messages from a chat
-func (s *MessageService) GetMessagesByChatID(chatID, userID int) ([]entity.Message, error) {
-	messages, err := s.MessageRepo.GetMessagesByChatID(chatID)
+// GetMessagesByChatID - Fetch a page of a chat's history for a chat member
+func (s *MessageService) GetMessagesByChatID(ctx context.Context, chatID, userID int, cursor dto.MessagePageQuery) (*dto.MessagePage, error) {
+	if err := s.ensureMember(chatID, userID); err != nil {
+		return nil, err
+	}
+
+	limit := pageSize(cursor.Limit)
+	messages, hasMore, err := s.MessageRepo.GetMessagesPage(chatID, userID, cursor.Before, cursor.After, limit)
 	if err != nil {
+		logger.Log.Error("Failed to fetch messages", slog.Int("chat_id", chatID), slog.String("error", err.Error()))
 		return nil, err
 	}
 
 	for i := range messages {
 		messages[i].IsMine = messages[i].SenderID == userID
 	}
+	if err := s.prepare(ctx, messages); err != nil {
+		return nil, err
+	}
 
-	return messages, nil
+	page := &dto.MessagePage{Messages: messages, HasMore: hasMore}
+	if hasMore {
+		// Страница отсортирована по возрастанию: назад продолжаем от первого, вперёд — от последнего
+		next := messages[0].ID
+		if cursor.After > 0 {
+			next = messages[len(messages)-1].ID
+		}
+		page.NextCursor = &next
+	}
+	return page, nil
+}
+
+// SearchMessages - Full-text search across the user's chats, optionally within one chat
+func (s *MessageService) SearchMessages(ctx context.Context, userID int, query dto.MessageSearchQuery) (*dto.MessageSearchPage, error) {
+	if query.ChatID > 0 {
+		if err := s.ensureMember(query.ChatID, userID); err != nil {
+			return nil, err
+		}
+	}
+
+	limit := pageSize(query.Limit)
+	results, hasMore, err := s.MessageRepo.SearchMessages(userID, query.Query, query.ChatID, query.Before, limit)
+	if err != nil {
+		logger.Log.Error("Failed to search messages", slog.Int("user_id", userID), slog.String("error", err.Error()))
+		return nil, err
+	}
+
+	messages := make([]entity.Message, len(results))
+	for i := range results {
+		results[i].IsMine = results[i].SenderID == userID
+		messages[i] = results[i].Message
+	}
+	if err := s.prepare(ctx, messages); err != nil {
+		return nil, err
+	}
+	for i := range results {
+		results[i].Message = messages[i]
+	}
+
+	page := &dto.MessageSearchPage{Results: results, HasMore: hasMore}
+	if hasMore {
+		next := results[len(results)-1].ID
+		page.NextCursor = &next
+	}
+	return page, nil
+}
+
+func pageSize(limit int) int {
+	if limit <= 0 {
+		return dto.DefaultMessagePageSize
+	}
+	return min(limit, dto.MaxMessagePageSize)
 }
 
 // GetMessageByID - Fetch a message by ID
@@ -59,7 +184,95 @@ func (s *MessageService) GetMessageByID(messageID int) (*entity.Message, error)
 	return s.MessageRepo.GetMessageByID(messageID)
 }
 
+// GetMessageForUser - Fetch a message by ID for a chat member, with signed attachment links
+func (s *MessageService) GetMessageForUser(ctx context.Context, messageID, userID int) (*entity.Message, error) {
+	message, err := s.memberMessage(messageID, userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.prepareOne(ctx, message, userID)
+}
+
+// EditMessage - Replaces the text of the sender's message and keeps the previous version in the history
+func (s *MessageService) EditMessage(ctx context.Context, messageID, userID int, content string) (*entity.Message, error) {
+	message, err := s.memberMessage(messageID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if message.SenderID != userID || message.Type == entity.SystemMessage {
+		return nil, ErrMessageForbidden
+	}
+	if message.IsDeleted {
+		return nil, ErrMessageDeleted
+	}
+	if content == "" && message.Attachment == nil {
+		return nil, ErrEmptyMessage
+	}
+	if message.Content != nil && *message.Content == content {
+		return s.prepareOne(ctx, message, userID)
+	}
+
+	updated, err := s.MessageRepo.UpdateMessageContent(messageID, content)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrMessageDeleted
+	}
+	if err != nil {
+		logger.Log.Error("Failed to edit message", slog.Int("message_id", messageID), slog.String("error", err.Error()))
+		return nil, err
+	}
+
+	logger.Log.Info("Message edited", slog.Int("message_id", messageID), slog.Int("user_id", userID))
+	return s.prepareOne(ctx, updated, userID)
+}
+
+// DeleteMessage - Deletes a message for everyone (sender only) or hides it for the current user
+func (s *MessageService) DeleteMessage(ctx context.Context, messageID, userID int, scope DeleteScope) (*entity.Message, error) {
+	message, err := s.memberMessage(messageID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	switch scope {
+	case DeleteForSelf:
+		err = s.MessageRepo.HideMessage(messageID, userID)
+	default:
+		// Служебные сообщения ведут журнал состава чата и для всех не удаляются
+		if message.SenderID != userID || message.Type == entity.SystemMessage {
+			return nil, ErrMessageForbidden
+		}
+		if !message.IsDeleted {
+			err = s.MessageRepo.SoftDeleteMessage(messageID)
+		}
+		message.IsDeleted = true
+		redact(message)
+	}
+	if err != nil {
+		logger.Log.Error("Failed to delete message", slog.Int("message_id", messageID), slog.String("scope", string(scope)), slog.String("error", err.Error()))
+		return nil, err
+	}
+
+	logger.Log.Info("Message deleted", slog.Int("message_id", messageID), slog.Int("user_id", userID), slog.String("scope", string(scope)))
+	return message, nil
+}
+
+// GetMessageEdits - Edit history of a message for a chat member
+func (s *MessageService) GetMessageEdits(ctx context.Context, messageID, userID int) ([]entity.MessageEdit, error) {
+	message, err := s.memberMessage(messageID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if message.IsDeleted {
+		return nil, ErrMessageDeleted
+	}
+	return s.MessageRepo.GetMessageEdits(messageID)
+}
+
+// MarkMessageAsRead - Marks a message as read; only members of the message's chat can do it
 func (s *MessageService) MarkMessageAsRead(ctx context.Context, messageID, userID int) error {
+	if _, err := s.memberMessage(messageID, userID); err != nil {
+		return err
+	}
+
 	err := s.MessageRepo.MarkMessageAsRead(messageID, userID)
 	if err != nil {
 		logger.Log.Error("Failed to update read status", slog.Int("message_id", messageID), slog.Int("user_id", userID), slog.String("error", err.Error()))
@@ -69,3 +282,145 @@ func (s *MessageService) MarkMessageAsRead(ctx context.Context, messageID, userI
 	logger.Log.Info("Message marked as read", slog.Int("message_id", messageID), slog.Int("user_id", userID))
 	return nil
 }
+
+// MarkChatRead - Marks the chat as read up to and including upToMessageID; returns the remaining unread count
+func (s *MessageService) MarkChatRead(ctx context.Context, chatID, userID, upToMessageID int) (int, error) {
+	if err := s.ensureMember(chatID, userID); err != nil {
+		return 0, err
+	}
+
+	marked, err := s.MessageRepo.MarkChatReadUpTo(chatID, userID, upToMessageID)
+	if err != nil {
+		return 0, err
+	}
+
+	unread, err := s.MessageRepo.CountUnreadInChat(chatID, userID)
+	if err != nil {
+		return 0, err
+	}
+
+	logger.Log.Info("Chat marked as read", slog.Int("chat_id", chatID), slog.Int("user_id", userID), slog.Int("up_to_message_id", upToMessageID), slog.Int64("marked", marked))
+	return unread, nil
+}
+
+// GetUnreadBadge - Total unread messages across the user's chats
+func (s *MessageService) GetUnreadBadge(ctx context.Context, userID int) (*dto.UnreadBadge, error) {
+	total, chats, err := s.MessageRepo.CountUnread(userID)
+	if err != nil {
+		logger.Log.Error("Failed to count unread messages", slog.Int("user_id", userID), slog.String("error", err.Error()))
+		return nil, err
+	}
+	return &dto.UnreadBadge{Total: total, Chats: chats}, nil
+}
+
+func (s *MessageService) ensureMember(chatID, userID int) error {
+	isMember, err := s.ChatRepo.IsChatMember(chatID, userID)
+	if err != nil {
+		return err
+	}
+	if !isMember {
+		return ErrNotChatMember
+	}
+	return nil
+}
+
+// memberMessage загружает сообщение и проверяет, что пользователь состоит в его чате
+func (s *MessageService) memberMessage(messageID, userID int) (*entity.Message, error) {
+	message, err := s.MessageRepo.GetMessageByID(messageID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrMessageNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := s.ensureMember(message.ChatID, userID); err != nil {
+		return nil, err
+	}
+	return message, nil
+}
+
+func (s *MessageService) prepareOne(ctx context.Context, message *entity.Message, userID int) (*entity.Message, error) {
+	message.IsMine = message.SenderID == userID
+	messages := []entity.Message{*message}
+	if err := s.prepare(ctx, messages); err != nil {
+		return nil, err
+	}
+	return &messages[0], nil
+}
+
+// prepare готовит сообщения к выдаче: скрывает содержимое удалённых, подставляет прочитавших и цитаты,
+// подписывает ссылки
+func (s *MessageService) prepare(ctx context.Context, messages []entity.Message) error {
+	messageIDs := make([]int, 0, len(messages))
+	var parentIDs []int
+	for i := range messages {
+		messageIDs = append(messageIDs, messages[i].ID)
+		if messages[i].IsDeleted {
+			redact(&messages[i])
+		}
+		if messages[i].ReplyToID != nil {
+			parentIDs = append(parentIDs, *messages[i].ReplyToID)
+		}
+	}
+
+	if len(messageIDs) > 0 {
+		readers, err := s.MessageRepo.GetReadersByMessageIDs(messageIDs)
+		if err != nil {
+			return err
+		}
+		for i := range messages {
+			messages[i].ReadBy = readers[messages[i].ID]
+			if messages[i].ReadBy == nil {
+				messages[i].ReadBy = pq.Int64Array{}
+			}
+		}
+	}
+
+	if len(parentIDs) > 0 {
+		parents, err := s.MessageRepo.GetMessagesByIDs(uniqueInts(parentIDs))
+		if err != nil {
+			return err
+		}
+		quotes := make(map[int]*entity.MessageQuote, len(parents))
+		for i := range parents {
+			quotes[parents[i].ID] = quoteOf(&parents[i])
+		}
+		for i := range messages {
+			if messages[i].ReplyToID != nil && !messages[i].IsDeleted {
+				messages[i].ReplyTo = quotes[*messages[i].ReplyToID]
+			}
+		}
+	}
+
+	return s.sign(ctx, messages)
+}
+
+// redact убирает содержимое сообщения, удалённого для всех
+func redact(message *entity.Message) {
+	message.Content = nil
+	message.FileURL = nil
+	message.Attachment = nil
+	message.ReplyToID = nil
+	message.ReplyTo = nil
+	message.EditedAt = nil
+}
+
+func quoteOf(parent *entity.Message) *entity.MessageQuote {
+	quote := &entity.MessageQuote{ID: parent.ID, SenderID: parent.SenderID, Type: parent.Type, IsDeleted: parent.IsDeleted}
+	if !parent.IsDeleted {
+		quote.Content = parent.Content
+	}
+	return quote
+}
+
+// sign подписывает ссылки на вложения; срок ссылок короткий, поэтому они выдаются при каждом чтении
+func (s *MessageService) sign(ctx context.Context, messages []entity.Message) error {
+	if s.Attachments == nil {
+		return nil
+	}
+	if err := s.Attachments.Sign(ctx, messages); err != nil {
+		logger.Log.Error("Failed to sign attachment links", slog.String("error", err.Error()))
+		return err
+	}
+	return nil
+}
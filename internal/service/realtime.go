@@ -0,0 +1,39 @@
+package service
+
+import (
+	"jumyste-app-backend/internal/entity"
+	"jumyste-app-backend/internal/manager"
+)
+
+// RealtimePublisher доставляет события подключённым по WebSocket клиентам
+type RealtimePublisher interface {
+	PublishToChat(chatID int, eventType string, payload interface{})
+	PublishToUser(userID int, eventType string, payload interface{})
+	PublishToRole(roleID int, eventType string, payload interface{})
+	JoinChat(chatID int, userIDs ...int)
+	LeaveChat(chatID int, userIDs ...int)
+}
+
+// publishMessage сообщает участникам чата о сообщении, созданном сервисом (отклик, интервью)
+func publishMessage(realtime RealtimePublisher, message *entity.Message) {
+	if realtime == nil {
+		return
+	}
+	realtime.PublishToChat(message.ChatID, manager.EventMessageNew, message)
+}
+
+// joinChat подписывает открытые соединения участников на только что созданный чат
+func joinChat(realtime RealtimePublisher, chatID int, userIDs ...int) {
+	if realtime == nil {
+		return
+	}
+	realtime.JoinChat(chatID, userIDs...)
+}
+
+// leaveChat отписывает соединения пользователей, покинувших чат
+func leaveChat(realtime RealtimePublisher, chatID int, userIDs ...int) {
+	if realtime == nil {
+		return
+	}
+	realtime.LeaveChat(chatID, userIDs...)
+}
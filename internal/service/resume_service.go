@@ -18,11 +18,11 @@ import (
 )
 
 type ResumeService struct {
-	AIClient         *ai.OpenAIClient
+	AIClient         ai.Provider
 	ResumeRepository *repository.ResumeRepository
 }
 
-func NewResumeService(aiClient *ai.OpenAIClient, resumeRepo *repository.ResumeRepository) *ResumeService {
+func NewResumeService(aiClient ai.Provider, resumeRepo *repository.ResumeRepository) *ResumeService {
 	return &ResumeService{AIClient: aiClient, ResumeRepository: resumeRepo}
 }
 
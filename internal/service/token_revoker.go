@@ -0,0 +1,46 @@
+package service
+
+import (
+	"context"
+	"jumyste-app-backend/internal/repository"
+	"jumyste-app-backend/pkg/logger"
+	"jumyste-app-backend/utils"
+	"log/slog"
+	"time"
+)
+
+// TokenRevoker отзывает выданные токены: отдельный access-токен по jti или все токены и сессии пользователя
+type TokenRevoker struct {
+	Revocations repository.TokenRevocationStore
+	Sessions    *repository.SessionRepository
+}
+
+func NewTokenRevoker(revocations repository.TokenRevocationStore, sessions *repository.SessionRepository) *TokenRevoker {
+	return &TokenRevoker{Revocations: revocations, Sessions: sessions}
+}
+
+// RevokeAccessToken вносит access-токен в denylist до истечения его срока
+func (r *TokenRevoker) RevokeAccessToken(ctx context.Context, claims *utils.Claims) error {
+	if claims.ExpiresAt == nil {
+		return nil
+	}
+	return r.Revocations.RevokeToken(ctx, claims.ID, claims.ExpiresAt.Time)
+}
+
+// RevokeUser делает недействительными все выданные пользователю access-токены и отзывает его сессии;
+// reason попадает в лог (выход со всех устройств, сброс пароля, смена роли, исключение из компании).
+// Возвращает число отозванных сессий.
+func (r *TokenRevoker) RevokeUser(ctx context.Context, userID int, reason string) (int, error) {
+	if err := r.Revocations.SetTokensValidAfter(ctx, userID, time.Now()); err != nil {
+		logger.Log.Error("Failed to revoke user tokens", slog.Int("user_id", userID), slog.String("reason", reason), slog.String("error", err.Error()))
+		return 0, err
+	}
+	sessions, err := r.Sessions.DeleteSessionsByUserID(ctx, userID)
+	if err != nil {
+		logger.Log.Error("Failed to revoke user sessions", slog.Int("user_id", userID), slog.String("reason", reason), slog.String("error", err.Error()))
+		return 0, err
+	}
+
+	logger.Log.Info("User tokens revoked", slog.Int("user_id", userID), slog.String("reason", reason), slog.Int("sessions", sessions))
+	return sessions, nil
+}
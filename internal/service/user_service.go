@@ -1,6 +1,8 @@
 package service
 
 import (
+	"context"
+	"jumyste-app-backend/internal/dto"
 	"jumyste-app-backend/internal/entity"
 	"jumyste-app-backend/internal/repository"
 	"jumyste-app-backend/pkg/logger"
@@ -11,10 +13,11 @@ import (
 type UserService struct {
 	UserRepo    *repository.UserRepository
 	CompanyRepo *repository.CompanyRepository
+	Revoker     *TokenRevoker
 }
 
-func NewUserService(userRepo *repository.UserRepository, companyRepo *repository.CompanyRepository) *UserService {
-	return &UserService{UserRepo: userRepo, CompanyRepo: companyRepo}
+func NewUserService(userRepo *repository.UserRepository, companyRepo *repository.CompanyRepository, revoker *TokenRevoker) *UserService {
+	return &UserService{UserRepo: userRepo, CompanyRepo: companyRepo, Revoker: revoker}
 }
 
 func (s *UserService) CreateUser(user *entity.User) error {
@@ -64,7 +67,20 @@ func (s *UserService) GetUserByIDWithClaims(claims *utils.Claims) (*entity.UserR
 	logger.Log.Info("User fetched successfully", slog.Int("user_id", claims.UserID))
 	return user, nil
 }
-func (s *UserService) UpdateUser(userID int, updates map[string]interface{}) error {
+
+// UpdateUser меняет только поля профиля из UpdateUserRequest: роль, пароль и подтверждение email
+// через этот путь не меняются
+func (s *UserService) UpdateUser(userID int, req dto.UpdateUserRequest) error {
+	updates := make(map[string]interface{})
+	if req.FirstName != nil {
+		updates["first_name"] = *req.FirstName
+	}
+	if req.LastName != nil {
+		updates["last_name"] = *req.LastName
+	}
+	if req.ProfilePicture != nil {
+		updates["profile_picture"] = *req.ProfilePicture
+	}
 	logger.Log.Info("Service: Updating user", slog.Int("user_id", userID), slog.Any("updates", updates))
 
 	if err := s.UserRepo.UpdateUser(userID, updates); err != nil {
@@ -75,3 +91,19 @@ func (s *UserService) UpdateUser(userID int, updates map[string]interface{}) err
 	logger.Log.Info("User updated successfully in service", slog.Int("user_id", userID))
 	return nil
 }
+
+// ChangeRole - Единственный путь смены роли пользователя. Роль зашита в выданные токены,
+// поэтому после смены все сессии отзываются и пользователь входит заново.
+func (s *UserService) ChangeRole(ctx context.Context, userID, roleID int) error {
+	if err := s.UserRepo.UpdateRole(userID, roleID); err != nil {
+		logger.Log.Error("Failed to change user role", slog.Int("user_id", userID), slog.String("error", err.Error()))
+		return err
+	}
+
+	if _, err := s.Revoker.RevokeUser(ctx, userID, "role change"); err != nil {
+		return err
+	}
+
+	logger.Log.Info("User role changed", slog.Int("user_id", userID), slog.Int("role_id", roleID))
+	return nil
+}
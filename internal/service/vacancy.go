@@ -6,6 +6,8 @@ import (
 	"jumyste-app-backend/internal/ai"
 	"jumyste-app-backend/internal/dto"
 	"jumyste-app-backend/internal/entity"
+	"jumyste-app-backend/internal/manager"
+	"jumyste-app-backend/internal/policy"
 	"jumyste-app-backend/internal/repository"
 	"jumyste-app-backend/pkg/logger"
 	"log/slog"
@@ -13,13 +15,15 @@ import (
 
 type VacancyService struct {
 	repo     *repository.VacancyRepository
-	AiClient *ai.OpenAIClient
+	AiClient ai.Provider
+	Realtime RealtimePublisher
 }
 
-func NewVacancyService(repo *repository.VacancyRepository, aiClient *ai.OpenAIClient) *VacancyService {
+func NewVacancyService(repo *repository.VacancyRepository, aiClient ai.Provider, realtime RealtimePublisher) *VacancyService {
 	return &VacancyService{
 		repo:     repo,
 		AiClient: aiClient,
+		Realtime: realtime,
 	}
 }
 
@@ -33,6 +37,11 @@ func (s *VacancyService) CreateVacancy(v *entity.Vacancy) error {
 	}
 
 	logger.Log.Info("Vacancy created successfully", slog.String("title", v.Title), slog.Int("created_by", v.CreatedBy))
+
+	// Соискатели в сети узнают о новой открытой вакансии сразу
+	if s.Realtime != nil && v.Status != "closed" {
+		s.Realtime.PublishToRole(policy.RoleCandidate, manager.EventVacancyNew, v)
+	}
 	return nil
 }
 
@@ -74,19 +83,8 @@ func (s *VacancyService) GetVacancyById(id int, allowClosed bool) (*entity.Vacan
 	return vac, nil
 }
 
-func (s *VacancyService) DeleteVacancy(id, userID int) error {
-	vacancy, err := s.repo.GetVacancyById(id)
-	if err != nil {
-		logger.Log.Error("Vacancy not found", slog.Int("vacancy_id", id), slog.String("error", err.Error()))
-		return errors.New("vacancy not found")
-	}
-
-	if vacancy.CreatedBy != userID {
-		logger.Log.Warn("Unauthorized vacancy delete attempt",
-			slog.Int("vacancy_id", id), slog.Int("user_id", userID))
-		return errors.New("you can only delete your own vacancies")
-	}
-
+// DeleteVacancy удаляет вакансию; права на удаление проверяет policy.EditVacancy
+func (s *VacancyService) DeleteVacancy(id int) error {
 	return s.repo.DeleteVacancy(id)
 }
 
@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_job_applications_ai_status;
+
+ALTER TABLE job_applications
+    DROP COLUMN ai_status,
+    DROP COLUMN ai_attempts,
+    DROP COLUMN ai_error,
+    DROP COLUMN ai_updated_at;
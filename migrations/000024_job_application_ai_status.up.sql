@@ -0,0 +1,10 @@
+ALTER TABLE job_applications
+    ADD COLUMN ai_status     VARCHAR(20) NOT NULL DEFAULT 'pending'
+        CHECK (ai_status IN ('pending', 'processing', 'done', 'failed')),
+    ADD COLUMN ai_attempts   INTEGER     NOT NULL DEFAULT 0,
+    ADD COLUMN ai_error      TEXT,
+    ADD COLUMN ai_updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP;
+
+UPDATE job_applications SET ai_status = 'done' WHERE ai_matching_score IS NOT NULL;
+
+CREATE INDEX idx_job_applications_ai_status ON job_applications (ai_status);
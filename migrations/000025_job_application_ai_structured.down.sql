@@ -0,0 +1,4 @@
+ALTER TABLE job_applications
+    DROP COLUMN IF EXISTS ai_breakdown,
+    ALTER COLUMN ai_strengths TYPE TEXT USING array_to_string(ai_strengths, ', '),
+    ALTER COLUMN ai_weaknesses TYPE TEXT USING array_to_string(ai_weaknesses, ', ');
@@ -0,0 +1,6 @@
+ALTER TABLE job_applications
+    ALTER COLUMN ai_strengths TYPE TEXT[] USING
+        CASE WHEN ai_strengths IS NULL OR ai_strengths = '' THEN '{}'::TEXT[] ELSE string_to_array(ai_strengths, ', ') END,
+    ALTER COLUMN ai_weaknesses TYPE TEXT[] USING
+        CASE WHEN ai_weaknesses IS NULL OR ai_weaknesses = '' THEN '{}'::TEXT[] ELSE string_to_array(ai_weaknesses, ', ') END,
+    ADD COLUMN ai_breakdown JSONB;
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_job_applications_rule_score;
+
+ALTER TABLE job_applications
+    DROP COLUMN IF EXISTS rule_breakdown,
+    DROP COLUMN IF EXISTS rule_score;
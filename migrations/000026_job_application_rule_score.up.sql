@@ -0,0 +1,5 @@
+ALTER TABLE job_applications
+    ADD COLUMN rule_score     INTEGER,
+    ADD COLUMN rule_breakdown JSONB;
+
+CREATE INDEX idx_job_applications_rule_score ON job_applications (vacancy_id, rule_score DESC);
@@ -0,0 +1,9 @@
+DROP TABLE IF EXISTS job_application_status_history;
+
+UPDATE job_applications SET status = 'new' WHERE status = 'screening';
+UPDATE job_applications SET status = 'interview' WHERE status = 'offer';
+
+ALTER TABLE job_applications DROP CONSTRAINT IF EXISTS job_applications_status_check;
+ALTER TABLE job_applications
+    ADD CONSTRAINT job_applications_status_check
+        CHECK (status IN ('new', 'invited', 'interview', 'accepted', 'rejected'));
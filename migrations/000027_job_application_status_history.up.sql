@@ -0,0 +1,23 @@
+ALTER TABLE job_applications DROP CONSTRAINT IF EXISTS job_applications_status_check;
+ALTER TABLE job_applications
+    ADD CONSTRAINT job_applications_status_check
+        CHECK (status IN ('new', 'screening', 'invited', 'interview', 'offer', 'accepted', 'rejected'));
+
+CREATE TABLE job_application_status_history
+(
+    id             SERIAL PRIMARY KEY,
+    application_id INT         NOT NULL REFERENCES job_applications (id) ON DELETE CASCADE,
+    from_status    VARCHAR(50),
+    to_status      VARCHAR(50) NOT NULL,
+    actor_id       INT         REFERENCES users (id) ON DELETE SET NULL,
+    comment        TEXT,
+    created_at     TIMESTAMP   NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_job_application_status_history_application
+    ON job_application_status_history (application_id, created_at);
+
+-- Для существующих откликов восстанавливаем хотя бы текущий статус на момент отклика
+INSERT INTO job_application_status_history (application_id, from_status, to_status, actor_id, created_at)
+SELECT id, NULL, COALESCE(status, 'new'), NULL, COALESCE(applied_at, CURRENT_TIMESTAMP)
+FROM job_applications;
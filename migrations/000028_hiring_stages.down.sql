@@ -0,0 +1,10 @@
+DROP INDEX IF EXISTS idx_job_applications_stage;
+
+ALTER TABLE job_application_status_history
+    DROP COLUMN IF EXISTS from_stage_id,
+    DROP COLUMN IF EXISTS to_stage_id;
+
+ALTER TABLE job_applications
+    DROP COLUMN IF EXISTS stage_id;
+
+DROP TABLE IF EXISTS hiring_stages;
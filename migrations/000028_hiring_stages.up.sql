@@ -0,0 +1,42 @@
+CREATE TABLE hiring_stages
+(
+    id         SERIAL PRIMARY KEY,
+    company_id INTEGER      NOT NULL REFERENCES companies (id) ON DELETE CASCADE,
+    vacancy_id INTEGER REFERENCES vacancies (id) ON DELETE CASCADE,
+    name       VARCHAR(100) NOT NULL,
+    status     VARCHAR(50)  NOT NULL
+        CHECK (status IN ('new', 'screening', 'invited', 'interview', 'offer', 'accepted', 'rejected')),
+    position   INTEGER      NOT NULL,
+    created_at TIMESTAMP    NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_hiring_stages_scope ON hiring_stages (company_id, vacancy_id, position);
+
+ALTER TABLE job_applications
+    ADD COLUMN stage_id INTEGER REFERENCES hiring_stages (id) ON DELETE SET NULL;
+
+ALTER TABLE job_application_status_history
+    ADD COLUMN from_stage_id INTEGER REFERENCES hiring_stages (id) ON DELETE SET NULL,
+    ADD COLUMN to_stage_id   INTEGER REFERENCES hiring_stages (id) ON DELETE SET NULL;
+
+-- Стандартная воронка для уже существующих компаний
+INSERT INTO hiring_stages (company_id, vacancy_id, name, status, position)
+SELECT c.id, NULL, d.name, d.status, d.position
+FROM companies c
+         CROSS JOIN (VALUES ('Новый', 'new', 1),
+                            ('Скрининг', 'screening', 2),
+                            ('Приглашён', 'invited', 3),
+                            ('Интервью', 'interview', 4),
+                            ('Оффер', 'offer', 5),
+                            ('Принят', 'accepted', 6),
+                            ('Отказ', 'rejected', 7)) AS d(name, status, position);
+
+UPDATE job_applications ja
+SET stage_id = hs.id
+FROM vacancies v, hiring_stages hs
+WHERE v.id = ja.vacancy_id
+  AND hs.company_id = v.company_id
+  AND hs.vacancy_id IS NULL
+  AND hs.status = ja.status;
+
+CREATE INDEX idx_job_applications_stage ON job_applications (stage_id);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS interview_interviewers;
+DROP TABLE IF EXISTS interviews;
@@ -0,0 +1,30 @@
+CREATE TABLE interviews
+(
+    id               SERIAL PRIMARY KEY,
+    application_id   INTEGER     NOT NULL REFERENCES job_applications (id) ON DELETE CASCADE,
+    organizer_id     INTEGER REFERENCES users (id) ON DELETE SET NULL,
+    start_at         TIMESTAMPTZ NOT NULL,
+    end_at           TIMESTAMPTZ NOT NULL,
+    format           VARCHAR(20) NOT NULL CHECK (format IN ('online', 'offline', 'phone')),
+    location         TEXT,
+    meeting_link     TEXT,
+    notes            TEXT,
+    status           VARCHAR(20) NOT NULL DEFAULT 'scheduled'
+        CHECK (status IN ('scheduled', 'completed', 'cancelled')),
+    sequence         INTEGER     NOT NULL DEFAULT 0,
+    reminder_sent_at TIMESTAMPTZ,
+    created_at       TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at       TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CHECK (end_at > start_at)
+);
+
+CREATE TABLE interview_interviewers
+(
+    interview_id INTEGER NOT NULL REFERENCES interviews (id) ON DELETE CASCADE,
+    user_id      INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    PRIMARY KEY (interview_id, user_id)
+);
+
+CREATE INDEX idx_interviews_application ON interviews (application_id);
+CREATE INDEX idx_interviews_scheduled_start ON interviews (start_at) WHERE status = 'scheduled';
+CREATE INDEX idx_interview_interviewers_user ON interview_interviewers (user_id);
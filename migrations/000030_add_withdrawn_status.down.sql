@@ -0,0 +1,8 @@
+DROP INDEX IF EXISTS idx_job_applications_user;
+
+UPDATE job_applications SET status = 'rejected' WHERE status = 'withdrawn';
+
+ALTER TABLE job_applications DROP CONSTRAINT IF EXISTS job_applications_status_check;
+ALTER TABLE job_applications
+    ADD CONSTRAINT job_applications_status_check
+        CHECK (status IN ('new', 'screening', 'invited', 'interview', 'offer', 'accepted', 'rejected'));
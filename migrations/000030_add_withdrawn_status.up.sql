@@ -0,0 +1,6 @@
+ALTER TABLE job_applications DROP CONSTRAINT IF EXISTS job_applications_status_check;
+ALTER TABLE job_applications
+    ADD CONSTRAINT job_applications_status_check
+        CHECK (status IN ('new', 'screening', 'invited', 'interview', 'offer', 'accepted', 'rejected', 'withdrawn'));
+
+CREATE INDEX IF NOT EXISTS idx_job_applications_user ON job_applications (user_id, applied_at DESC);
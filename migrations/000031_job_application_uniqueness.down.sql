@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS uq_job_applications_idempotency_key;
+ALTER TABLE job_applications DROP COLUMN IF EXISTS idempotency_key;
+DROP INDEX IF EXISTS uq_job_applications_active;
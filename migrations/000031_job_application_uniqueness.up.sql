@@ -0,0 +1,27 @@
+-- Лишние активные дубли (оставляем самый ранний отклик) закрываем как отозванные, чтобы построить уникальный индекс
+WITH duplicates AS (SELECT id, status, stage_id, user_id
+                    FROM (SELECT id, status, stage_id, user_id,
+                                 ROW_NUMBER() OVER (PARTITION BY user_id, vacancy_id ORDER BY applied_at, id) AS rn
+                          FROM job_applications
+                          WHERE status NOT IN ('rejected', 'withdrawn')) ranked
+                    WHERE rn > 1),
+     history AS (
+         INSERT INTO job_application_status_history (application_id, from_status, to_status, from_stage_id, actor_id, comment)
+             SELECT id, status, 'withdrawn', stage_id, user_id, 'Повторный отклик закрыт автоматически'
+             FROM duplicates)
+UPDATE job_applications ja
+SET status   = 'withdrawn',
+    stage_id = NULL
+FROM duplicates d
+WHERE ja.id = d.id;
+
+-- Одна активная заявка на пару (соискатель, вакансия); после отказа или отзыва можно откликнуться снова
+CREATE UNIQUE INDEX uq_job_applications_active
+    ON job_applications (user_id, vacancy_id)
+    WHERE status NOT IN ('rejected', 'withdrawn');
+
+ALTER TABLE job_applications ADD COLUMN idempotency_key VARCHAR(255);
+
+CREATE UNIQUE INDEX uq_job_applications_idempotency_key
+    ON job_applications (user_id, idempotency_key)
+    WHERE idempotency_key IS NOT NULL;
@@ -0,0 +1,6 @@
+ALTER TABLE messages
+    DROP COLUMN IF EXISTS thumbnail_key,
+    DROP COLUMN IF EXISTS attachment_size,
+    DROP COLUMN IF EXISTS attachment_mime,
+    DROP COLUMN IF EXISTS attachment_name,
+    DROP COLUMN IF EXISTS attachment_key;
@@ -0,0 +1,6 @@
+ALTER TABLE messages
+    ADD COLUMN IF NOT EXISTS attachment_key  TEXT,
+    ADD COLUMN IF NOT EXISTS attachment_name TEXT,
+    ADD COLUMN IF NOT EXISTS attachment_mime TEXT,
+    ADD COLUMN IF NOT EXISTS attachment_size BIGINT,
+    ADD COLUMN IF NOT EXISTS thumbnail_key   TEXT;
@@ -0,0 +1,5 @@
+DROP TRIGGER IF EXISTS trg_update_message_search_vector ON messages;
+DROP FUNCTION IF EXISTS update_message_search_vector();
+DROP INDEX IF EXISTS idx_messages_chat_id_id;
+DROP INDEX IF EXISTS idx_messages_search;
+ALTER TABLE messages DROP COLUMN IF EXISTS search_vector;
@@ -0,0 +1,21 @@
+ALTER TABLE messages ADD COLUMN search_vector tsvector;
+
+UPDATE messages
+SET search_vector = to_tsvector('russian', coalesce(content, '') || ' ' || coalesce(attachment_name, ''));
+
+CREATE INDEX idx_messages_search ON messages USING GIN(search_vector);
+
+-- Курсорная пагинация истории чата идёт по id внутри чата
+CREATE INDEX idx_messages_chat_id_id ON messages (chat_id, id);
+
+CREATE FUNCTION update_message_search_vector() RETURNS trigger AS $$
+BEGIN
+    NEW.search_vector = to_tsvector('russian', coalesce(NEW.content, '') || ' ' || coalesce(NEW.attachment_name, ''));
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER trg_update_message_search_vector
+    BEFORE INSERT OR UPDATE OF content, attachment_name ON messages
+    FOR EACH ROW
+EXECUTE FUNCTION update_message_search_vector();
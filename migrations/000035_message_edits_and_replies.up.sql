@@ -0,0 +1,28 @@
+ALTER TABLE messages
+    ADD COLUMN reply_to_id INTEGER REFERENCES messages (id) ON DELETE SET NULL,
+    ADD COLUMN edited_at   TIMESTAMP,
+    ADD COLUMN deleted_at  TIMESTAMP,
+    ADD COLUMN updated_at  TIMESTAMP;
+
+UPDATE messages SET updated_at = created_at;
+ALTER TABLE messages ALTER COLUMN updated_at SET DEFAULT now();
+
+-- Предыдущие версии текста при редактировании
+CREATE TABLE message_edits
+(
+    id         SERIAL PRIMARY KEY,
+    message_id INTEGER NOT NULL REFERENCES messages (id) ON DELETE CASCADE,
+    content    TEXT,
+    edited_at  TIMESTAMP NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_message_edits_message_id ON message_edits (message_id, id);
+
+-- Сообщения, удалённые пользователем только у себя
+CREATE TABLE message_hidden
+(
+    message_id INTEGER NOT NULL REFERENCES messages (id) ON DELETE CASCADE,
+    user_id    INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    hidden_at  TIMESTAMP NOT NULL DEFAULT now(),
+    PRIMARY KEY (user_id, message_id)
+);
@@ -0,0 +1,12 @@
+DROP INDEX IF EXISTS idx_message_seen_by_user_id;
+
+ALTER TABLE messages ADD COLUMN read_by INTEGER[] DEFAULT '{}';
+
+UPDATE messages m
+SET read_by = s.user_ids
+FROM (SELECT message_id, array_agg(user_id ORDER BY seen_at) AS user_ids
+      FROM message_seen_by
+      GROUP BY message_id) s
+WHERE s.message_id = m.id;
+
+ALTER TABLE message_seen_by DROP COLUMN seen_at;
@@ -0,0 +1,14 @@
+ALTER TABLE message_seen_by ADD COLUMN seen_at TIMESTAMP NOT NULL DEFAULT now();
+
+-- Переносим отметки о прочтении из messages.read_by
+INSERT INTO message_seen_by (message_id, user_id)
+SELECT m.id, r.user_id
+FROM messages m
+         CROSS JOIN LATERAL unnest(m.read_by) AS r(user_id)
+         JOIN users u ON u.id = r.user_id
+ON CONFLICT DO NOTHING;
+
+ALTER TABLE messages DROP COLUMN read_by;
+
+-- Подсчёт непрочитанных: «есть ли отметка этого пользователя на сообщении»
+CREATE INDEX idx_message_seen_by_user_id ON message_seen_by (user_id, message_id);
@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_chats_vacancy_id;
+DROP INDEX IF EXISTS uq_chats_application_id;
+
+ALTER TABLE chats
+    DROP COLUMN IF EXISTS application_id,
+    DROP COLUMN IF EXISTS vacancy_id;
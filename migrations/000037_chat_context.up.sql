@@ -0,0 +1,24 @@
+ALTER TABLE chats
+    ADD COLUMN vacancy_id     INTEGER REFERENCES vacancies (id) ON DELETE SET NULL,
+    ADD COLUMN application_id INTEGER REFERENCES job_applications (id) ON DELETE SET NULL;
+
+-- Один чат на отклик
+CREATE UNIQUE INDEX uq_chats_application_id ON chats (application_id) WHERE application_id IS NOT NULL;
+CREATE INDEX idx_chats_vacancy_id ON chats (vacancy_id);
+
+-- Старые чаты пары «соискатель — автор вакансии» привязываем к отклику, если он у пары единственный
+WITH single AS (SELECT cu1.chat_id, min(ja.id) AS application_id, min(ja.vacancy_id) AS vacancy_id
+                FROM chat_users cu1
+                         JOIN chat_users cu2 ON cu2.chat_id = cu1.chat_id AND cu2.user_id <> cu1.user_id
+                         JOIN job_applications ja ON ja.user_id = cu1.user_id
+                         JOIN vacancies v ON v.id = ja.vacancy_id AND v.created_by = cu2.user_id
+                GROUP BY cu1.chat_id
+                HAVING count(*) = 1),
+     first_chat AS (SELECT DISTINCT ON (application_id) chat_id, application_id, vacancy_id
+                    FROM single
+                    ORDER BY application_id, chat_id)
+UPDATE chats c
+SET application_id = f.application_id,
+    vacancy_id     = f.vacancy_id
+FROM first_chat f
+WHERE f.chat_id = c.id;
@@ -0,0 +1,18 @@
+DELETE FROM messages WHERE type = 'system';
+ALTER TABLE messages DROP CONSTRAINT messages_type_check;
+ALTER TABLE messages ADD CONSTRAINT messages_type_check CHECK (type IN ('text', 'image', 'video', 'audio', 'file'));
+
+DELETE FROM chats WHERE is_internal;
+DROP INDEX uq_chats_application_id;
+CREATE UNIQUE INDEX uq_chats_application_id ON chats (application_id) WHERE application_id IS NOT NULL;
+
+ALTER TABLE chat_users
+    DROP COLUMN IF EXISTS joined_at,
+    DROP COLUMN IF EXISTS role;
+
+ALTER TABLE chats
+    DROP COLUMN IF EXISTS created_by,
+    DROP COLUMN IF EXISTS company_id,
+    DROP COLUMN IF EXISTS is_internal,
+    DROP COLUMN IF EXISTS title,
+    DROP COLUMN IF EXISTS is_group;
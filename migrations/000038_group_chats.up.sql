@@ -0,0 +1,18 @@
+ALTER TABLE chats
+    ADD COLUMN is_group    BOOLEAN NOT NULL DEFAULT false,
+    ADD COLUMN title       VARCHAR(255),
+    ADD COLUMN is_internal BOOLEAN NOT NULL DEFAULT false,
+    ADD COLUMN company_id  INTEGER REFERENCES companies (id) ON DELETE CASCADE,
+    ADD COLUMN created_by  INTEGER REFERENCES users (id) ON DELETE SET NULL;
+
+ALTER TABLE chat_users
+    ADD COLUMN role      VARCHAR(10) NOT NULL DEFAULT 'member' CHECK (role IN ('member', 'admin')),
+    ADD COLUMN joined_at TIMESTAMP   NOT NULL DEFAULT now();
+
+-- Внутренние чаты HR могут обсуждать отклик, у которого уже есть чат с соискателем
+DROP INDEX uq_chats_application_id;
+CREATE UNIQUE INDEX uq_chats_application_id ON chats (application_id) WHERE application_id IS NOT NULL AND NOT is_internal;
+
+-- Системные сообщения о составе участников
+ALTER TABLE messages DROP CONSTRAINT messages_type_check;
+ALTER TABLE messages ADD CONSTRAINT messages_type_check CHECK (type IN ('text', 'image', 'video', 'audio', 'file', 'system'));
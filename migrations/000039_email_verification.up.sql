@@ -0,0 +1,14 @@
+ALTER TABLE users ADD COLUMN email_verified_at TIMESTAMP;
+
+-- Аккаунты, созданные до появления подтверждения, считаются подтверждёнными
+UPDATE users SET email_verified_at = COALESCE(created_at, now());
+
+CREATE TABLE email_verifications
+(
+    id         SERIAL PRIMARY KEY,
+    user_id    INTEGER    NOT NULL UNIQUE REFERENCES users (id) ON DELETE CASCADE,
+    code       VARCHAR(6) NOT NULL,
+    attempts   INTEGER    NOT NULL DEFAULT 0,
+    expires_at TIMESTAMP  NOT NULL,
+    created_at TIMESTAMP  NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
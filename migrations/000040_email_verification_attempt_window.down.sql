@@ -0,0 +1,3 @@
+ALTER TABLE email_verifications
+    DROP COLUMN IF EXISTS window_attempts,
+    DROP COLUMN IF EXISTS window_started_at;
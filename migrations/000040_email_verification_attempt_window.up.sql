@@ -0,0 +1,4 @@
+-- Неверные вводы считаются по пользователю в скользящем окне и не сбрасываются повторной отправкой кода
+ALTER TABLE email_verifications
+    ADD COLUMN window_attempts   INTEGER   NOT NULL DEFAULT 0,
+    ADD COLUMN window_started_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP;
@@ -0,0 +1,106 @@
+package calendar
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+const icsTimeLayout = "20060102T150405Z"
+
+type Attendee struct {
+	Name  string
+	Email string
+}
+
+// Event — одно событие календаря. Sequence увеличивается при каждом изменении,
+// чтобы календарные клиенты обновляли уже импортированное событие по UID.
+type Event struct {
+	UID         string
+	Summary     string
+	Description string
+	Location    string
+	URL         string
+	Start       time.Time
+	End         time.Time
+	Organizer   Attendee
+	Attendees   []Attendee
+	Sequence    int
+	Cancelled   bool
+}
+
+// ICS формирует файл iCalendar (RFC 5545) с одним событием
+func (e Event) ICS() []byte {
+	method, status := "REQUEST", "CONFIRMED"
+	if e.Cancelled {
+		method, status = "CANCEL", "CANCELLED"
+	}
+
+	lines := []string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"PRODID:-//Jumyste//Interviews//RU",
+		"CALSCALE:GREGORIAN",
+		"METHOD:" + method,
+		"BEGIN:VEVENT",
+		"UID:" + e.UID,
+		"DTSTAMP:" + time.Now().UTC().Format(icsTimeLayout),
+		"DTSTART:" + e.Start.UTC().Format(icsTimeLayout),
+		"DTEND:" + e.End.UTC().Format(icsTimeLayout),
+		fmt.Sprintf("SEQUENCE:%d", e.Sequence),
+		"STATUS:" + status,
+		"SUMMARY:" + escape(e.Summary),
+	}
+	if e.Description != "" {
+		lines = append(lines, "DESCRIPTION:"+escape(e.Description))
+	}
+	if e.Location != "" {
+		lines = append(lines, "LOCATION:"+escape(e.Location))
+	}
+	if e.URL != "" {
+		lines = append(lines, "URL:"+e.URL)
+	}
+	if e.Organizer.Email != "" {
+		lines = append(lines, fmt.Sprintf("ORGANIZER;CN=%s:mailto:%s", escapeParam(e.Organizer.Name), e.Organizer.Email))
+	}
+	for _, attendee := range e.Attendees {
+		lines = append(lines, fmt.Sprintf("ATTENDEE;CN=%s;ROLE=REQ-PARTICIPANT;RSVP=TRUE:mailto:%s", escapeParam(attendee.Name), attendee.Email))
+	}
+	lines = append(lines, "END:VEVENT", "END:VCALENDAR")
+
+	var b strings.Builder
+	for _, line := range lines {
+		b.WriteString(fold(line))
+		b.WriteString("\r\n")
+	}
+	return []byte(b.String())
+}
+
+func escape(value string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`).Replace(value)
+}
+
+func escapeParam(value string) string {
+	return `"` + strings.ReplaceAll(value, `"`, "'") + `"`
+}
+
+// fold переносит строки длиннее 75 байт, не разрывая UTF-8 символы
+func fold(line string) string {
+	const limit = 75
+	if len(line) <= limit {
+		return line
+	}
+
+	var b strings.Builder
+	size := 0
+	for _, r := range line {
+		width := len(string(r))
+		if size+width > limit {
+			b.WriteString("\r\n ")
+			size = 1
+		}
+		b.WriteRune(r)
+		size += width
+	}
+	return b.String()
+}
@@ -0,0 +1,59 @@
+package jwtkeys
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"math/big"
+	"sort"
+)
+
+// JWK — открытый ключ в формате RFC 7517
+type JWK struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	N         string `json:"n,omitempty"`
+	E         string `json:"e,omitempty"`
+	Curve     string `json:"crv,omitempty"`
+	X         string `json:"x,omitempty"`
+}
+
+// JWKS — набор ключей, которыми другие сервисы проверяют токены
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS возвращает все активные открытые ключи; секрет HS256 не публикуется
+func (m *Manager) JWKS() JWKS {
+	set := JWKS{Keys: make([]JWK, 0, len(m.public))}
+	for kid, public := range m.public {
+		switch key := public.(type) {
+		case *rsa.PublicKey:
+			set.Keys = append(set.Keys, JWK{
+				KeyType:   "RSA",
+				KeyID:     kid,
+				Use:       "sig",
+				Algorithm: AlgRS256,
+				N:         encode(key.N.Bytes()),
+				E:         encode(big.NewInt(int64(key.E)).Bytes()),
+			})
+		case ed25519.PublicKey:
+			set.Keys = append(set.Keys, JWK{
+				KeyType:   "OKP",
+				KeyID:     kid,
+				Use:       "sig",
+				Algorithm: AlgEdDSA,
+				Curve:     "Ed25519",
+				X:         encode(key),
+			})
+		}
+	}
+	sort.Slice(set.Keys, func(i, j int) bool { return set.Keys[i].KeyID < set.Keys[j].KeyID })
+	return set
+}
+
+func encode(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
@@ -0,0 +1,231 @@
+package jwtkeys
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Поддерживаемые алгоритмы подписи
+const (
+	AlgHS256 = "HS256"
+	AlgRS256 = "RS256"
+	AlgEdDSA = "EdDSA"
+)
+
+var (
+	ErrUnknownKey        = errors.New("unknown signing key")
+	ErrAlgorithmMismatch = errors.New("token algorithm does not match the key")
+)
+
+// Config — ключи подписи. Для HS256 нужен Secret; для RS256 и EdDSA — закрытый ключ в PEM
+// (PrivateKey или PrivateKeyFile). VerificationKeys — прежние открытые ключи в виде "kid=путь,kid=путь":
+// токены, подписанные ими, принимаются до окончания ротации.
+type Config struct {
+	Algorithm        string
+	KeyID            string
+	Secret           string
+	PrivateKey       string
+	PrivateKeyFile   string
+	VerificationKeys string
+}
+
+type verificationKey struct {
+	method jwt.SigningMethod
+	key    interface{}
+}
+
+// Manager подписывает токены текущим ключом и проверяет их любым активным ключом по заголовку kid
+type Manager struct {
+	keyID      string
+	method     jwt.SigningMethod
+	signingKey interface{}
+	verify     map[string]verificationKey
+	// public — открытые ключи для JWKS; у HS256 их нет
+	public map[string]crypto.PublicKey
+}
+
+func NewManager(cfg Config) (*Manager, error) {
+	m := &Manager{
+		keyID:  cfg.KeyID,
+		verify: make(map[string]verificationKey),
+		public: make(map[string]crypto.PublicKey),
+	}
+
+	switch cfg.Algorithm {
+	case "", AlgHS256:
+		if cfg.Secret == "" {
+			return nil, errors.New("jwt secret is required for HS256")
+		}
+		m.method = jwt.SigningMethodHS256
+		m.signingKey = []byte(cfg.Secret)
+		m.verify[m.keyID] = verificationKey{method: m.method, key: m.signingKey}
+	case AlgRS256, AlgEdDSA:
+		if m.keyID == "" {
+			return nil, fmt.Errorf("key id is required for %s", cfg.Algorithm)
+		}
+		data, err := readPEM(cfg.PrivateKey, cfg.PrivateKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		signer, err := parsePrivateKey(data)
+		if err != nil {
+			return nil, err
+		}
+		method, err := methodFor(signer.Public())
+		if err != nil {
+			return nil, err
+		}
+		if method.Alg() != cfg.Algorithm {
+			return nil, fmt.Errorf("private key is not a %s key", cfg.Algorithm)
+		}
+		m.method = method
+		m.signingKey = signer
+		m.addPublic(m.keyID, method, signer.Public())
+	default:
+		return nil, fmt.Errorf("unsupported jwt algorithm %q", cfg.Algorithm)
+	}
+
+	if err := m.loadVerificationKeys(cfg.VerificationKeys); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Sign подписывает claims текущим ключом и указывает его kid в заголовке
+func (m *Manager) Sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(m.method, claims)
+	if m.keyID != "" {
+		token.Header["kid"] = m.keyID
+	}
+	return token.SignedString(m.signingKey)
+}
+
+// Parse проверяет подпись ключом из заголовка kid и разбирает claims
+func (m *Manager) Parse(tokenString string, claims jwt.Claims) (*jwt.Token, error) {
+	return jwt.ParseWithClaims(tokenString, claims, m.keyFunc, jwt.WithValidMethods(m.validMethods()))
+}
+
+// keyFunc выбирает ключ по kid; токены без kid проверяются текущим ключом.
+// Алгоритм токена обязан совпадать с алгоритмом ключа.
+func (m *Manager) keyFunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	key, ok := m.verify[kid]
+	if !ok && kid == "" {
+		key, ok = m.verify[m.keyID]
+	}
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownKey, kid)
+	}
+	if token.Method.Alg() != key.method.Alg() {
+		return nil, ErrAlgorithmMismatch
+	}
+	return key.key, nil
+}
+
+func (m *Manager) validMethods() []string {
+	seen := make(map[string]struct{})
+	var methods []string
+	for _, key := range m.verify {
+		if _, ok := seen[key.method.Alg()]; !ok {
+			seen[key.method.Alg()] = struct{}{}
+			methods = append(methods, key.method.Alg())
+		}
+	}
+	return methods
+}
+
+func (m *Manager) loadVerificationKeys(spec string) error {
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		kid, path, ok := strings.Cut(entry, "=")
+		if !ok || kid == "" || path == "" {
+			return fmt.Errorf("invalid verification key %q, expected kid=path", entry)
+		}
+		if _, exists := m.verify[kid]; exists {
+			return fmt.Errorf("duplicate key id %q", kid)
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("read verification key %q: %w", kid, err)
+		}
+		public, err := parsePublicKey(data)
+		if err != nil {
+			return fmt.Errorf("verification key %q: %w", kid, err)
+		}
+		method, err := methodFor(public)
+		if err != nil {
+			return fmt.Errorf("verification key %q: %w", kid, err)
+		}
+		m.addPublic(kid, method, public)
+	}
+	return nil
+}
+
+func (m *Manager) addPublic(kid string, method jwt.SigningMethod, public crypto.PublicKey) {
+	m.verify[kid] = verificationKey{method: method, key: public}
+	m.public[kid] = public
+}
+
+func readPEM(inline, path string) ([]byte, error) {
+	if inline != "" {
+		// В переменных окружения переводы строк часто передают как \n
+		return []byte(strings.ReplaceAll(inline, `\n`, "\n")), nil
+	}
+	if path == "" {
+		return nil, errors.New("private key is required for asymmetric jwt algorithms")
+	}
+	return os.ReadFile(path)
+}
+
+func parsePrivateKey(data []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("private key is not PEM encoded")
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("parse private key: %w", err)
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, errors.New("private key cannot sign")
+	}
+	return signer, nil
+}
+
+func parsePublicKey(data []byte) (crypto.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("public key is not PEM encoded")
+	}
+	if key, err := x509.ParsePKCS1PublicKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	return x509.ParsePKIXPublicKey(block.Bytes)
+}
+
+func methodFor(public crypto.PublicKey) (jwt.SigningMethod, error) {
+	switch public.(type) {
+	case *rsa.PublicKey:
+		return jwt.SigningMethodRS256, nil
+	case ed25519.PublicKey:
+		return jwt.SigningMethodEdDSA, nil
+	}
+	return nil, fmt.Errorf("unsupported key type %T", public)
+}
@@ -1,16 +1,35 @@
 package mail
 
 import (
+	"bytes"
+	"encoding/base64"
 	"fmt"
 	"jumyste-app-backend/config"
 	"jumyste-app-backend/pkg/logger"
+	"mime"
+	"mime/multipart"
 	"net/smtp"
+	"net/textproto"
+	"strings"
 )
 
+type Attachment struct {
+	Filename    string
+	ContentType string
+	Data        []byte
+}
+
+// encodeSubject кодирует тему по RFC 2047, чтобы кириллица не портилась, а переводы строк
+// из темы (например, из названия вакансии) не добавляли в письмо свои заголовки
+func encodeSubject(subject string) string {
+	subject = strings.NewReplacer("\r", " ", "\n", " ").Replace(subject)
+	return mime.QEncoding.Encode("UTF-8", subject)
+}
+
 func SendEmail(to, subject, body string) error {
 	smtpConfig := config.AppConfig.SMTP
 
-	logger.Log.Info("SMTP_HOST: %s, SMTP_PORT: %s, SMTP_SENDER: %s", smtpConfig.Host, smtpConfig.Port, smtpConfig.Sender)
+	logger.Log.Info("Sending email", "smtp_host", smtpConfig.Host, "smtp_port", smtpConfig.Port, "sender", smtpConfig.Sender)
 
 	addr := fmt.Sprintf("%s:%s", smtpConfig.Host, smtpConfig.Port)
 
@@ -18,17 +37,67 @@ func SendEmail(to, subject, body string) error {
 
 	msg := []byte("From: " + smtpConfig.Sender + "\r\n" +
 		"To: " + to + "\r\n" +
-		"Subject: " + subject + "\r\n" +
+		"Subject: " + encodeSubject(subject) + "\r\n" +
 		"MIME-Version: 1.0\r\n" +
 		"Content-Type: text/plain; charset=UTF-8\r\n\r\n" +
 		body)
 
 	err := smtp.SendMail(addr, auth, smtpConfig.Sender, []string{to}, msg)
 	if err != nil {
-		logger.Log.Error("Ошибка при отправке email: %v", err)
+		logger.Log.Error("Ошибка при отправке email", "to", to, "error", err)
+		return err
+	}
+
+	logger.Log.Info("Email отправлен", "to", to)
+	return nil
+}
+
+// SendEmailWithAttachment отправляет письмо с текстом и одним вложением (multipart/mixed)
+func SendEmailWithAttachment(to, subject, body string, attachment Attachment) error {
+	smtpConfig := config.AppConfig.SMTP
+
+	var buf bytes.Buffer
+	writer := multipart.NewWriter(&buf)
+
+	buf.WriteString("From: " + smtpConfig.Sender + "\r\n" +
+		"To: " + to + "\r\n" +
+		"Subject: " + encodeSubject(subject) + "\r\n" +
+		"MIME-Version: 1.0\r\n" +
+		"Content-Type: multipart/mixed; boundary=" + writer.Boundary() + "\r\n\r\n")
+
+	textPart, err := writer.CreatePart(textproto.MIMEHeader{"Content-Type": {"text/plain; charset=UTF-8"}})
+	if err != nil {
+		return err
+	}
+	textPart.Write([]byte(body))
+
+	filePart, err := writer.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {attachment.ContentType},
+		"Content-Transfer-Encoding": {"base64"},
+		"Content-Disposition":       {fmt.Sprintf("attachment; filename=%q", attachment.Filename)},
+	})
+	if err != nil {
+		return err
+	}
+	encoded := base64.StdEncoding.EncodeToString(attachment.Data)
+	for len(encoded) > 76 {
+		filePart.Write([]byte(encoded[:76] + "\r\n"))
+		encoded = encoded[76:]
+	}
+	filePart.Write([]byte(encoded))
+
+	if err := writer.Close(); err != nil {
+		return err
+	}
+
+	addr := fmt.Sprintf("%s:%s", smtpConfig.Host, smtpConfig.Port)
+	auth := smtp.PlainAuth("", smtpConfig.Username, smtpConfig.Password, smtpConfig.Host)
+
+	if err := smtp.SendMail(addr, auth, smtpConfig.Sender, []string{to}, buf.Bytes()); err != nil {
+		logger.Log.Error("Ошибка при отправке email с вложением", "to", to, "error", err)
 		return err
 	}
 
-	logger.Log.Info("Email отправлен на %s", to)
+	logger.Log.Info("Email с вложением отправлен", "to", to, "attachment", attachment.Filename)
 	return nil
 }
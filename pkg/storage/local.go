@@ -0,0 +1,117 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LocalStorage хранит файлы в каталоге на диске. Ссылки на скачивание ведут на
+// GET {baseURL}/api/files/{key} и подписываются URLSigner.
+type LocalStorage struct {
+	dir     string
+	baseURL string
+	signer  *URLSigner
+}
+
+func NewLocalStorage(dir, baseURL string, signer *URLSigner) (*LocalStorage, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, err
+	}
+	return &LocalStorage{dir: dir, baseURL: strings.TrimRight(baseURL, "/"), signer: signer}, nil
+}
+
+func (s *LocalStorage) Put(ctx context.Context, key string, body io.Reader, size int64, contentType string) error {
+	target, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(target), 0o750); err != nil {
+		return err
+	}
+
+	// Пишем во временный файл и переименовываем, чтобы читатель не увидел файл наполовину
+	tmp, err := os.CreateTemp(filepath.Dir(target), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, body); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), target)
+}
+
+func (s *LocalStorage) Get(ctx context.Context, key string) (io.ReadCloser, *Object, error) {
+	target, err := s.path(key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	file, err := os.Open(target)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, nil, err
+	}
+
+	contentType := mime.TypeByExtension(path.Ext(key))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	return file, &Object{Size: info.Size(), ContentType: contentType}, nil
+}
+
+func (s *LocalStorage) Delete(ctx context.Context, key string) error {
+	target, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(target); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func (s *LocalStorage) SignedURL(ctx context.Context, key, filename string, ttl time.Duration) (string, error) {
+	key, err := CleanKey(key)
+	if err != nil {
+		return "", err
+	}
+
+	expires := time.Now().Add(ttl).Unix()
+	query := url.Values{}
+	query.Set("expires", strconv.FormatInt(expires, 10))
+	query.Set("name", filename)
+	query.Set("signature", s.signer.Sign(key, filename, expires))
+	return fmt.Sprintf("%s/api/files/%s?%s", s.baseURL, key, query.Encode()), nil
+}
+
+func (s *LocalStorage) path(key string) (string, error) {
+	key, err := CleanKey(key)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(s.dir, filepath.FromSlash(key)), nil
+}
@@ -0,0 +1,276 @@
+package storage
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	s3Algorithm       = "AWS4-HMAC-SHA256"
+	s3UnsignedPayload = "UNSIGNED-PAYLOAD"
+	s3MaxPresignTTL   = 7 * 24 * time.Hour
+)
+
+// S3Config — параметры S3-совместимого хранилища (AWS S3, MinIO, Yandex Object Storage и т.п.)
+type S3Config struct {
+	Endpoint  string // например https://s3.eu-central-1.amazonaws.com или http://minio:9000
+	Region    string
+	Bucket    string
+	AccessKey string
+	SecretKey string
+	PathStyle bool // адресация endpoint/bucket/key вместо bucket.endpoint/key (нужна для MinIO)
+}
+
+// S3Storage работает с бакетом напрямую по REST API с подписью AWS Signature V4.
+// Ссылки на скачивание — presigned URL самого S3, приложение файл не проксирует.
+type S3Storage struct {
+	endpoint *url.URL
+	cfg      S3Config
+	client   *http.Client
+}
+
+func NewS3Storage(cfg S3Config) (*S3Storage, error) {
+	endpoint, err := url.Parse(cfg.Endpoint)
+	if err != nil || endpoint.Host == "" {
+		return nil, fmt.Errorf("invalid S3 endpoint %q", cfg.Endpoint)
+	}
+	if cfg.Bucket == "" {
+		return nil, fmt.Errorf("S3 bucket is not configured")
+	}
+	if cfg.Region == "" {
+		cfg.Region = "us-east-1"
+	}
+	return &S3Storage{endpoint: endpoint, cfg: cfg, client: &http.Client{Timeout: 5 * time.Minute}}, nil
+}
+
+func (s *S3Storage) Put(ctx context.Context, key string, body io.Reader, size int64, contentType string) error {
+	req, err := s.request(ctx, http.MethodPut, key, body)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = size
+	req.Header.Set("Content-Type", contentType)
+
+	resp, err := s.do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+func (s *S3Storage) Get(ctx context.Context, key string) (io.ReadCloser, *Object, error) {
+	req, err := s.request(ctx, http.MethodGet, key, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	resp, err := s.do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	return resp.Body, &Object{Size: resp.ContentLength, ContentType: resp.Header.Get("Content-Type")}, nil
+}
+
+func (s *S3Storage) Delete(ctx context.Context, key string) error {
+	req, err := s.request(ctx, http.MethodDelete, key, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := s.do(req)
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// SignedURL возвращает presigned GET URL (query-подпись SigV4)
+func (s *S3Storage) SignedURL(ctx context.Context, key, filename string, ttl time.Duration) (string, error) {
+	return s.presign(key, filename, ttl, time.Now().UTC())
+}
+
+func (s *S3Storage) presign(key, filename string, ttl time.Duration, now time.Time) (string, error) {
+	objectURL, err := s.objectURL(key)
+	if err != nil {
+		return "", err
+	}
+	if ttl > s3MaxPresignTTL {
+		ttl = s3MaxPresignTTL
+	}
+
+	amzDate := now.Format("20060102T150405Z")
+	scope := s.scope(now)
+
+	query := url.Values{}
+	query.Set("X-Amz-Algorithm", s3Algorithm)
+	query.Set("X-Amz-Credential", s.cfg.AccessKey+"/"+scope)
+	query.Set("X-Amz-Date", amzDate)
+	query.Set("X-Amz-Expires", strconv.Itoa(int(ttl.Seconds())))
+	query.Set("X-Amz-SignedHeaders", "host")
+	if filename != "" {
+		query.Set("response-content-disposition", ContentDisposition(filename))
+	}
+
+	canonical := strings.Join([]string{
+		http.MethodGet,
+		uriEncode(objectURL.Path, false),
+		canonicalQuery(query),
+		"host:" + objectURL.Host + "\n",
+		"host",
+		s3UnsignedPayload,
+	}, "\n")
+	query.Set("X-Amz-Signature", s.signature(now, amzDate, scope, canonical))
+
+	objectURL.RawQuery = canonicalQuery(query)
+	return objectURL.String(), nil
+}
+
+func (s *S3Storage) objectURL(key string) (*url.URL, error) {
+	key, err := CleanKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	objectURL := *s.endpoint
+	if s.cfg.PathStyle {
+		objectURL.Path = "/" + s.cfg.Bucket + "/" + key
+	} else {
+		objectURL.Host = s.cfg.Bucket + "." + s.endpoint.Host
+		objectURL.Path = "/" + key
+	}
+	objectURL.RawPath = uriEncode(objectURL.Path, false)
+	return &objectURL, nil
+}
+
+// request собирает запрос к объекту и подписывает его заголовком Authorization
+func (s *S3Storage) request(ctx context.Context, method, key string, body io.Reader) (*http.Request, error) {
+	objectURL, err := s.objectURL(key)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, method, objectURL.String(), body)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	amzDate := now.Format("20060102T150405Z")
+	scope := s.scope(now)
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", s3UnsignedPayload)
+
+	signedHeaders := "host;x-amz-content-sha256;x-amz-date"
+	canonical := strings.Join([]string{
+		method,
+		uriEncode(objectURL.Path, false),
+		"",
+		"host:" + objectURL.Host + "\n" +
+			"x-amz-content-sha256:" + s3UnsignedPayload + "\n" +
+			"x-amz-date:" + amzDate + "\n",
+		signedHeaders,
+		s3UnsignedPayload,
+	}, "\n")
+
+	req.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s3Algorithm, s.cfg.AccessKey, scope, signedHeaders, s.signature(now, amzDate, scope, canonical)))
+	return req, nil
+}
+
+func (s *S3Storage) do(req *http.Request) (*http.Response, error) {
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		resp.Body.Close()
+		return nil, ErrNotFound
+	}
+	if resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("s3 %s %s: %s: %s", req.Method, req.URL.Path, resp.Status, strings.TrimSpace(string(detail)))
+	}
+	return resp, nil
+}
+
+func (s *S3Storage) scope(now time.Time) string {
+	return now.Format("20060102") + "/" + s.cfg.Region + "/s3/aws4_request"
+}
+
+func (s *S3Storage) signature(now time.Time, amzDate, scope, canonicalRequest string) string {
+	hash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := s3Algorithm + "\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(hash[:])
+
+	key := hmacSHA256([]byte("AWS4"+s.cfg.SecretKey), now.Format("20060102"))
+	key = hmacSHA256(key, s.cfg.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	return hex.EncodeToString(hmacSHA256(key, stringToSign))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// canonicalQuery — параметры, отсортированные по имени и закодированные по правилам SigV4
+func canonicalQuery(values url.Values) string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, 0, len(keys))
+	for _, key := range keys {
+		for _, value := range values[key] {
+			parts = append(parts, uriEncode(key, true)+"="+uriEncode(value, true))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// uriEncode кодирует всё, кроме незарезервированных символов RFC 3986; '/' — по флагу
+func uriEncode(value string, encodeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9', c == '-', c == '_', c == '.', c == '~':
+			b.WriteByte(c)
+		case c == '/' && !encodeSlash:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+// ContentDisposition формирует заголовок для скачивания с сохранением имени файла (RFC 6266)
+func ContentDisposition(filename string) string {
+	fallback := strings.Map(func(r rune) rune {
+		if r < 0x20 || r > 0x7e || r == '"' || r == '\\' {
+			return '_'
+		}
+		return r
+	}, filename)
+	return fmt.Sprintf("attachment; filename=\"%s\"; filename*=UTF-8''%s", fallback, uriEncode(filename, true))
+}
@@ -0,0 +1,74 @@
+// Package storage — хранилище загруженных файлов: локальная файловая система или S3-совместимое.
+// Файлы отдаются только по подписанным ссылкам с ограниченным сроком действия.
+package storage
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrNotFound         = errors.New("object not found")
+	ErrInvalidKey       = errors.New("invalid object key")
+	ErrInvalidSignature = errors.New("invalid download signature")
+	ErrLinkExpired      = errors.New("download link expired")
+)
+
+// Object — метаданные сохранённого объекта
+type Object struct {
+	Size        int64
+	ContentType string
+}
+
+type Storage interface {
+	Put(ctx context.Context, key string, body io.Reader, size int64, contentType string) error
+	Get(ctx context.Context, key string) (io.ReadCloser, *Object, error)
+	Delete(ctx context.Context, key string) error
+	// SignedURL возвращает ссылку на скачивание, действующую ttl; filename уходит в Content-Disposition
+	SignedURL(ctx context.Context, key, filename string, ttl time.Duration) (string, error)
+}
+
+// CleanKey проверяет, что ключ — относительный путь без выхода за пределы хранилища
+func CleanKey(key string) (string, error) {
+	cleaned := path.Clean(strings.TrimPrefix(key, "/"))
+	if cleaned == "." || cleaned == ".." || strings.HasPrefix(cleaned, "../") || strings.Contains(cleaned, "\\") {
+		return "", ErrInvalidKey
+	}
+	return cleaned, nil
+}
+
+// URLSigner подписывает ссылки на скачивание, которые отдаёт само приложение (локальное хранилище)
+type URLSigner struct {
+	secret []byte
+}
+
+func NewURLSigner(secret string) *URLSigner {
+	return &URLSigner{secret: []byte(secret)}
+}
+
+// Sign возвращает подпись ключа, имени файла и срока действия
+func (s *URLSigner) Sign(key, filename string, expires int64) string {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(key + "\n" + filename + "\n" + strconv.FormatInt(expires, 10)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify проверяет подпись и срок действия ссылки
+func (s *URLSigner) Verify(key, filename string, expires int64, signature string) error {
+	expected := s.Sign(key, filename, expires)
+	if !hmac.Equal([]byte(expected), []byte(signature)) {
+		return ErrInvalidSignature
+	}
+	if time.Now().Unix() > expires {
+		return ErrLinkExpired
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+// Package thumbnail уменьшает JPEG, PNG и GIF до превью в JPEG без сторонних зависимостей.
+package thumbnail
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/draw"
+	_ "image/gif"
+	"image/jpeg"
+	_ "image/png"
+	"io"
+)
+
+// maxSourcePixels защищает от «декомпрессионных бомб»: крошечный файл с огромными размерами
+const maxSourcePixels = 50_000_000
+
+var ErrTooLarge = errors.New("image dimensions are too large for a thumbnail")
+
+// Make вписывает изображение в квадрат maxSide×maxSide с сохранением пропорций.
+// Прозрачность заливается белым, результат — JPEG.
+func Make(r io.ReadSeeker, maxSide int) ([]byte, error) {
+	cfg, _, err := image.DecodeConfig(r)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Width*cfg.Height > maxSourcePixels {
+		return nil, ErrTooLarge
+	}
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	src, _, err := image.Decode(r)
+	if err != nil {
+		return nil, err
+	}
+
+	width, height := fit(src.Bounds().Dx(), src.Bounds().Dy(), maxSide)
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(dst, dst.Bounds(), &image.Uniform{C: color.White}, image.Point{}, draw.Src)
+	downscale(dst, src)
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, dst, &jpeg.Options{Quality: 80}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func fit(width, height, maxSide int) (int, int) {
+	if width <= maxSide && height <= maxSide {
+		return width, height
+	}
+	if width >= height {
+		return maxSide, max(1, height*maxSide/width)
+	}
+	return max(1, width*maxSide/height), maxSide
+}
+
+// downscale усредняет блок исходных пикселей на каждый пиксель превью (box filter)
+// и накладывает результат на фон dst с учётом альфа-канала
+func downscale(dst *image.RGBA, src image.Image) {
+	sb := src.Bounds()
+	db := dst.Bounds()
+	for y := 0; y < db.Dy(); y++ {
+		y0 := sb.Min.Y + y*sb.Dy()/db.Dy()
+		y1 := max(y0+1, sb.Min.Y+(y+1)*sb.Dy()/db.Dy())
+		for x := 0; x < db.Dx(); x++ {
+			x0 := sb.Min.X + x*sb.Dx()/db.Dx()
+			x1 := max(x0+1, sb.Min.X+(x+1)*sb.Dx()/db.Dx())
+
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					cr, cg, cb, ca := src.At(sx, sy).RGBA()
+					r, g, b, a = r+uint64(cr), g+uint64(cg), b+uint64(cb), a+uint64(ca)
+					n++
+				}
+			}
+			pixel := color.RGBA64{R: uint16(r / n), G: uint16(g / n), B: uint16(b / n), A: uint16(a / n)}
+			draw.Draw(dst, image.Rect(x, y, x+1, y+1), &image.Uniform{C: pixel}, image.Point{}, draw.Over)
+		}
+	}
+}
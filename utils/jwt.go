@@ -1,70 +1,115 @@
 package utils
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"jumyste-app-backend/pkg/jwtkeys"
 )
 
-var jwtSecret = []byte("secretkey")
+var (
+	keys           *jwtkeys.Manager
+	accessTokenTTL = time.Hour
+)
+
+// InitJWT задаёт ключи подписи и срок жизни access-токена; вызывается при старте приложения
+func InitJWT(manager *jwtkeys.Manager, accessTTL time.Duration) {
+	keys = manager
+	if accessTTL > 0 {
+		accessTokenTTL = accessTTL
+	}
+}
+
+var errKeysNotConfigured = errors.New("jwt signing keys are not configured")
+
+// RefreshTokenTTL — срок жизни refresh-токена; каждая ротация продлевает сессию на этот срок
+const RefreshTokenTTL = 7 * 24 * time.Hour
+
+// TokenTypeRefresh отличает refresh-токен от access-токена, подписанного тем же ключом
+const TokenTypeRefresh = "refresh"
 
 type Claims struct {
-	UserID       int `json:"user_id"`
-	RoleID       int `json:"role_id"`
-	CompanyID    int `json:"company_id"`
-	DepartmentID int `json:"department_id"`
+	UserID       int    `json:"user_id"`
+	RoleID       int    `json:"role_id"`
+	CompanyID    int    `json:"company_id"`
+	DepartmentID int    `json:"department_id"`
+	SessionID    string `json:"sid,omitempty"`
+	TokenType    string `json:"token_type,omitempty"`
 	jwt.RegisteredClaims
 }
 
-func GenerateJWT(userID, roleID, companyId, depId int) (string, error) {
+func GenerateJWT(userID, roleID, companyId, depId int, sessionID string) (string, error) {
 	claims := Claims{
 		UserID:       userID,
 		RoleID:       roleID,
 		CompanyID:    companyId,
 		DepartmentID: depId,
+		SessionID:    sessionID,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour * 6)),
+			ID:        NewTokenID(),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(accessTokenTTL)),
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(jwtSecret)
+	return sign(claims)
 }
 
-func GenerateRefreshToken(userID, roleId, companyId, depId int) (string, error) {
+// GenerateRefreshToken выдаёт refresh-токен сессии sessionID; tokenID (jti) меняется при каждой ротации
+func GenerateRefreshToken(userID, roleId, companyId, depId int, sessionID, tokenID string) (string, error) {
 	claims := Claims{
 		UserID:       userID,
 		RoleID:       roleId,
 		CompanyID:    companyId,
 		DepartmentID: depId,
+		SessionID:    sessionID,
+		TokenType:    TokenTypeRefresh,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(7 * 24 * time.Hour)),
+			ID:        tokenID,
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(RefreshTokenTTL)),
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return sign(claims)
+}
 
-	secretKey := jwtSecret
-	refreshToken, err := token.SignedString(secretKey)
-	if err != nil {
-		return "", err
+func sign(claims Claims) (string, error) {
+	if keys == nil {
+		return "", errKeysNotConfigured
 	}
+	return keys.Sign(claims)
+}
 
-	return refreshToken, nil
+// ParseClaims проверяет подпись любым активным ключом и разбирает claims токена
+func ParseClaims(tokenString string) (*Claims, *jwt.Token, error) {
+	if keys == nil {
+		return nil, nil, errKeysNotConfigured
+	}
+	claims := &Claims{}
+	token, err := keys.Parse(tokenString, claims)
+	return claims, token, err
+}
+
+// NewTokenID — случайный jti, по которому токен можно отозвать
+func NewTokenID() string {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return fmt.Sprintf("%x", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(buf)
 }
 
 func ParseJWT(tokenString string) (*Claims, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-		return jwtSecret, nil
-	})
+	claims, token, err := ParseClaims(tokenString)
 	if err != nil {
 		return nil, err
 	}
-
-	claims, ok := token.Claims.(*Claims)
-	if !ok || !token.Valid {
+	if !token.Valid || claims.TokenType == TokenTypeRefresh {
 		return nil, errors.New("invalid token")
 	}
 
@@ -72,14 +117,7 @@ func ParseJWT(tokenString string) (*Claims, error) {
 }
 
 func ValidateRefreshToken(refreshToken string) (*Claims, error) {
-	token, err := jwt.ParseWithClaims(refreshToken, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-
-		return jwtSecret, nil
-	})
-
+	claims, token, err := ParseClaims(refreshToken)
 	if err != nil {
 		return nil, fmt.Errorf("invalid refresh token: %v", err)
 	}
@@ -87,10 +125,8 @@ func ValidateRefreshToken(refreshToken string) (*Claims, error) {
 	if !token.Valid {
 		return nil, errors.New("refresh token is not valid")
 	}
-
-	claims, ok := token.Claims.(*Claims)
-	if !ok {
-		return nil, errors.New("cannot parse claims")
+	if claims.TokenType != TokenTypeRefresh || claims.SessionID == "" || claims.ID == "" {
+		return nil, errors.New("not a refresh token")
 	}
 
 	return claims, nil